@@ -4,6 +4,7 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net"
@@ -11,20 +12,31 @@ import (
 	_ "net/http/pprof"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
+	"time"
 
+	"github.com/grishagavrin/link-shortener/internal/checker"
 	"github.com/grishagavrin/link-shortener/internal/config"
 	"github.com/grishagavrin/link-shortener/internal/errs"
 	"github.com/grishagavrin/link-shortener/internal/handlers"
+	"github.com/grishagavrin/link-shortener/internal/handlers/middlewares"
 	handlersgrpc "github.com/grishagavrin/link-shortener/internal/handlersGPRC"
+	"github.com/grishagavrin/link-shortener/internal/handlersGPRC/interceptors"
+	"github.com/grishagavrin/link-shortener/internal/health"
+	"github.com/grishagavrin/link-shortener/internal/lifecycle"
 	"github.com/grishagavrin/link-shortener/internal/logger"
+	"github.com/grishagavrin/link-shortener/internal/metrics"
 	ls "github.com/grishagavrin/link-shortener/internal/proto"
 	"github.com/grishagavrin/link-shortener/internal/routes"
 	"github.com/grishagavrin/link-shortener/internal/storage"
 	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/grishagavrin/link-shortener/internal/tracing"
+	"github.com/grishagavrin/link-shortener/internal/utils"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/acme/autocert"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 // @Title Link Shortener API
@@ -36,6 +48,9 @@ import (
 // @BasePath /
 // @Host 127.0.0.1:8080
 
+// defaultShutdownTimeout timeout of component start and stop used if value in config is invalid
+const defaultShutdownTimeout = 10 * time.Second
+
 // Global variables
 var (
 	buildVersion string
@@ -62,8 +77,33 @@ func main() {
 	)
 	defer cancel()
 
-	// Batch channel for batch delete
-	chBatch := make(chan models.BatchDelete)
+	// Config instance, parse flags before subcommands
+	cfg, err := config.Instance()
+	if err != nil {
+		l.Fatal("fatal config init", zap.Error(err))
+	}
+
+	// Subcommand for schema migrations: shortener -d <dsn> migrate up|down|status
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		if err = runMigrate(ctx, l, args[1:]); err != nil {
+			l.Fatal("fatal migrate", zap.Error(err))
+		}
+		return
+	}
+
+	// Proxies which are allowed to set real ip of client are parsed once, invalid CIDR stops app
+	if _, err = utils.TrustedProxies(); err != nil {
+		l.Fatal("fatal trusted proxies", zap.Error(err))
+	}
+
+	// Tracer provider with exporter from config
+	shutdownTracing, err := tracing.Init(l)
+	if err != nil {
+		l.Fatal("fatal tracing init", zap.Error(err))
+	}
+
+	// Bounded queue for batch delete
+	chBatch := storage.NewBatchQueue(l)
 
 	// Storage instance allocate logger and batch channel
 	stor, err := storage.Instance(l, chBatch)
@@ -71,147 +111,247 @@ func main() {
 		l.Fatal("fatal storage init", zap.Error(err))
 	}
 
+	// Metrics of delete queue, db pool and cache of links
+	registerMetrics(l, stor, chBatch)
+
 	// Handlers REST
 	h := handlers.New(stor.Repository, l)
 	// Handlers GRPC
-	hGRPC := handlersgrpc.New(stor.Repository, l)
+	hGRPC := handlersgrpc.New(stor.Repository, l, chBatch)
+	// Destination checker for blocklist and lookup service
+	chk, err := checker.FromConfig(ctx, l)
+	if err != nil {
+		l.Fatal("fatal checker init", zap.Error(err))
+	}
+	h.SetChecker(chk)
+	hGRPC.SetChecker(chk)
+	// Readiness checks of storage, delete workers, file storage and GRPC server
+	ready := health.New()
+	stor.RegisterChecks(ready)
+	h.SetHealth(ready)
 	// Routing app
-	r := routes.NewRouterFacade(h, l, chBatch)
+	r, err := routes.NewRouterFacade(h, l, chBatch)
+	if err != nil {
+		l.Fatal("fatal routes init", zap.Error(err))
+	}
+
+	// Components of app are started in order and stopped in reverse order:
+	// servers finish requests, then batches are drained and storage is closed
+	lc := lifecycle.New(l, shutdownTimeout(l, cfg))
+	lc.Append(lifecycle.Hook{Name: "tracing", Stop: shutdownTracing})
+	lc.Append(lifecycle.Hook{Name: "storage", Stop: stor.Close})
+	lc.Append(lifecycle.Hook{Name: "delete pipeline", Stop: stor.DrainDeletes})
+	lc.Append(lifecycle.Hook{Name: "storage workers", Stop: stor.StopWorkers})
+	lc.Append(grpcServer(l, cfg, hGRPC, ready))
+	lc.Append(metricsServer(l, cfg))
+	lc.Append(httpServer(l, cfg, r.HTTPRoute.Route))
 
 	// Start server
-	startServer(ctx, r, l, stor, chBatch, hGRPC)
-}
+	if err = lc.Start(ctx); err != nil {
+		l.Fatal("fatal start", zap.Error(err))
+	}
 
-// start server function
-func startServer(
-	ctx context.Context,
-	r *routes.RouterFacade,
-	l *zap.Logger,
-	stor *storage.InstanceStruct,
-	chBatch chan models.BatchDelete,
-	hGRPC *handlersgrpc.GRPCHandler,
-) {
-	// Start GRPC Server
-	startGRPCServer(hGRPC)
-
-	// Config instance
-	cfg, err := config.Instance()
-	if errors.Is(err, errs.ErrENVLoading) {
-		log.Fatal(errs.ErrConfigInstance, zap.Error(err))
+	<-ctx.Done()
+	l.Info("The service is shutting down...")
+	if err = lc.Stop(context.Background()); err != nil {
+		l.Info("app error exit", zap.Error(err))
 	}
+}
 
-	// Get server address
-	srvAddr, err := cfg.GetCfgValue(config.ServerAddress)
-	if errors.Is(err, errs.ErrUnknownEnvOrFlag) {
-		l.Fatal("fatal get config value: ", zap.Error(err))
+// registerMetrics export state of delete queue, db pool and cache of links
+func registerMetrics(l *zap.Logger, stor *storage.InstanceStruct, chBatch chan models.BatchDelete) {
+	if err := metrics.RegisterDeleteQueue(chBatch); err != nil {
+		l.Info("register metrics of delete queue", zap.Error(err))
 	}
 
-	if cfg.EnableHTTPS == "" {
-		// Start func for HTTP server
-		srv := startHTTPServer(srvAddr, r.HTTPRoute.Route, l)
-		releaseResources(ctx, l, stor, chBatch, srv)
-	} else {
-		// Start func for HTTPS server
-		srv := startHTTPSServer(srvAddr, r.HTTPRoute.Route, l)
-		releaseResources(ctx, l, stor, chBatch, srv)
+	if stor.SQLDB != nil {
+		if err := metrics.RegisterPool(stor.SQLDB); err != nil {
+			l.Info("register metrics of db pool", zap.Error(err))
+		}
 	}
 
+	if c, ok := stor.Repository.(interface{ CacheStats() models.CacheStats }); ok {
+		if err := metrics.RegisterCache(c.CacheStats); err != nil {
+			l.Info("register metrics of cache", zap.Error(err))
+		}
+	}
 }
 
-// Start GRPC Server
-func startGRPCServer(hGRPC *handlersgrpc.GRPCHandler) {
-	lis, err := net.Listen("tcp", ":50051")
+// shutdownTimeout get timeout of component start and stop from config
+func shutdownTimeout(l *zap.Logger, cfg *config.MyConfig) time.Duration {
+	value, err := cfg.GetCfgValue(config.ShutdownTimeout)
 	if err != nil {
-		log.Fatalf("cannot create listener: %s", err)
+		return defaultShutdownTimeout
 	}
 
-	serverRegistrar := grpc.NewServer()
-	ls.RegisterApiServiceServer(serverRegistrar, hGRPC)
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		l.Info("invalid shutdown timeout in config, set default", zap.String("timeout", value))
+		return defaultShutdownTimeout
+	}
 
-	go func() {
-		fmt.Println("GRPC Started on port 50051")
-		err = serverRegistrar.Serve(lis)
-		if err != nil {
-			log.Fatalf("impossible to serve: %s", err)
-		}
-	}()
+	return timeout
 }
 
-// Release of resources app
-func releaseResources(ctx context.Context,
-	l *zap.Logger,
-	stor *storage.InstanceStruct,
-	chBatch chan models.BatchDelete,
-	srv *http.Server,
-) {
-	<-ctx.Done()
-	if ctx.Err() != nil {
-		fmt.Printf("Error:%v\n", ctx.Err())
+// grpcServer component of GRPC server with health service, stop waits for running calls
+func grpcServer(l *zap.Logger, cfg *config.MyConfig, hGRPC *handlersgrpc.GRPCHandler, ready *health.Registry) lifecycle.Hook {
+	// Get GRPC server address
+	addr, err := cfg.GetCfgValue(config.GRPCAddress)
+	if errors.Is(err, errs.ErrUnknownEnvOrFlag) {
+		l.Fatal("fatal get config value: ", zap.Error(err))
 	}
 
-	l.Info("The service is shutting down...")
-	if stor.SQLDB != nil {
-		l.Info("closing connect to db")
-		stor.SQLDB.Close()
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			interceptors.UnaryTracing,
+			interceptors.UnaryMetrics,
+			interceptors.UnaryLogger(l),
+			interceptors.UnaryTrustedSubnet,
+			interceptors.UnaryAuth,
+		),
+		grpc.ChainStreamInterceptor(
+			interceptors.StreamTracing,
+			interceptors.StreamMetrics,
+			interceptors.StreamLogger(l),
+			interceptors.StreamTrustedSubnet,
+			interceptors.StreamAuth,
+		),
+	)
+	ls.RegisterApiServiceServer(srv, hGRPC)
+	healthpb.RegisterHealthServer(srv, handlersgrpc.NewHealthServer(ready))
+
+	// serving is set while server accepts calls
+	var serving int32
+	ready.Add("grpc", func(context.Context) error {
+		if atomic.LoadInt32(&serving) == 0 {
+			return errs.ErrGRPCNotServing
+		}
+		return nil
+	})
+
+	return lifecycle.Hook{
+		Name: "grpc server",
+		Start: func(ctx context.Context) error {
+			lis, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("cannot create listener: %w", err)
+			}
+
+			atomic.StoreInt32(&serving, 1)
+			go func() {
+				defer atomic.StoreInt32(&serving, 0)
+				fmt.Printf("GRPC Started on %s\n", lis.Addr())
+				if err := srv.Serve(lis); err != nil {
+					l.Info("grpc server error", zap.Error(err))
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			atomic.StoreInt32(&serving, 0)
+			done := make(chan struct{})
+			go func() {
+				srv.GracefulStop()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				// Running calls are cancelled after timeout
+				srv.Stop()
+				return ctx.Err()
+			}
+		},
 	}
+}
 
-	// Close channel of batch delete
-	close(chBatch)
-
-	// Shutdown server
-	if err := srv.Shutdown(ctx); err != nil {
-		l.Info("app error exit", zap.Error(err))
+// httpServer component of HTTP or HTTPS server, stop waits for running requests
+func httpServer(l *zap.Logger, cfg *config.MyConfig, h http.Handler) lifecycle.Hook {
+	// Get server address
+	addr, err := cfg.GetCfgValue(config.ServerAddress)
+	if errors.Is(err, errs.ErrUnknownEnvOrFlag) {
+		l.Fatal("fatal get config value: ", zap.Error(err))
 	}
-}
 
-// Start HTTP server func
-func startHTTPServer(
-	srvAddr string,
-	h http.Handler,
-	l *zap.Logger,
-) *http.Server {
 	srv := &http.Server{
-		Addr:    srvAddr,
+		Addr:    addr,
 		Handler: h,
 	}
-	go func() {
-		l.Info("Start HTTP server")
-		err := srv.ListenAndServe()
-		if err != nil {
-			l.Info("app error exit", zap.Error(err))
+
+	tls := cfg.EnableHTTPS != ""
+	if tls {
+		manager := &autocert.Manager{
+			Cache:      autocert.DirCache("cache-dir"),
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(addr),
 		}
-	}()
+		srv.Addr = ":443"
+		srv.TLSConfig = manager.TLSConfig()
+	}
 
-	return srv
+	return lifecycle.Hook{
+		Name: "http server",
+		Start: func(ctx context.Context) error {
+			lis, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return fmt.Errorf("cannot create listener: %w", err)
+			}
+
+			go func() {
+				var err error
+				if tls {
+					l.Info("Start HTTPS server")
+					err = srv.ServeTLS(lis, "server.crt", "server.key")
+				} else {
+					l.Info("Start HTTP server")
+					err = srv.Serve(lis)
+				}
+				if err != nil && !errors.Is(err, http.ErrServerClosed) {
+					l.Info("app error exit", zap.Error(err))
+				}
+			}()
+			return nil
+		},
+		Stop: srv.Shutdown,
+	}
 }
 
-// Start HTTPS server func
-func startHTTPSServer(
-	srvAddr string,
-	h http.Handler,
-	l *zap.Logger,
-) *http.Server {
-	manager := &autocert.Manager{
-		Cache:      autocert.DirCache("cache-dir"),
-		Prompt:     autocert.AcceptTOS,
-		HostPolicy: autocert.HostWhitelist(srvAddr),
+// metricsServer component of HTTP server with prometheus metrics on separate listener,
+// it is not exposed by public routes
+func metricsServer(l *zap.Logger, cfg *config.MyConfig) lifecycle.Hook {
+	addr, err := cfg.GetCfgValue(config.MetricsAddress)
+	if errors.Is(err, errs.ErrUnknownEnvOrFlag) {
+		l.Fatal("fatal get config value: ", zap.Error(err))
 	}
 
+	mux := http.NewServeMux()
+	// Metrics are limited to trusted subnet as internal stats
+	mux.Handle("/metrics", middlewares.TrustedSubnet(metrics.Handler()))
 	srv := &http.Server{
-		Addr:      ":443",
-		Handler:   h,
-		TLSConfig: manager.TLSConfig(),
+		Addr:    addr,
+		Handler: mux,
 	}
 
-	go func() {
-		l.Info("Start HTTPS server")
-		err := srv.ListenAndServeTLS("server.crt", "server.key")
-		if err != nil {
-			l.Info("app error exit", zap.Error(err))
-		}
-	}()
-
-	return srv
+	return lifecycle.Hook{
+		Name: "metrics server",
+		Start: func(ctx context.Context) error {
+			lis, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return fmt.Errorf("cannot create listener: %w", err)
+			}
+
+			go func() {
+				l.Info("Start metrics server", zap.String("address", lis.Addr().String()))
+				if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					l.Info("metrics server error", zap.Error(err))
+				}
+			}()
+			return nil
+		},
+		Stop: srv.Shutdown,
+	}
 }
 
 // Print build info print info about package
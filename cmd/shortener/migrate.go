@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/dbstorage"
+	"github.com/grishagavrin/link-shortener/internal/storage/migrations"
+	"github.com/grishagavrin/link-shortener/internal/utils/db"
+	"go.uber.org/zap"
+)
+
+// migrateCommands subcommands of migrate
+var migrateCommands = map[string]bool{
+	"up":        true,
+	"down":      true,
+	"status":    true,
+	"normalize": true,
+}
+
+// runMigrate run migrate subcommand: up, down, status or normalize of saved origins
+func runMigrate(ctx context.Context, l *zap.Logger, args []string) error {
+	// Command is checked before connect to db
+	if len(args) != 1 || !migrateCommands[args[0]] {
+		return errs.ErrMigrateCommand
+	}
+
+	dbi, err := db.SQLDBConnection(l)
+	if err != nil {
+		return err
+	}
+	defer dbi.Close()
+
+	m, err := migrations.New(dbi, l)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		return m.Down(ctx)
+	case "normalize":
+		count, err := dbstorage.NormalizeOrigins(ctx, dbi, l)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stdout, "normalized %d origins\n", count)
+		return nil
+	default:
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		printStatus(os.Stdout, statuses)
+		return nil
+	}
+}
+
+// printStatus print one line per migration version
+func printStatus(w io.Writer, statuses []migrations.Status) {
+	for _, s := range statuses {
+		applied := "pending"
+		if s.Applied {
+			applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%04d %-40s %s\n", s.Version, s.Name, applied)
+	}
+}
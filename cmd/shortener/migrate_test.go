@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/migrations"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRunMigrate(t *testing.T) {
+	// создаём массив тестов: аргументы и желаемая ошибка
+	tests := []struct {
+		name string
+		args []string
+		err  error
+	}{
+		{
+			name: "positive test #1",
+			args: []string{"up"},
+			err:  errs.ErrDatabaseNotAvaliable,
+		},
+		{
+			name: "positive test #2",
+			args: []string{"status"},
+			err:  errs.ErrDatabaseNotAvaliable,
+		},
+		{
+			name: "positive test #3",
+			args: []string{"normalize"},
+			err:  errs.ErrDatabaseNotAvaliable,
+		},
+		{
+			name: "negative test #1",
+			args: nil,
+			err:  errs.ErrMigrateCommand,
+		},
+		{
+			name: "negative test #2",
+			args: []string{"sideways"},
+			err:  errs.ErrMigrateCommand,
+		},
+		{
+			name: "negative test #3",
+			args: []string{"up", "down"},
+			err:  errs.ErrMigrateCommand,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест, без DATABASE_DSN известная команда упирается в подключение
+		t.Run(tt.name, func(t *testing.T) {
+			err := runMigrate(context.Background(), zap.NewNop(), tt.args)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestPrintStatus(t *testing.T) {
+	appliedAt := time.Date(2024, 3, 1, 10, 20, 30, 0, time.UTC)
+	var buf bytes.Buffer
+	printStatus(&buf, []migrations.Status{
+		{Version: 1, Name: "short_links", Applied: true, AppliedAt: appliedAt},
+		{Version: 12, Name: "link_revisions"},
+	})
+
+	// проверяем строки статуса
+	want := "0001 short_links                              applied 2024-03-01 10:20:30\n" +
+		"0012 link_revisions                           pending\n"
+	assert.Equal(t, want, buf.String())
+}
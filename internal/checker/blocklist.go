@@ -0,0 +1,164 @@
+package checker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/utils"
+	"go.uber.org/zap"
+)
+
+// regexPrefix prefix of regex rules in blocklist file
+const regexPrefix = "regex:"
+
+// Blocklist local list of blocked domains and regular expressions from file.
+// Domain blocks its subdomains too, regex is matched against whole url.
+type Blocklist struct {
+	mu      sync.RWMutex
+	path    string
+	l       *zap.Logger
+	domains map[string]struct{}
+	regexps []*regexp.Regexp
+	modTime time.Time
+}
+
+// NewBlocklist load blocklist from file
+func NewBlocklist(path string, l *zap.Logger) (*Blocklist, error) {
+	bl := &Blocklist{path: path, l: l}
+	if err := bl.Reload(); err != nil {
+		return nil, err
+	}
+	return bl, nil
+}
+
+// Reload read blocklist file, on error previous rules are kept
+func (b *Blocklist) Reload() error {
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrBlocklist, err)
+	}
+
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrBlocklist, err)
+	}
+
+	domains := make(map[string]struct{})
+	var regexps []*regexp.Regexp
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, regexPrefix) {
+			re, err := regexp.Compile(strings.TrimPrefix(line, regexPrefix))
+			if err != nil {
+				return fmt.Errorf("%w: line %d: %v", errs.ErrBlocklist, n, err)
+			}
+			regexps = append(regexps, re)
+			continue
+		}
+
+		// Domains are compared in punycode like hosts of normalized urls, ip addresses as is
+		domain, err := utils.NormalizeDomain(line)
+		if err != nil && net.ParseIP(line) == nil {
+			return fmt.Errorf("%w: line %d: %v", errs.ErrBlocklist, n, err)
+		}
+		if err != nil {
+			domain = line
+		}
+		domains[domain] = struct{}{}
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrBlocklist, err)
+	}
+
+	b.mu.Lock()
+	b.domains = domains
+	b.regexps = regexps
+	b.modTime = info.ModTime()
+	b.mu.Unlock()
+
+	return nil
+}
+
+// Watch reload file after its change until context done
+func (b *Blocklist) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(b.path)
+			if err != nil {
+				b.l.Info("blocklist stat error", zap.Error(err))
+				continue
+			}
+
+			b.mu.RLock()
+			changed := !info.ModTime().Equal(b.modTime)
+			b.mu.RUnlock()
+			if !changed {
+				continue
+			}
+
+			if err = b.Reload(); err != nil {
+				b.l.Info("blocklist reload error, previous rules are kept", zap.Error(err))
+				continue
+			}
+			b.l.Info("blocklist reloaded", zap.String("path", b.path))
+		}
+	}
+}
+
+// Check if host of url or its parent domain is in list or url matches regex
+func (b *Blocklist) Check(_ context.Context, rawURL string) (Verdict, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("%w: %v", errs.ErrInvalidURL, err)
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	host := strings.ToLower(u.Hostname())
+	if ascii, err := utils.NormalizeDomain(host); err == nil {
+		host = ascii
+	}
+	for host != "" {
+		if _, ok := b.domains[host]; ok {
+			return Verdict{Blocked: true, Reason: "blocklist domain " + host}, nil
+		}
+
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			break
+		}
+		host = host[i+1:]
+	}
+
+	for _, re := range b.regexps {
+		if re.MatchString(rawURL) {
+			return Verdict{Blocked: true, Reason: "blocklist regex " + re.String()}, nil
+		}
+	}
+
+	return Verdict{}, nil
+}
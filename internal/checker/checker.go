@@ -0,0 +1,174 @@
+// Package checker implements screening of link destinations before save and redirect
+package checker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"go.uber.org/zap"
+)
+
+// reloadInterval interval for check of blocklist file changes
+const reloadInterval = 5 * time.Second
+
+// Policies for failed destination check
+const (
+	// FailOpen link is not blocked if check fails
+	FailOpen = "open"
+	// FailClosed link is blocked if check fails
+	FailClosed = "closed"
+)
+
+// reasonCheckFailed reason of verdict for failed check with closed policy
+const reasonCheckFailed = "destination check failed"
+
+// Verdict result of destination check
+type Verdict struct {
+	Blocked bool
+	Reason  string
+	// Failed check did not answer and link is blocked by closed policy, flag is not stored on redirect
+	Failed bool
+}
+
+// DestinationChecker check if destination of link is allowed
+type DestinationChecker interface {
+	Check(ctx context.Context, url string) (Verdict, error)
+}
+
+// Chain run checkers in order until first blocked verdict
+type Chain []DestinationChecker
+
+// Check destination by all checkers of chain
+func (c Chain) Check(ctx context.Context, url string) (Verdict, error) {
+	for _, chk := range c {
+		v, err := chk.Check(ctx, url)
+		if err != nil {
+			return Verdict{}, err
+		}
+		if v.Blocked {
+			return v, nil
+		}
+	}
+	return Verdict{}, nil
+}
+
+// FromConfig create checkers from config, nil checker means screening is off.
+// Blocklist file is reloaded until context done.
+func FromConfig(ctx context.Context, l *zap.Logger) (DestinationChecker, error) {
+	cfg, err := config.Instance()
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := cfg.GetCfgValue(config.CheckFailPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if policy != FailOpen && policy != FailClosed {
+		return nil, fmt.Errorf("%w: %s", errs.ErrCheckPolicy, policy)
+	}
+
+	var chain Chain
+
+	path, err := cfg.GetCfgValue(config.BlocklistFile)
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		bl, err := NewBlocklist(path, l)
+		if err != nil {
+			return nil, err
+		}
+		go bl.Watch(ctx, reloadInterval)
+		chain = append(chain, bl)
+	}
+
+	endpoint, err := cfg.GetCfgValue(config.CheckerURL)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint != "" {
+		chain = append(chain, NewHTTPLookup(endpoint, nil))
+	}
+
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+// Screen check url by checker, link is not blocked if checker is off,
+// failed check blocks link only with closed policy
+func Screen(ctx context.Context, chk DestinationChecker, url string, l *zap.Logger) Verdict {
+	if chk == nil {
+		return Verdict{}
+	}
+
+	v, err := chk.Check(ctx, url)
+	if err != nil {
+		l.Info("destination check error", zap.String("url", url), zap.Error(err))
+		if failClosed() {
+			return Verdict{Blocked: true, Reason: reasonCheckFailed, Failed: true}
+		}
+		return Verdict{}
+	}
+	return v
+}
+
+// ScreenAll check urls of batch concurrently, verdicts are in order of urls
+func ScreenAll(ctx context.Context, chk DestinationChecker, urls []string, l *zap.Logger) []Verdict {
+	verdicts := make([]Verdict, len(urls))
+	if chk == nil {
+		return verdicts
+	}
+
+	sem := make(chan struct{}, config.SCREENWORKERS)
+	var wg sync.WaitGroup
+	for k, url := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(k int, url string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			verdicts[k] = Screen(ctx, chk, url, l)
+		}(k, url)
+	}
+	wg.Wait()
+
+	return verdicts
+}
+
+// failClosed get config policy for failed check
+func failClosed() bool {
+	cfg, err := config.Instance()
+	if err != nil {
+		return false
+	}
+
+	value, err := cfg.GetCfgValue(config.CheckFailPolicy)
+	if err != nil {
+		return false
+	}
+	return value == FailClosed
+}
+
+// OnRedirect get config flag for check of destination on every redirect
+func OnRedirect() bool {
+	cfg, err := config.Instance()
+	if err != nil {
+		return false
+	}
+
+	value, err := cfg.GetCfgValue(config.CheckOnRedirect)
+	if err != nil {
+		return false
+	}
+
+	on, _ := strconv.ParseBool(value)
+	return on
+}
@@ -0,0 +1,156 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	// тесты проверяют политику закрытия при ошибке проверки
+	os.Setenv("CHECK_FAIL_POLICY", FailClosed)
+	os.Exit(m.Run())
+}
+
+// fakeChecker блокирует адреса с "malware", на адресах с "fail" возвращает ошибку
+type fakeChecker struct{}
+
+func (fakeChecker) Check(_ context.Context, url string) (Verdict, error) {
+	if strings.Contains(url, "fail") {
+		return Verdict{}, errors.New("checker is down")
+	}
+	if strings.Contains(url, "malware") {
+		return Verdict{Blocked: true, Reason: "malware"}, nil
+	}
+	return Verdict{}, nil
+}
+
+func TestScreen(t *testing.T) {
+	// создаём массив тестов: адрес и желаемый результат
+	tests := []struct {
+		name string
+		chk  DestinationChecker
+		url  string
+		want Verdict
+	}{
+		{
+			name: "positive test #1",
+			chk:  fakeChecker{},
+			url:  "http://yandex.ru",
+			want: Verdict{},
+		},
+		{
+			name: "positive test #2",
+			chk:  fakeChecker{},
+			url:  "http://yandex.ru/malware",
+			want: Verdict{Blocked: true, Reason: "malware"},
+		},
+		{
+			name: "positive test #3",
+			url:  "http://yandex.ru/fail",
+			want: Verdict{},
+		},
+		{
+			name: "negative test #1",
+			chk:  fakeChecker{},
+			url:  "http://yandex.ru/fail",
+			want: Verdict{Blocked: true, Reason: reasonCheckFailed, Failed: true},
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Screen(context.Background(), tt.chk, tt.url, zap.NewNop()))
+		})
+	}
+}
+
+func TestScreenAll(t *testing.T) {
+	urls := make([]string, 0, 30)
+	for n := 0; n < 10; n++ {
+		urls = append(urls, "http://yandex.ru", "http://yandex.ru/malware", "http://yandex.ru/fail")
+	}
+
+	// вердикты идут в порядке адресов
+	verdicts := ScreenAll(context.Background(), fakeChecker{}, urls, zap.NewNop())
+	assert.Len(t, verdicts, len(urls))
+	for n, v := range verdicts {
+		assert.Equal(t, n%3 != 0, v.Blocked)
+		assert.Equal(t, n%3 == 2, v.Failed)
+	}
+
+	// без проверки ссылки не блокируются
+	assert.Equal(t, make([]Verdict, len(urls)), ScreenAll(context.Background(), nil, urls, zap.NewNop()))
+}
+
+func TestBlocklist_Check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	os.WriteFile(path, []byte("# comment\nпример.рф\nEvil.com.\n10.0.0.1\nregex:/phish\n"), 0o600)
+	bl, err := NewBlocklist(path, zap.NewNop())
+	assert.NoError(t, err)
+
+	// создаём массив тестов: адрес и желаемый результат
+	tests := []struct {
+		name    string
+		url     string
+		blocked bool
+	}{
+		{
+			name:    "positive test #1",
+			url:     "http://xn--e1afmkfd.xn--p1ai/path",
+			blocked: true,
+		},
+		{
+			name:    "positive test #2",
+			url:     "http://www.пример.рф",
+			blocked: true,
+		},
+		{
+			name:    "positive test #3",
+			url:     "http://sub.evil.com",
+			blocked: true,
+		},
+		{
+			name:    "positive test #4",
+			url:     "http://10.0.0.1:8080",
+			blocked: true,
+		},
+		{
+			name:    "positive test #5",
+			url:     "http://yandex.ru/phish",
+			blocked: true,
+		},
+		{
+			name:    "negative test #1",
+			url:     "http://notevil.com",
+			blocked: false,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := bl.Check(context.Background(), tt.url)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.blocked, v.Blocked)
+		})
+	}
+
+	// неверный домен в файле не загружается
+	os.WriteFile(path, []byte("bad domain\n"), 0o600)
+	_, err = NewBlocklist(path, zap.NewNop())
+	assert.ErrorIs(t, err, errs.ErrBlocklist)
+}
+
+func TestFromConfig(t *testing.T) {
+	// политика closed из окружения допустима, проверка выключена без настроек
+	chk, err := FromConfig(context.Background(), zap.NewNop())
+	assert.NoError(t, err)
+	assert.Nil(t, chk)
+}
@@ -0,0 +1,69 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+)
+
+// lookupTimeout default timeout of lookup request
+const lookupTimeout = 2 * time.Second
+
+// HTTPLookup checker which asks external service about url.
+// Service gets POST with {"url": "..."} and answers {"blocked": true, "reason": "..."}.
+type HTTPLookup struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewHTTPLookup create lookup client, nil client means client with default timeout
+func NewHTTPLookup(endpoint string, client *http.Client) *HTTPLookup {
+	if client == nil {
+		client = &http.Client{Timeout: lookupTimeout}
+	}
+	return &HTTPLookup{
+		endpoint: endpoint,
+		client:   client,
+	}
+}
+
+// Check ask lookup service about url
+func (h *HTTPLookup) Check(ctx context.Context, url string) (Verdict, error) {
+	body, err := json.Marshal(struct {
+		URL string `json:"url"`
+	}{URL: url})
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Verdict{}, fmt.Errorf("%w: %v", errs.ErrLookup, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("%w: %v", errs.ErrLookup, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return Verdict{}, fmt.Errorf("%w: status %d", errs.ErrLookup, res.StatusCode)
+	}
+
+	var answer struct {
+		Blocked bool   `json:"blocked"`
+		Reason  string `json:"reason"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&answer); err != nil {
+		return Verdict{}, fmt.Errorf("%w: %v", errs.ErrLookup, err)
+	}
+
+	return Verdict{Blocked: answer.Blocked, Reason: answer.Reason}, nil
+}
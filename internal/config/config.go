@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/caarlos0/env"
 	"github.com/grishagavrin/link-shortener/internal/errs"
@@ -15,35 +16,181 @@ import (
 
 // Config consts for param config func
 const (
-	ServerAddress   = "ServerAddress"
-	BaseURL         = "BaseURL"
-	FileStoragePath = "FileStoragePath"
-	DatabaseDSN     = "DatabaseDSN"
-	EnableHTTPS     = "EnableHTTPS"
-	TrustedSubnet   = "TRUSTEDSUBNET"
-	LENHASH         = 16
-	Config          = "CONFIG"
+	ServerAddress     = "ServerAddress"
+	BaseURL           = "BaseURL"
+	FileStoragePath   = "FileStoragePath"
+	DatabaseDSN       = "DatabaseDSN"
+	EnableHTTPS       = "EnableHTTPS"
+	TrustedSubnet     = "TRUSTEDSUBNET"
+	SweepInterval     = "SweepInterval"
+	WALFsync          = "WALFsync"
+	WALCompact        = "WALCompact"
+	GRPCAddress       = "GRPCAddress"
+	CookieKeys        = "CookieKeys"
+	CookieKeyFile     = "CookieKeyFile"
+	APIKeyRequired    = "APIKeyRequired"
+	RateLimitBackend  = "RateLimitBackend"
+	RateLimitUser     = "RateLimitUser"
+	RateLimitIP       = "RateLimitIP"
+	RateLimitRoutes   = "RateLimitRoutes"
+	AllowPrivateURLs  = "AllowPrivateURLs"
+	BlocklistFile     = "BlocklistFile"
+	CheckerURL        = "CheckerURL"
+	CheckOnRedirect   = "CheckOnRedirect"
+	BlockedAction     = "BlockedAction"
+	PurgeAfterDays    = "PurgeAfterDays"
+	PurgeInterval     = "PurgeInterval"
+	DeleteWorkers     = "DeleteWorkers"
+	DeleteQueue       = "DeleteQueue"
+	DeleteFlush       = "DeleteFlush"
+	ShutdownTimeout   = "ShutdownTimeout"
+	CacheSize         = "CacheSize"
+	CacheTTL          = "CacheTTL"
+	CacheNegativeTTL  = "CacheNegativeTTL"
+	TraceExporter     = "TraceExporter"
+	TraceEndpoint     = "TraceEndpoint"
+	LogLevel          = "LogLevel"
+	LogFormat         = "LogFormat"
+	TrustedProxies    = "TrustedProxies"
+	CheckFailPolicy   = "CheckFailPolicy"
+	MetricsAddress    = "MetricsAddress"
+	StrictDomains     = "StrictDomains"
+	LENHASH           = 16
+	ALIASMINLEN       = 3
+	ALIASMAXLEN       = 50
+	URLMAXLEN         = 2048
+	REQUESTIDMAXLEN   = 64
+	DEFAULTREDIRECT   = 307
+	CLICKSBUFFER      = 1024
+	CLICKSBATCH       = 100
+	DELETEBATCH       = 500
+	STATSHOURS        = 48
+	STATSDAYS         = 30
+	WALCOMPACTRECORDS = 10000
+	Config            = "CONFIG"
 )
 
+// CLICKSFLUSH interval for write buffered click events
+const CLICKSFLUSH = time.Second
+
+// TOKENTTL default lifetime of bearer token
+const TOKENTTL = 24 * time.Hour
+
+// TOKENMAXTTL max lifetime of bearer token
+const TOKENMAXTTL = 30 * 24 * time.Hour
+
+// JOBTTL lifetime of finished batch job status
+const JOBTTL = time.Hour
+
+// WALSYNC interval for fsync of file storage log with interval policy
+const WALSYNC = time.Second
+
+// RESOLVETIMEOUT timeout of host lookup for check of private targets
+const RESOLVETIMEOUT = time.Second
+
+// RESOLVETTL lifetime of cached result of host lookup
+const RESOLVETTL = time.Minute
+
+// DOMAINSREFRESH lifetime of cached set of custom domains
+const DOMAINSREFRESH = 10 * time.Second
+
+// SCREENWORKERS max count of concurrent destination checks of one batch
+const SCREENWORKERS = 8
+
+// PURGEMINDAYS min age in days of deleted links which can be purged by request
+const PURGEMINDAYS = 1
+
+// HEALTHTIMEOUT timeout of one readiness check of component
+const HEALTHTIMEOUT = 2 * time.Second
+
+// HEALTHWATCH interval of readiness checks for grpc health watch
+const HEALTHWATCH = 5 * time.Second
+
 // JSONConfig for json config
 type JSONConfig struct {
-	BaseURL         string `json:"base_url"`
-	ServerAddress   string `json:"server_address"`
-	FileStoragePath string `json:"file_storage_path"`
-	DatabaseDsn     string `json:"database_dsn"`
-	EnableHTTPS     bool   `json:"enable_https"`
-	TrustedSubnet   string `json:"trusted_subnet"`
+	BaseURL          string `json:"base_url"`
+	ServerAddress    string `json:"server_address"`
+	FileStoragePath  string `json:"file_storage_path"`
+	DatabaseDsn      string `json:"database_dsn"`
+	EnableHTTPS      bool   `json:"enable_https"`
+	TrustedSubnet    string `json:"trusted_subnet"`
+	SweepInterval    string `json:"sweep_interval"`
+	WALFsync         string `json:"wal_fsync"`
+	WALCompact       string `json:"wal_compact_interval"`
+	GRPCAddress      string `json:"grpc_address"`
+	CookieKeys       string `json:"cookie_keys"`
+	CookieKeyFile    string `json:"cookie_key_file"`
+	APIKeyRequired   bool   `json:"api_key_required"`
+	RateLimitBackend string `json:"rate_limit_backend"`
+	RateLimitUser    string `json:"rate_limit_user"`
+	RateLimitIP      string `json:"rate_limit_ip"`
+	RateLimitRoutes  string `json:"rate_limit_routes"`
+	AllowPrivateURLs bool   `json:"allow_private_urls"`
+	BlocklistFile    string `json:"blocklist_file"`
+	CheckerURL       string `json:"checker_url"`
+	CheckOnRedirect  bool   `json:"check_on_redirect"`
+	BlockedAction    string `json:"blocked_action"`
+	PurgeAfterDays   int    `json:"purge_after_days"`
+	PurgeInterval    string `json:"purge_interval"`
+	DeleteWorkers    int    `json:"delete_workers"`
+	DeleteQueue      int    `json:"delete_queue"`
+	DeleteFlush      string `json:"delete_flush"`
+	ShutdownTimeout  string `json:"shutdown_timeout"`
+	CacheSize        *int   `json:"cache_size"`
+	CacheTTL         string `json:"cache_ttl"`
+	CacheNegativeTTL string `json:"cache_negative_ttl"`
+	TraceExporter    string `json:"trace_exporter"`
+	TraceEndpoint    string `json:"trace_endpoint"`
+	LogLevel         string `json:"log_level"`
+	LogFormat        string `json:"log_format"`
+	TrustedProxies   string `json:"trusted_proxies"`
+	CheckFailPolicy  string `json:"check_fail_policy"`
+	MetricsAddress   string `json:"metrics_address"`
+	StrictDomains    bool   `json:"strict_domains"`
 }
 
 // Config base struct with default initialize
 type MyConfig struct {
-	ServerAddress   string `env:"SERVER_ADDRESS" envDefault:"127.0.0.1:8080"`
-	BaseURL         string `env:"BASE_URL" envDefault:"http://localhost:8080"`
-	FileStoragePath string `env:"FILE_STORAGE_PATH" envDefault:"../../filedata"`
-	DatabaseDSN     string `env:"DATABASE_DSN" envDefault:""`
-	EnableHTTPS     string `env:"ENABLE_HTTPS" envDefault:""`
-	TrustedSubnet   string `env:"TRUSTED_SUBNET" envDefault:"127.0.0.1/8"`
-	Config          string `env:"CONFIG" envDefault:""`
+	ServerAddress    string `env:"SERVER_ADDRESS" envDefault:"127.0.0.1:8080"`
+	BaseURL          string `env:"BASE_URL" envDefault:"http://localhost:8080"`
+	FileStoragePath  string `env:"FILE_STORAGE_PATH" envDefault:"../../filedata"`
+	DatabaseDSN      string `env:"DATABASE_DSN" envDefault:""`
+	EnableHTTPS      string `env:"ENABLE_HTTPS" envDefault:""`
+	TrustedSubnet    string `env:"TRUSTED_SUBNET" envDefault:"127.0.0.1/8"`
+	SweepInterval    string `env:"SWEEP_INTERVAL" envDefault:"1m"`
+	WALFsync         string `env:"WAL_FSYNC" envDefault:"interval"`
+	WALCompact       string `env:"WAL_COMPACT_INTERVAL" envDefault:"5m"`
+	GRPCAddress      string `env:"GRPC_ADDRESS" envDefault:":50051"`
+	CookieKeys       string `env:"COOKIE_KEYS" envDefault:""`
+	CookieKeyFile    string `env:"COOKIE_KEY_FILE" envDefault:"../../cookie.keys"`
+	APIKeyRequired   string `env:"API_KEY_REQUIRED" envDefault:""`
+	RateLimitBackend string `env:"RATE_LIMIT_BACKEND" envDefault:"memory"`
+	RateLimitUser    string `env:"RATE_LIMIT_USER" envDefault:"20:40"`
+	RateLimitIP      string `env:"RATE_LIMIT_IP" envDefault:"50:100"`
+	RateLimitRoutes  string `env:"RATE_LIMIT_ROUTES" envDefault:"POST /=5:20,POST /api/shorten/batch=1:5"`
+	AllowPrivateURLs string `env:"ALLOW_PRIVATE_URLS" envDefault:""`
+	BlocklistFile    string `env:"BLOCKLIST_FILE" envDefault:""`
+	CheckerURL       string `env:"CHECKER_URL" envDefault:""`
+	CheckOnRedirect  string `env:"CHECK_ON_REDIRECT" envDefault:""`
+	BlockedAction    string `env:"BLOCKED_ACTION" envDefault:"451"`
+	PurgeAfterDays   string `env:"PURGE_AFTER_DAYS" envDefault:"30"`
+	PurgeInterval    string `env:"PURGE_INTERVAL" envDefault:"24h"`
+	DeleteWorkers    string `env:"DELETE_WORKERS" envDefault:"2"`
+	DeleteQueue      string `env:"DELETE_QUEUE" envDefault:"1024"`
+	DeleteFlush      string `env:"DELETE_FLUSH" envDefault:"50ms"`
+	ShutdownTimeout  string `env:"SHUTDOWN_TIMEOUT" envDefault:"10s"`
+	CacheSize        string `env:"CACHE_SIZE" envDefault:""`
+	CacheTTL         string `env:"CACHE_TTL" envDefault:"1m"`
+	CacheNegativeTTL string `env:"CACHE_NEGATIVE_TTL" envDefault:"5s"`
+	TraceExporter    string `env:"TRACE_EXPORTER" envDefault:"none"`
+	TraceEndpoint    string `env:"TRACE_ENDPOINT" envDefault:"http://localhost:4318"`
+	LogLevel         string `env:"LOG_LEVEL" envDefault:"info"`
+	LogFormat        string `env:"LOG_FORMAT" envDefault:"json"`
+	TrustedProxies   string `env:"TRUSTED_PROXIES" envDefault:""`
+	CheckFailPolicy  string `env:"CHECK_FAIL_POLICY" envDefault:"open"`
+	MetricsAddress   string `env:"METRICS_ADDRESS" envDefault:"127.0.0.1:9090"`
+	StrictDomains    string `env:"STRICT_DOMAINS" envDefault:""`
+	Config           string `env:"CONFIG" envDefault:""`
 }
 
 // Instance variable of config
@@ -109,6 +256,105 @@ func (c *MyConfig) initJSON() {
 	if c.EnableHTTPS == "" {
 		c.EnableHTTPS = strconv.FormatBool(config.EnableHTTPS)
 	}
+	if c.SweepInterval == "" {
+		c.SweepInterval = config.SweepInterval
+	}
+	if c.WALFsync == "" {
+		c.WALFsync = config.WALFsync
+	}
+	if c.WALCompact == "" {
+		c.WALCompact = config.WALCompact
+	}
+	if c.GRPCAddress == "" {
+		c.GRPCAddress = config.GRPCAddress
+	}
+	if c.CookieKeys == "" {
+		c.CookieKeys = config.CookieKeys
+	}
+	if c.CookieKeyFile == "" {
+		c.CookieKeyFile = config.CookieKeyFile
+	}
+	if c.APIKeyRequired == "" {
+		c.APIKeyRequired = strconv.FormatBool(config.APIKeyRequired)
+	}
+	if c.RateLimitBackend == "" {
+		c.RateLimitBackend = config.RateLimitBackend
+	}
+	if c.RateLimitUser == "" {
+		c.RateLimitUser = config.RateLimitUser
+	}
+	if c.RateLimitIP == "" {
+		c.RateLimitIP = config.RateLimitIP
+	}
+	if c.RateLimitRoutes == "" {
+		c.RateLimitRoutes = config.RateLimitRoutes
+	}
+	if c.AllowPrivateURLs == "" {
+		c.AllowPrivateURLs = strconv.FormatBool(config.AllowPrivateURLs)
+	}
+	if c.BlocklistFile == "" {
+		c.BlocklistFile = config.BlocklistFile
+	}
+	if c.CheckerURL == "" {
+		c.CheckerURL = config.CheckerURL
+	}
+	if c.CheckOnRedirect == "" {
+		c.CheckOnRedirect = strconv.FormatBool(config.CheckOnRedirect)
+	}
+	if c.BlockedAction == "" {
+		c.BlockedAction = config.BlockedAction
+	}
+	if c.PurgeAfterDays == "" {
+		c.PurgeAfterDays = strconv.Itoa(config.PurgeAfterDays)
+	}
+	if c.PurgeInterval == "" {
+		c.PurgeInterval = config.PurgeInterval
+	}
+	if c.DeleteWorkers == "" {
+		c.DeleteWorkers = strconv.Itoa(config.DeleteWorkers)
+	}
+	if c.DeleteQueue == "" {
+		c.DeleteQueue = strconv.Itoa(config.DeleteQueue)
+	}
+	if c.DeleteFlush == "" {
+		c.DeleteFlush = config.DeleteFlush
+	}
+	if c.ShutdownTimeout == "" {
+		c.ShutdownTimeout = config.ShutdownTimeout
+	}
+	if c.CacheSize == "" && config.CacheSize != nil {
+		c.CacheSize = strconv.Itoa(*config.CacheSize)
+	}
+	if c.CacheTTL == "" {
+		c.CacheTTL = config.CacheTTL
+	}
+	if c.CacheNegativeTTL == "" {
+		c.CacheNegativeTTL = config.CacheNegativeTTL
+	}
+	if c.TraceExporter == "" {
+		c.TraceExporter = config.TraceExporter
+	}
+	if c.TraceEndpoint == "" {
+		c.TraceEndpoint = config.TraceEndpoint
+	}
+	if c.LogLevel == "" {
+		c.LogLevel = config.LogLevel
+	}
+	if c.LogFormat == "" {
+		c.LogFormat = config.LogFormat
+	}
+	if c.TrustedProxies == "" {
+		c.TrustedProxies = config.TrustedProxies
+	}
+	if c.CheckFailPolicy == "" {
+		c.CheckFailPolicy = config.CheckFailPolicy
+	}
+	if c.MetricsAddress == "" {
+		c.MetricsAddress = config.MetricsAddress
+	}
+	if c.StrictDomains == "" {
+		c.StrictDomains = strconv.FormatBool(config.StrictDomains)
+	}
 
 }
 
@@ -129,6 +375,7 @@ func (c *MyConfig) initFlags() {
 	sFlag := flag.String("s", "", "")
 	cFlag := flag.String("c", "", "")
 	tFlag := flag.String("t", "", "")
+	gFlag := flag.String("g", "", "")
 	flag.Parse()
 
 	if *aFlag != "" {
@@ -152,6 +399,9 @@ func (c *MyConfig) initFlags() {
 	if *tFlag != "" {
 		c.TrustedSubnet = *tFlag
 	}
+	if *gFlag != "" {
+		c.GRPCAddress = *gFlag
+	}
 }
 
 // Get param config
@@ -171,6 +421,72 @@ func (c *MyConfig) GetCfgValue(env string) (string, error) {
 		return c.Config, nil
 	case TrustedSubnet:
 		return c.TrustedSubnet, nil
+	case SweepInterval:
+		return c.SweepInterval, nil
+	case WALFsync:
+		return c.WALFsync, nil
+	case WALCompact:
+		return c.WALCompact, nil
+	case GRPCAddress:
+		return c.GRPCAddress, nil
+	case CookieKeys:
+		return c.CookieKeys, nil
+	case CookieKeyFile:
+		return c.CookieKeyFile, nil
+	case APIKeyRequired:
+		return c.APIKeyRequired, nil
+	case RateLimitBackend:
+		return c.RateLimitBackend, nil
+	case RateLimitUser:
+		return c.RateLimitUser, nil
+	case RateLimitIP:
+		return c.RateLimitIP, nil
+	case RateLimitRoutes:
+		return c.RateLimitRoutes, nil
+	case AllowPrivateURLs:
+		return c.AllowPrivateURLs, nil
+	case BlocklistFile:
+		return c.BlocklistFile, nil
+	case CheckerURL:
+		return c.CheckerURL, nil
+	case CheckOnRedirect:
+		return c.CheckOnRedirect, nil
+	case BlockedAction:
+		return c.BlockedAction, nil
+	case PurgeAfterDays:
+		return c.PurgeAfterDays, nil
+	case PurgeInterval:
+		return c.PurgeInterval, nil
+	case DeleteWorkers:
+		return c.DeleteWorkers, nil
+	case DeleteQueue:
+		return c.DeleteQueue, nil
+	case DeleteFlush:
+		return c.DeleteFlush, nil
+	case ShutdownTimeout:
+		return c.ShutdownTimeout, nil
+	case CacheSize:
+		return c.CacheSize, nil
+	case CacheTTL:
+		return c.CacheTTL, nil
+	case CacheNegativeTTL:
+		return c.CacheNegativeTTL, nil
+	case TraceExporter:
+		return c.TraceExporter, nil
+	case TraceEndpoint:
+		return c.TraceEndpoint, nil
+	case LogLevel:
+		return c.LogLevel, nil
+	case LogFormat:
+		return c.LogFormat, nil
+	case TrustedProxies:
+		return c.TrustedProxies, nil
+	case CheckFailPolicy:
+		return c.CheckFailPolicy, nil
+	case MetricsAddress:
+		return c.MetricsAddress, nil
+	case StrictDomains:
+		return c.StrictDomains, nil
 	}
 
 	return "", errs.ErrUnknownEnvOrFlag
@@ -31,12 +31,27 @@ var ErrURLNotFound = errors.New("url not found")
 // Already has short link
 var ErrAlreadyHasShort = errors.New("already has short")
 
+// Invalid custom alias
+var ErrInvalidAlias = errors.New("invalid alias")
+
+// Alias already taken
+var ErrAliasTaken = errors.New("alias already taken")
+
+// Invalid expiration of link
+var ErrInvalidExpiry = errors.New("invalid expiration")
+
+// Invalid redirect status code
+var ErrInvalidRedirect = errors.New("invalid redirect code")
+
 // URL is gone
 var ErrURLIsGone = errors.New("url is gone")
 
 // File storage not close
 var ErrFileStorageNotClose = errors.New("file storage has not close")
 
+// File storage write error
+var ErrFileStorageWrite = errors.New("file storage write error")
+
 // CorrelationIds is null
 var ErrCorrelation = errors.New("correlationIds is null")
 
@@ -58,9 +73,93 @@ var ErrDatabaseQuery = errors.New("query error in db")
 // Scan rows error DB
 var ErrDatabaseScanRows = errors.New("scan rows error in db")
 
+// Migration error DB
+var ErrMigration = errors.New("migration error in db")
+
+// Unknown migrate command
+var ErrMigrateCommand = errors.New("unknown migrate command, use up|down|status|normalize")
+
 // RAM not avaliable
 var ErrRAMNotAvaliable = errors.New("ram not avaliable")
 
+// Unknown fsync policy of file storage log
+var ErrWALPolicy = errors.New("unknown fsync policy of log")
+
+// Invalid key of cookie keyring
+var ErrCookieKey = errors.New("invalid cookie encryption key")
+
+// Cookie value can`t be decrypted by any key of keyring
+var ErrCookieDecrypt = errors.New("can`t decrypt cookie")
+
+// Bearer token is invalid or expired
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Unknown scope in token request
+var ErrInvalidScope = errors.New("invalid scope")
+
+// Credential has no scope for request
+var ErrInsufficientScope = errors.New("insufficient scope")
+
+// Token can be issued only with cookie credential
+var ErrTokenForCookie = errors.New("token can be issued only to cookie user")
+
+// API key is unknown or revoked
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// API key is required for shorten
+var ErrAPIKeyRequired = errors.New("api key is required")
+
+// API key not found
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// Daily quota of API key is exhausted
+var ErrQuotaExceeded = errors.New("daily quota of api key exceeded")
+
+// URL is invalid
+var ErrInvalidURL = errors.New("invalid url")
+
+// URL targets private or loopback host
+var ErrPrivateURL = errors.New("private or loopback url is not allowed")
+
+// Link destination is blocked
+var ErrLinkBlocked = errors.New("link destination is blocked")
+
+// Blocklist file can`t be loaded
+var ErrBlocklist = errors.New("invalid blocklist")
+
+// Lookup service of destination checker failed
+var ErrLookup = errors.New("destination lookup error")
+
+// Domain of link
+var ErrInvalidDomain = errors.New("invalid domain")
+
+// Domain is not registered for tenant
+var ErrUnknownDomain = errors.New("domain is not registered")
+
+// Domain is registered already
+var ErrDomainExists = errors.New("domain already registered")
+
+// Domain not found
+var ErrDomainNotFound = errors.New("domain not found")
+
+// Domain is registered for other tenant
+var ErrDomainForbidden = errors.New("domain belongs to other tenant")
+
+// Link is turned off by owner
+var ErrLinkInactive = errors.New("link is inactive")
+
+// Update of link without changes
+var ErrEmptyUpdate = errors.New("nothing to update")
+
+// Rate limit exceeded
+var ErrRateLimited = errors.New("too many requests")
+
+// Invalid rate limit in config
+var ErrRateLimitConfig = errors.New("invalid rate limit config")
+
+// Unknown backend of rate limits
+var ErrRateLimitBackend = errors.New("unknown rate limit backend")
+
 // Initialize error logger
 var ErrInitLogger = errors.New("can`t initialize logger")
 
@@ -84,3 +183,42 @@ var ErrConfigInstance = errors.New("get config instance error")
 
 // Config value error
 var ErrConfigValue = errors.New("get config value error: ")
+
+// Invalid retention of deleted links
+var ErrInvalidPurgeDays = errors.New("invalid purge days")
+
+// Batch job not found
+var ErrJobNotFound = errors.New("job not found")
+
+// Queue of batch delete is full
+var ErrQueueFull = errors.New("delete queue is full, try later")
+
+// Cache of links is disabled
+var ErrCacheDisabled = errors.New("cache of links is disabled")
+
+// Unknown exporter of traces
+var ErrTraceExporter = errors.New("unknown trace exporter")
+
+// Invalid endpoint of traces collector
+var ErrTraceEndpoint = errors.New("invalid trace endpoint")
+
+// Unknown format of logs
+var ErrLogFormat = errors.New("unknown log format")
+
+// Storage backend is not ready
+var ErrStorageUnhealthy = errors.New("storage is not ready")
+
+// Delete workers are stopped
+var ErrDeleteWorkersStopped = errors.New("delete workers are stopped")
+
+// GRPC server is not serving
+var ErrGRPCNotServing = errors.New("grpc server is not serving")
+
+// Check of component did not finish in time
+var ErrHealthTimeout = errors.New("health check timeout")
+
+// Invalid list of trusted proxies
+var ErrTrustedProxies = errors.New("invalid trusted proxies")
+
+// Unknown failure policy of destination checker
+var ErrCheckPolicy = errors.New("unknown check fail policy")
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/handlers/middlewares"
+	"github.com/grishagavrin/link-shortener/internal/logger"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/grishagavrin/link-shortener/internal/utils"
+	"go.uber.org/zap"
+)
+
+// APIKeyAuth set user and scopes of api key from X-API-Key header, invalid key is rejected
+func (h *Handler) APIKeyAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		secret := req.Header.Get(middlewares.APIKeyHeader)
+		if secret == "" {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		key, err := h.s.APIKeyByHash(req.Context(), utils.HashAPIKey(secret))
+		if errors.Is(err, errs.ErrAPIKeyNotFound) {
+			http.Error(res, errs.ErrInvalidAPIKey.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			logger.FromContext(req.Context()).Info("get api key error", zap.Error(err))
+			http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		next.ServeHTTP(res, middlewares.WithAPIKey(req, key))
+	})
+}
+
+// useQuota reserve n created links in daily quota of api key, they are returned by refundQuota if save failed.
+// Without api key shorten is allowed if api keys are not required by config.
+func (h *Handler) useQuota(req *http.Request, n int) (int, error) {
+	key, ok := middlewares.GetContextAPIKey(req)
+	if !ok {
+		cfg, err := config.Instance()
+		if err != nil {
+			return http.StatusInternalServerError, errs.ErrInternalSrv
+		}
+		required, _ := cfg.GetCfgValue(config.APIKeyRequired)
+		if ok, _ = strconv.ParseBool(required); ok {
+			return http.StatusUnauthorized, errs.ErrAPIKeyRequired
+		}
+		return http.StatusOK, nil
+	}
+
+	// Zero quota means unlimited key
+	if key.DailyQuota == 0 {
+		return http.StatusOK, nil
+	}
+
+	err := h.s.UseAPIKeyQuota(req.Context(), key.ID, n, key.DailyQuota)
+	if errors.Is(err, errs.ErrQuotaExceeded) {
+		return http.StatusTooManyRequests, err
+	}
+	if err != nil {
+		logger.FromContext(req.Context()).Info("use api key quota error", zap.Error(err))
+		return http.StatusInternalServerError, errs.ErrInternalSrv
+	}
+
+	return http.StatusOK, nil
+}
+
+// refundQuota return quota reserved by useQuota for n links which were not saved
+func (h *Handler) refundQuota(req *http.Request, n int) {
+	key, ok := middlewares.GetContextAPIKey(req)
+	if !ok || key.DailyQuota == 0 {
+		return
+	}
+
+	if err := h.s.UseAPIKeyQuota(req.Context(), key.ID, -n, key.DailyQuota); err != nil {
+		logger.FromContext(req.Context()).Info("refund api key quota error", zap.Error(err))
+	}
+}
+
+// CreateAPIKey godoc
+// @Tags CreateAPIKey
+// @Summary Create api key for partner system
+// @Failure 400 {string} string "invalid scope"
+// @Failure 403 {string} string "status forbidden"
+// @Success 201 {object} object
+// @Router /api/internal/keys [post]
+// CreateAPIKey create api key, secret is returned only once
+func (h *Handler) CreateAPIKey(res http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, fmt.Errorf("%w: %v", errs.ErrReadAll, err).Error(), http.StatusInternalServerError)
+		return
+	}
+
+	reqBody := struct {
+		UserID     string   `json:"user_id"`
+		Scopes     []string `json:"scopes"`
+		DailyQuota int      `json:"daily_quota"`
+	}{}
+
+	decJSON := json.NewDecoder(strings.NewReader(string(body)))
+	decJSON.DisallowUnknownFields()
+
+	if err = decJSON.Decode(&reqBody); err != nil {
+		http.Error(res, fmt.Errorf("%w: %v", errs.ErrFieldsJSON, err).Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(reqBody.Scopes) == 0 {
+		http.Error(res, errs.ErrInvalidScope.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, s := range reqBody.Scopes {
+		if !validScope(s) {
+			http.Error(res, fmt.Errorf("%w: %s", errs.ErrInvalidScope, s).Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if reqBody.DailyQuota < 0 {
+		http.Error(res, errs.ErrBadRequest.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Key without user binding gets new user
+	if reqBody.UserID == "" {
+		reqBody.UserID = uuid.New().String()
+	}
+
+	id, secret, err := utils.NewAPIKey()
+	if err != nil {
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	key := models.APIKey{
+		ID:         id,
+		Hash:       utils.HashAPIKey(secret),
+		UserID:     models.UniqUser(reqBody.UserID),
+		Scopes:     reqBody.Scopes,
+		DailyQuota: reqBody.DailyQuota,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	if err = h.s.SaveAPIKey(req.Context(), key); err != nil {
+		logger.FromContext(req.Context()).Info("save api key error", zap.Error(err))
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resBody := struct {
+		models.APIKey
+		Key string `json:"key"`
+	}{
+		APIKey: key,
+		Key:    secret,
+	}
+
+	js, err := json.Marshal(resBody)
+	if err != nil {
+		http.Error(res, errs.ErrJSONMarshall.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("content-type", "application/json")
+	res.Header().Set("Cache-Control", "no-store")
+	res.WriteHeader(http.StatusCreated)
+	res.Write(js)
+}
+
+// GetAPIKeys godoc
+// @Tags GetAPIKeys
+// @Summary Get all api keys without secrets
+// @Failure 403 {string} string "status forbidden"
+// @Success 200 {array} models.APIKey
+// @Router /api/internal/keys [get]
+// GetAPIKeys get all api keys without secrets
+func (h *Handler) GetAPIKeys(res http.ResponseWriter, req *http.Request) {
+	keys, err := h.s.APIKeys(req.Context())
+	if err != nil {
+		logger.FromContext(req.Context()).Info("get api keys error", zap.Error(err))
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(keys)
+	if err != nil {
+		http.Error(res, errs.ErrJSONMarshall.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Add("Content-Type", "application/json; charset=utf-8")
+	res.WriteHeader(http.StatusOK)
+	res.Write(body)
+}
+
+// DeleteAPIKey godoc
+// @Tags DeleteAPIKey
+// @Summary Revoke api key
+// @Param id path string true "9f86d081884c7d65"
+// @Failure 404 {string} string "api key not found"
+// @Success 204 {string} string
+// @Router /api/internal/keys/{id} [delete]
+// DeleteAPIKey revoke api key
+func (h *Handler) DeleteAPIKey(res http.ResponseWriter, req *http.Request) {
+	err := h.s.DeleteAPIKey(req.Context(), chi.URLParam(req, "id"))
+	if errors.Is(err, errs.ErrAPIKeyNotFound) {
+		http.Error(res, errs.ErrAPIKeyNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(req.Context()).Info("delete api key error", zap.Error(err))
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.WriteHeader(http.StatusNoContent)
+}
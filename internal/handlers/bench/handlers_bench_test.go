@@ -30,7 +30,7 @@ func BenchmarkHandler_SaveTXT(b *testing.B) {
 	// создаем handler
 	h := handlers.New(stor.Repository, l)
 	// создаем роутер
-	routes := routes.NewRouterFacade(h, l, chBatch)
+	routes, _ := routes.NewRouterFacade(h, l, chBatch)
 
 	rtr := chi.NewRouter()
 
@@ -67,7 +67,7 @@ func BenchmarkHandler_SaveJSON(b *testing.B) {
 	// создаем handler
 	h := handlers.New(stor.Repository, l)
 	// создаем роутер
-	routes := routes.NewRouterFacade(h, l, chBatch)
+	routes, _ := routes.NewRouterFacade(h, l, chBatch)
 
 	rtr := chi.NewRouter()
 
@@ -105,7 +105,7 @@ func BenchmarkHandler_SaveBatch(b *testing.B) {
 	// создаем handler
 	h := handlers.New(stor.Repository, l)
 	// создаем роутер
-	routes := routes.NewRouterFacade(h, l, chBatch)
+	routes, _ := routes.NewRouterFacade(h, l, chBatch)
 
 	rtr := chi.NewRouter()
 
@@ -142,7 +142,7 @@ func BenchmarkHandler_GetUrls(b *testing.B) {
 	// создаем handler
 	h := handlers.New(stor.Repository, l)
 	// создаем роутер
-	routes := routes.NewRouterFacade(h, l, chBatch)
+	routes, _ := routes.NewRouterFacade(h, l, chBatch)
 
 	rtr := chi.NewRouter()
 
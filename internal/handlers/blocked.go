@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/grishagavrin/link-shortener/internal/checker"
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/logger"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/grishagavrin/link-shortener/internal/utils"
+	"go.uber.org/zap"
+)
+
+// blockedActionWarn show warning page instead of 451 for blocked links
+const blockedActionWarn = "warn"
+
+// warningPage interstitial for blocked links
+var warningPage = template.Must(template.New("warning").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="robots" content="noindex"><title>Warning</title></head>
+<body>
+<h1>This link may be unsafe</h1>
+<p>The destination was flagged: {{.Reason}}</p>
+<p>If you trust it, you can continue to <a href="{{.Origin}}" rel="noreferrer nofollow">{{.Origin}}</a></p>
+</body>
+</html>
+`))
+
+// SetChecker set destination checker for create and redirect, nil checker turns screening off
+func (h *Handler) SetChecker(chk checker.DestinationChecker) {
+	h.checker = chk
+}
+
+// screenLink check destination of saved link and flag it if it is blocked now,
+// link blocked by failed check is not flagged
+func (h *Handler) screenLink(ctx context.Context, shortKey models.ShortURL, link *models.Link) {
+	v := checker.Screen(ctx, h.checker, string(link.Origin), logger.FromContext(ctx))
+	if !v.Blocked {
+		return
+	}
+
+	link.Blocked, link.BlockReason = true, v.Reason
+	if v.Failed {
+		return
+	}
+	if err := h.s.SetBlocked(ctx, shortKey, true, v.Reason); err != nil {
+		logger.FromContext(ctx).Info("flag blocked link error", zap.Error(err))
+	}
+}
+
+// blockedResponse answer with 451 or warning page by config
+func (h *Handler) blockedResponse(res http.ResponseWriter, req *http.Request, link models.Link) {
+	res.Header().Set("Cache-Control", "no-store")
+
+	cfg, err := config.Instance()
+	if err == nil {
+		action, _ := cfg.GetCfgValue(config.BlockedAction)
+		if action == blockedActionWarn {
+			res.Header().Set("Content-Type", "text/html; charset=utf-8")
+			res.WriteHeader(http.StatusOK)
+			if err = warningPage.Execute(res, link); err != nil {
+				logger.FromContext(req.Context()).Info("warning page error", zap.Error(err))
+			}
+			return
+		}
+	}
+
+	http.Error(res, errs.ErrLinkBlocked.Error(), http.StatusUnavailableForLegalReasons)
+}
+
+// GetBlockedLinks godoc
+// @Tags GetBlockedLinks
+// @Summary Get links flagged by destination checker
+// @Failure 403 {string} string "status forbidden"
+// @Success 200 {array} models.BlockedLink
+// @Router /api/internal/blocked [get]
+// GetBlockedLinks get links flagged by destination checker
+func (h *Handler) GetBlockedLinks(res http.ResponseWriter, req *http.Request) {
+	links, err := h.s.BlockedLinks(req.Context())
+	if err != nil {
+		logger.FromContext(req.Context()).Info("get blocked links error", zap.Error(err))
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(links)
+	if err != nil {
+		http.Error(res, errs.ErrJSONMarshall.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Add("Content-Type", "application/json; charset=utf-8")
+	res.WriteHeader(http.StatusOK)
+	res.Write(body)
+}
+
+// UnblockLink godoc
+// @Tags UnblockLink
+// @Summary Remove blocked flag from link
+// @Param id path string true "2dace3f162eb9f0d"
+// @Param domain query string false "go.brand.com"
+// @Failure 404 {string} string "url not found"
+// @Success 204 {string} string
+// @Router /api/internal/blocked/{id} [delete]
+// UnblockLink remove blocked flag from link
+func (h *Handler) UnblockLink(res http.ResponseWriter, req *http.Request) {
+	q := chi.URLParam(req, "id")
+
+	if !utils.IsValidShortKey(q) {
+		http.Error(res, errs.ErrCorrectURL.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := h.s.SetBlocked(req.Context(), linkKey(req, q), false, "")
+	if errors.Is(err, errs.ErrURLNotFound) {
+		http.Error(res, errs.ErrURLNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(req.Context()).Info("unblock link error", zap.Error(fmt.Errorf("%s: %w", q, err)))
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.WriteHeader(http.StatusNoContent)
+}
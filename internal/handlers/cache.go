@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+)
+
+// cacheStater repository with cache of links
+type cacheStater interface {
+	CacheStats() models.CacheStats
+}
+
+// GetCacheStats godoc
+// @Tags GetCacheStats
+// @Summary Counters of cache of links, trusted subnet only
+// @Failure 404 {string} string "cache is disabled"
+// @Success 200 {object} models.CacheStats
+// @Router /api/internal/cache [get]
+// GetCacheStats get hits, misses and evictions of cache of links
+func (h *Handler) GetCacheStats(res http.ResponseWriter, req *http.Request) {
+	c, ok := h.s.(cacheStater)
+	if !ok {
+		http.Error(res, errs.ErrCacheDisabled.Error(), http.StatusNotFound)
+		return
+	}
+
+	body, err := json.Marshal(c.CacheStats())
+	if err != nil {
+		http.Error(res, errs.ErrJSONMarshall.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Add("Content-Type", "application/json; charset=utf-8")
+	res.WriteHeader(http.StatusOK)
+	res.Write(body)
+}
@@ -2,16 +2,28 @@
 package delete
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/go-chi/chi"
+	"github.com/grishagavrin/link-shortener/internal/config"
 	"github.com/grishagavrin/link-shortener/internal/errs"
 	"github.com/grishagavrin/link-shortener/internal/handlers/middlewares"
+	"github.com/grishagavrin/link-shortener/internal/jobs"
+	"github.com/grishagavrin/link-shortener/internal/logger"
 	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/grishagavrin/link-shortener/internal/tracing"
+	"github.com/grishagavrin/link-shortener/internal/utils"
 	"go.uber.org/zap"
 )
 
+// retryAfter seconds to wait before retry if queue is full
+const retryAfter = 1
+
 // Handler struct for delete batch
 type Handler struct {
 	l       *zap.Logger
@@ -30,40 +42,210 @@ func New(l *zap.Logger, chBatch chan models.BatchDelete) *Handler {
 // @Tags DeleteBatch
 // @Summary Delete handler with fan in channel
 // @Failure 500 {string} string "internal error"
-// @Success 200 {array} array
+// @Failure 503 {string} string "queue is full"
+// @Success 202 {object} jobRes
 // @Router /api/user/urls [delete]
 // Delete handler with fan in channel
 func (h Handler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
-	var correlationIDs []string
+	correlationIDs, ok := readIDs(res, req)
+	if !ok {
+		return
+	}
+
+	h.enqueue(res, req, models.BatchDelete{
+		UserID: string(middlewares.GetContextUserID(req)),
+		URLs:   correlationIDs,
+		Trace:  tracing.Carrier(req.Context()),
+	})
+}
+
+// RestoreBatch godoc
+// @Tags RestoreBatch
+// @Summary Restore soft deleted links with fan in channel
+// @Failure 400 {string} string "bad request"
+// @Failure 503 {string} string "queue is full"
+// @Success 202 {object} jobRes
+// @Router /api/user/urls/restore [post]
+// Restore handler with fan in channel
+func (h Handler) Restore(res http.ResponseWriter, req *http.Request) {
+	correlationIDs, ok := readIDs(res, req)
+	if !ok {
+		return
+	}
+
+	h.enqueue(res, req, models.BatchDelete{
+		Op:     models.BatchOpRestore,
+		UserID: string(middlewares.GetContextUserID(req)),
+		URLs:   correlationIDs,
+		Trace:  tracing.Carrier(req.Context()),
+	})
+}
+
+// GetJob godoc
+// @Tags GetJob
+// @Summary Status of batch job with outcomes of links
+// @Failure 404 {string} string "not found"
+// @Success 200 {object} models.Job
+// @Router /api/user/jobs/{id} [get]
+// Get status of batch job of user
+func (h Handler) GetJob(res http.ResponseWriter, req *http.Request) {
 	userID := middlewares.GetContextUserID(req)
 
+	job, err := jobs.Instance().Get(string(userID), chi.URLParam(req, "id"))
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	out, err := json.Marshal(job)
+	if err != nil {
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	res.Write(out)
+}
+
+// jobRes response with id of queued batch job
+type jobRes struct {
+	JobID  string `json:"job_id"`
+	Status string `json:"status"`
+}
+
+// enqueue create job for batch, push batch to queue and answer 202 with job id or 503 if queue is full
+func (h Handler) enqueue(res http.ResponseWriter, req *http.Request, chStruct models.BatchDelete) {
+	job, err := jobs.Instance().Create(chStruct.UserID)
+	if err != nil {
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+	chStruct.JobID = job.ID
+
+	out, err := json.Marshal(jobRes{JobID: job.ID, Status: job.Status})
+	if err != nil {
+		jobs.Instance().Discard(job.ID)
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !h.push(res, req, chStruct) {
+		jobs.Instance().Discard(job.ID)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Location", "/api/user/jobs/"+job.ID)
+	res.WriteHeader(http.StatusAccepted)
+	res.Write(out)
+}
+
+// push batch to queue without blocking, answer 503 if queue is full
+func (h Handler) push(res http.ResponseWriter, req *http.Request, chStruct models.BatchDelete) bool {
+	select {
+	case h.chBatch <- chStruct:
+		logger.FromContext(req.Context()).Info("new chStruct", zap.String("op", chStruct.Op), zap.String("job", chStruct.JobID))
+		return true
+	default:
+		res.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		http.Error(res, errs.ErrQueueFull.Error(), http.StatusServiceUnavailable)
+		return false
+	}
+}
+
+// purgeReq request for purge of deleted links
+type purgeReq struct {
+	OlderThanDays *int `json:"older_than_days,omitempty"`
+}
+
+// purgeRes response for purge of deleted links
+type purgeRes struct {
+	Before time.Time `json:"before"`
+}
+
+// Purge godoc
+// @Tags Purge
+// @Summary Hard delete links soft deleted before retention period
+// @Failure 400 {string} string "bad request"
+// @Failure 503 {string} string "queue is full"
+// @Success 202 {object} purgeRes
+// @Router /api/internal/purge [post]
+// Purge handler with fan in channel
+func (h Handler) Purge(res http.ResponseWriter, req *http.Request) {
 	body, err := io.ReadAll(req.Body)
 	if err != nil {
 		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusBadRequest)
 		return
 	}
 
-	err = json.Unmarshal(body, &correlationIDs)
+	var reqBody purgeReq
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err = json.Unmarshal(body, &reqBody); err != nil {
+			http.Error(res, errs.ErrCorrectURL.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var days int
+	if reqBody.OlderThanDays != nil {
+		days = *reqBody.OlderThanDays
+	} else {
+		days, err = utils.PurgeDays()
+		if err != nil {
+			http.Error(res, errs.ErrInvalidPurgeDays.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Just deleted links can be restored, so they are never purged by request
+	if days < config.PURGEMINDAYS {
+		http.Error(res, errs.ErrInvalidPurgeDays.Error(), http.StatusBadRequest)
+		return
+	}
+
+	chStruct := models.BatchDelete{
+		Op:     models.BatchOpPurge,
+		Before: utils.PurgeBefore(time.Now(), days),
+		Trace:  tracing.Carrier(req.Context()),
+	}
+
+	out, err := json.Marshal(purgeRes{Before: chStruct.Before.UTC()})
 	if err != nil {
-		http.Error(res, errs.ErrCorrectURL.Error(), http.StatusBadRequest)
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Validate count
-	if len(correlationIDs) == 0 {
-		http.Error(res, errs.ErrCorrectURL.Error(), http.StatusBadRequest)
+	if !h.push(res, req, chStruct) {
 		return
 	}
 
+	res.Header().Set("Content-Type", "application/json")
 	res.WriteHeader(http.StatusAccepted)
+	res.Write(out)
+}
 
-	chStruct := models.BatchDelete{
-		UserID: string(userID),
-		URLs:   correlationIDs,
+// readIDs read not empty json array of link ids from request body
+func readIDs(res http.ResponseWriter, req *http.Request) ([]string, bool) {
+	var correlationIDs []string
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	err = json.Unmarshal(body, &correlationIDs)
+	if err != nil {
+		http.Error(res, errs.ErrCorrectURL.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Validate count
+	if len(correlationIDs) == 0 {
+		http.Error(res, errs.ErrCorrectURL.Error(), http.StatusBadRequest)
+		return nil, false
 	}
 
-	go func() {
-		h.l.Info("new chStruct")
-		h.chBatch <- chStruct
-	}()
+	return correlationIDs, true
 }
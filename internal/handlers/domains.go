@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/handlers/middlewares"
+	"github.com/grishagavrin/link-shortener/internal/logger"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/grishagavrin/link-shortener/internal/utils"
+	"go.uber.org/zap"
+)
+
+// linkDomain get registered domain of caller for new link, empty domain is default one
+func (h *Handler) linkDomain(req *http.Request, domain string) (string, int, error) {
+	ctx := req.Context()
+	host, err := utils.ResolveDomain(ctx, h.s.DomainByHost, domain, middlewares.GetContextUserID(req))
+	if errors.Is(err, errs.ErrInvalidDomain) || errors.Is(err, errs.ErrUnknownDomain) {
+		return "", http.StatusBadRequest, err
+	}
+	if errors.Is(err, errs.ErrDomainForbidden) {
+		return "", http.StatusForbidden, err
+	}
+	if err != nil {
+		logger.FromContext(ctx).Info("resolve domain error", zap.Error(err))
+		return "", http.StatusInternalServerError, errs.ErrInternalSrv
+	}
+	return host, 0, nil
+}
+
+// linkKey get key of link from id and optional domain query param
+func linkKey(req *http.Request, id string) models.ShortURL {
+	domain := strings.ToLower(req.URL.Query().Get("domain"))
+	return models.LinkKey(domain, models.ShortURL(id))
+}
+
+// CreateDomain godoc
+// @Tags CreateDomain
+// @Summary Register custom short domain of tenant
+// @Failure 400 {string} string "invalid domain"
+// @Failure 409 {string} string "domain already registered"
+// @Success 201 {object} models.Domain
+// @Router /api/internal/domains [post]
+// CreateDomain register custom short domain of tenant
+func (h *Handler) CreateDomain(res http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, fmt.Errorf("%w: %v", errs.ErrReadAll, err).Error(), http.StatusInternalServerError)
+		return
+	}
+
+	reqBody := struct {
+		Host   string `json:"host"`
+		Tenant string `json:"tenant"`
+	}{}
+
+	decJSON := json.NewDecoder(strings.NewReader(string(body)))
+	decJSON.DisallowUnknownFields()
+
+	if err = decJSON.Decode(&reqBody); err != nil {
+		http.Error(res, fmt.Errorf("%w: %v", errs.ErrFieldsJSON, err).Error(), http.StatusBadRequest)
+		return
+	}
+
+	host, err := utils.NormalizeDomain(reqBody.Host)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if reqBody.Tenant == "" {
+		http.Error(res, fmt.Errorf("%w: empty tenant", errs.ErrFieldsJSON).Error(), http.StatusBadRequest)
+		return
+	}
+
+	domain := models.Domain{
+		Host:      host,
+		Tenant:    reqBody.Tenant,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err = h.s.SaveDomain(req.Context(), domain)
+	if errors.Is(err, errs.ErrDomainExists) {
+		http.Error(res, errs.ErrDomainExists.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		logger.FromContext(req.Context()).Info("save domain error", zap.Error(err))
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	js, err := json.Marshal(domain)
+	if err != nil {
+		http.Error(res, errs.ErrJSONMarshall.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("content-type", "application/json")
+	res.WriteHeader(http.StatusCreated)
+	res.Write(js)
+}
+
+// GetDomains godoc
+// @Tags GetDomains
+// @Summary Get all custom short domains
+// @Failure 403 {string} string "status forbidden"
+// @Success 200 {array} models.Domain
+// @Router /api/internal/domains [get]
+// GetDomains get all custom short domains
+func (h *Handler) GetDomains(res http.ResponseWriter, req *http.Request) {
+	domains, err := h.s.Domains(req.Context())
+	if err != nil {
+		logger.FromContext(req.Context()).Info("get domains error", zap.Error(err))
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(domains)
+	if err != nil {
+		http.Error(res, errs.ErrJSONMarshall.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Add("Content-Type", "application/json; charset=utf-8")
+	res.WriteHeader(http.StatusOK)
+	res.Write(body)
+}
+
+// DeleteDomain godoc
+// @Tags DeleteDomain
+// @Summary Remove custom short domain, its links are not resolved anymore
+// @Param host path string true "go.brand.com"
+// @Failure 404 {string} string "domain not found"
+// @Success 204 {string} string
+// @Router /api/internal/domains/{host} [delete]
+// DeleteDomain remove custom short domain, its links are not resolved anymore
+func (h *Handler) DeleteDomain(res http.ResponseWriter, req *http.Request) {
+	err := h.s.DeleteDomain(req.Context(), strings.ToLower(chi.URLParam(req, "host")))
+	if errors.Is(err, errs.ErrDomainNotFound) {
+		http.Error(res, errs.ErrDomainNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(req.Context()).Info("delete domain error", zap.Error(err))
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.WriteHeader(http.StatusNoContent)
+}
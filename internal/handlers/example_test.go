@@ -23,7 +23,7 @@ func ExampleHandler_SaveTXT() {
 	// создаем handler
 	h := handlers.New(stor.Repository, l)
 	// создаем роутер
-	r := routes.NewRouterFacade(h, l, chBatch)
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
 	// create server
 	ts := httptest.NewServer(r.HTTPRoute.Route)
 	defer ts.Close()
@@ -48,7 +48,7 @@ func ExampleHandler_SaveJSON() {
 	// создаем handler
 	h := handlers.New(stor.Repository, l)
 	// создаем роутер
-	r := routes.NewRouterFacade(h, l, chBatch)
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
 	// create server
 	ts := httptest.NewServer(r.HTTPRoute.Route)
 	defer ts.Close()
@@ -73,7 +73,7 @@ func ExampleHandler_GetLink() {
 	// создаем handler
 	h := handlers.New(stor.Repository, l)
 	// создаем роутер
-	r := routes.NewRouterFacade(h, l, chBatch)
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
 	// create server
 	ts := httptest.NewServer(r.HTTPRoute.Route)
 	defer ts.Close()
@@ -7,40 +7,66 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi"
+	"github.com/grishagavrin/link-shortener/internal/checker"
 	"github.com/grishagavrin/link-shortener/internal/config"
 	"github.com/grishagavrin/link-shortener/internal/errs"
 	"github.com/grishagavrin/link-shortener/internal/handlers/middlewares"
+	"github.com/grishagavrin/link-shortener/internal/health"
+	"github.com/grishagavrin/link-shortener/internal/logger"
 	"github.com/grishagavrin/link-shortener/internal/storage/models"
-	"github.com/grishagavrin/link-shortener/internal/utils/db"
+	"github.com/grishagavrin/link-shortener/internal/utils"
 	"go.uber.org/zap"
 )
 
 // Repository interface for working with global storage
 type Repository interface {
-	GetLinkDB(context.Context, models.ShortURL) (models.Origin, error)
-	SaveLinkDB(context.Context, models.UniqUser, models.Origin) (models.ShortURL, error)
+	GetLinkDB(context.Context, models.ShortURL) (models.Link, error)
+	SaveLinkDB(context.Context, models.UniqUser, models.Origin, models.LinkOptions) (models.ShortURL, error)
 	LinksByUser(context.Context, models.UniqUser) (models.ShortLinks, error)
 	SaveBatch(context.Context, models.UniqUser, []models.BatchReqURL) ([]models.BatchResURL, error)
-	BunchUpdateAsDeleted(chan models.BatchDelete)
+	BunchUpdateAsDeleted(context.Context, []models.BatchDelete) error
 	GetStats(context.Context, models.UniqUser) (models.GetStatsResURL, error)
+	SaveClick(models.ClickEvent)
+	ClickStats(context.Context, models.UniqUser, models.ShortURL) (models.ClickStats, error)
+	SaveAPIKey(context.Context, models.APIKey) error
+	APIKeyByHash(context.Context, string) (models.APIKey, error)
+	APIKeys(context.Context) ([]models.APIKey, error)
+	DeleteAPIKey(context.Context, string) error
+	UseAPIKeyQuota(context.Context, string, int, int) error
+	SetBlocked(context.Context, models.ShortURL, bool, string) error
+	BlockedLinks(context.Context) ([]models.BlockedLink, error)
+	SaveDomain(context.Context, models.Domain) error
+	DomainByHost(context.Context, string) (models.Domain, error)
+	Domains(context.Context) ([]models.Domain, error)
+	DeleteDomain(context.Context, string) error
+	UpdateLink(context.Context, models.UniqUser, models.ShortURL, models.LinkUpdate) (models.Link, error)
+	LinkRevisions(context.Context, models.UniqUser, models.ShortURL) ([]models.LinkRevision, error)
+	Health(context.Context) error
 }
 
 // Handler general type fo handler
 type Handler struct {
-	s Repository
-	l *zap.Logger
+	s       Repository
+	l       *zap.Logger
+	checker checker.DestinationChecker
+	ready   *health.Registry
 }
 
-// New allocation new handler
+// New allocation new handler, readiness is checked by storage until SetHealth
 func New(stor Repository, l *zap.Logger) *Handler {
+	ready := health.New()
+	ready.Add("storage", stor.Health)
+
 	return &Handler{
-		s: stor,
-		l: l,
+		s:     stor,
+		l:     l,
+		ready: ready,
 	}
 }
 
@@ -58,36 +84,118 @@ func (h *Handler) GetLink(res http.ResponseWriter, req *http.Request) {
 
 	q := chi.URLParam(req, "id")
 
-	if len(q) != config.LENHASH {
+	if !utils.IsValidShortKey(q) {
 		http.Error(res, errs.ErrCorrectURL.Error(), http.StatusBadRequest)
 		return
 	}
 
-	h.l.Info("Get ID:", zap.String("id", q))
-	foundedURL, err := h.s.GetLinkDB(ctx, models.ShortURL(q))
+	// the same key can be used on different domains, links of unknown hosts are not found in strict mode
+	domain, err := utils.RequestDomain(ctx, h.s.DomainByHost, req.Host)
+	if errors.Is(err, errs.ErrDomainNotFound) {
+		http.Error(res, errs.ErrURLNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(ctx).Info("request domain error", zap.Error(err))
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+	key := models.LinkKey(domain, models.ShortURL(q))
+
+	foundedLink, err := h.s.GetLinkDB(ctx, key)
 
 	if err != nil {
 		if errors.Is(err, errs.ErrURLIsGone) {
-			h.l.Info(errs.ErrURLIsGone.Error(), zap.Error(err))
+			logger.FromContext(req.Context()).Info(errs.ErrURLIsGone.Error(), zap.Error(err))
 			http.Error(res, errs.ErrURLIsGone.Error(), http.StatusGone)
 			return
 		}
 
-		h.l.Info(errs.ErrBadRequest.Error(), zap.Error(err))
+		logger.FromContext(req.Context()).Info(errs.ErrBadRequest.Error(), zap.Error(err))
 		http.Error(res, errs.ErrBadRequest.Error(), http.StatusBadRequest)
 		return
 	}
 
-	http.Redirect(res, req, string(foundedURL), http.StatusTemporaryRedirect)
+	// link is turned off by owner
+	if foundedLink.Inactive {
+		http.Error(res, errs.ErrLinkInactive.Error(), http.StatusNotFound)
+		return
+	}
+
+	// destination can be blocked after link was saved, link unblocked by admin is not screened
+	if !foundedLink.Blocked && !foundedLink.Allowed && checker.OnRedirect() {
+		h.screenLink(ctx, key, &foundedLink)
+	}
+	if foundedLink.Blocked {
+		h.blockedResponse(res, req, foundedLink)
+		return
+	}
+
+	// Record click asynchronously
+	h.s.SaveClick(models.ClickEvent{
+		Short:     key,
+		ClickedAt: time.Now().UTC(),
+		Referrer:  req.Referer(),
+		UserAgent: req.UserAgent(),
+		IPBucket:  utils.IPBucket(utils.ClientIP(req)),
+	})
+
+	res.Header().Set("Cache-Control", utils.CacheControl(foundedLink, time.Now()))
+	http.Redirect(res, req, string(foundedLink.Origin), foundedLink.RedirectCode)
+}
+
+// GetLinkStats godoc
+// @Tags GetLinkStats
+// @Summary Get clicks statistics of user link
+// @Param id path string true "2dace3f162eb9f0d"
+// @Param domain query string false "go.brand.com"
+// @Failure 404 {string} string "url not found"
+// @Success 200 {object} models.ClickStats
+// @Router /api/user/urls/{id}/stats [get]
+// GetLinkStats get clicks statistics of user link
+func (h *Handler) GetLinkStats(res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	q := chi.URLParam(req, "id")
+
+	if !utils.IsValidShortKey(q) {
+		http.Error(res, errs.ErrCorrectURL.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := middlewares.GetContextUserID(req)
+
+	stats, err := h.s.ClickStats(ctx, userID, linkKey(req, q))
+	if errors.Is(err, errs.ErrNotFoundURL) {
+		http.Error(res, errs.ErrURLNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(req.Context()).Info("get clicks stats error", zap.Error(err))
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(stats)
+	if err != nil {
+		http.Error(res, errs.ErrJSONMarshall.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Add("Content-Type", "application/json; charset=utf-8")
+	res.WriteHeader(http.StatusOK)
+	res.Write(body)
 }
 
 // SaveBatch godoc
 // @Tags SaveBatch
 // @Summary Request to save data and return multiply
 // @Failure 400 {string} string "bad request"
+// @Failure 409 {string} string "alias already taken"
 // @Success 200 {object} object
 // @Router /api/shorten/batch [post]
-// SaveBatch save data and return multiply
+// SaveBatch save data and return multiply, urls which are already shortened get existing links and their aliases are ignored
 func (h *Handler) SaveBatch(res http.ResponseWriter, req *http.Request) {
 	ctx, cancel := context.WithCancel(req.Context())
 	defer cancel()
@@ -106,7 +214,64 @@ func (h *Handler) SaveBatch(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// validate urls, custom aliases and expiration
+	for k, u := range urls {
+		urls[k].Origin, err = utils.NormalizeURL(u.Origin)
+		if err != nil {
+			http.Error(res, fmt.Errorf("%w: %s", err, u.CorrID).Error(), http.StatusBadRequest)
+			return
+		}
+
+		var code int
+		urls[k].Domain, code, err = h.linkDomain(req, u.Domain)
+		if err != nil {
+			http.Error(res, fmt.Errorf("%w: %s", err, u.CorrID).Error(), code)
+			return
+		}
+
+		if u.Alias != "" {
+			if err = utils.ValidateAlias(u.Alias); err != nil {
+				http.Error(res, fmt.Errorf("%w: %s", err, u.Alias).Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		urls[k].ExpiresAt, err = utils.LinkExpiry(u.ExpiresIn, u.ExpiresAt)
+		if err != nil {
+			http.Error(res, fmt.Errorf("%w: %s", err, u.CorrID).Error(), http.StatusBadRequest)
+			return
+		}
+		urls[k].ExpiresIn = 0
+
+		if err = utils.ValidateRedirect(u.Redirect); err != nil {
+			http.Error(res, fmt.Errorf("%w: %s", err, u.CorrID).Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// destinations of batch are checked concurrently
+	origins := make([]string, len(urls))
+	for k := range urls {
+		origins[k] = urls[k].Origin
+	}
+	for k, v := range checker.ScreenAll(ctx, h.checker, origins, logger.FromContext(ctx)) {
+		urls[k].Blocked, urls[k].BlockReason = v.Blocked, v.Reason
+	}
+
+	// links created by api key are counted in its daily quota
+	if code, err := h.useQuota(req, len(urls)); err != nil {
+		http.Error(res, err.Error(), code)
+		return
+	}
+
 	shorts, err := h.s.SaveBatch(ctx, middlewares.GetContextUserID(req), urls)
+	if err != nil {
+		h.refundQuota(req, len(urls))
+	}
+	if errors.Is(err, errs.ErrAliasTaken) {
+		http.Error(res, errs.ErrAliasTaken.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusBadRequest)
 		return
@@ -128,7 +293,7 @@ func (h *Handler) SaveBatch(res http.ResponseWriter, req *http.Request) {
 
 	// prepare results
 	for k := range shorts {
-		shorts[k].Short = fmt.Sprintf("%s/%s", baseURL, shorts[k].Short)
+		shorts[k].Short = utils.LinkURL(baseURL, models.LinkKey(shorts[k].Domain, models.ShortURL(shorts[k].Short)))
 	}
 
 	body, err = json.Marshal(shorts)
@@ -149,6 +314,8 @@ func (h *Handler) SaveBatch(res http.ResponseWriter, req *http.Request) {
 // SaveTXT godoc
 // @Tags SaveTXT
 // @Summary Convert link to shorting and store in database
+// @Param redirect query int false "301, 302, 307 or 308"
+// @Param domain query string false "go.brand.com"
 // @Failure 400 {string} string "bad request"
 // @Success 200 {string} string
 // @Router / [post]
@@ -184,16 +351,57 @@ func (h *Handler) SaveTXT(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// url is stored in canonical form
+	body, err = utils.NormalizeURL(body)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// redirect mode is optional query param
+	var opts models.LinkOptions
+	if v := req.URL.Query().Get("redirect"); v != "" {
+		code, err := strconv.Atoi(v)
+		if err == nil {
+			err = utils.ValidateRedirect(code)
+		}
+		if err != nil {
+			http.Error(res, errs.ErrInvalidRedirect.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.RedirectCode = code
+	}
+
+	// short domain is optional query param too
+	domain, code, err := h.linkDomain(req, req.URL.Query().Get("domain"))
+	if err != nil {
+		http.Error(res, err.Error(), code)
+		return
+	}
+	opts.Domain = domain
+
+	v := checker.Screen(ctx, h.checker, body, logger.FromContext(ctx))
+	opts.Blocked, opts.BlockReason = v.Blocked, v.Reason
+
+	// links created by api key are counted in its daily quota
+	if code, err := h.useQuota(req, 1); err != nil {
+		http.Error(res, err.Error(), code)
+		return
+	}
+
 	userID := middlewares.GetContextUserID(req)
 
-	origin, err := h.s.SaveLinkDB(ctx, models.UniqUser(userID), models.Origin(body))
+	origin, err := h.s.SaveLinkDB(ctx, models.UniqUser(userID), models.Origin(body), opts)
+	if err != nil {
+		h.refundQuota(req, 1)
+	}
 
 	status := http.StatusCreated
 	if errors.Is(err, errs.ErrAlreadyHasShort) {
 		status = http.StatusConflict
 	}
 
-	response := fmt.Sprintf("%s/%s", baseURL, origin)
+	response := utils.LinkURL(baseURL, models.LinkKey(opts.Domain, origin))
 	res.Header().Set("content-type", "text/plain; charset=utf-8")
 	res.WriteHeader(status)
 	res.Write([]byte(response))
@@ -203,6 +411,7 @@ func (h *Handler) SaveTXT(res http.ResponseWriter, req *http.Request) {
 // @Tags SaveJSON
 // @Summary Convert link to shorting and store in database
 // @Failure 400 {string} string "bad request"
+// @Failure 409 {string} string "alias already taken"
 // @Success 200 {object} object
 // @Router /api/shorten [post]
 // SaveJSON convert link to shorting and store in database
@@ -231,7 +440,12 @@ func (h *Handler) SaveJSON(res http.ResponseWriter, req *http.Request) {
 	}
 
 	reqBody := struct {
-		URL string `json:"url"`
+		URL       string    `json:"url"`
+		Alias     string    `json:"alias"`
+		ExpiresIn int64     `json:"expires_in"`
+		ExpiresAt time.Time `json:"expires_at"`
+		Redirect  int       `json:"redirect"`
+		Domain    string    `json:"domain"`
 	}{}
 
 	decJSON := json.NewDecoder(strings.NewReader(string(body)))
@@ -242,9 +456,67 @@ func (h *Handler) SaveJSON(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// url is stored in canonical form
+	reqBody.URL, err = utils.NormalizeURL(reqBody.URL)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// custom alias is optional
+	if reqBody.Alias != "" {
+		if err = utils.ValidateAlias(reqBody.Alias); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// expiration is optional too
+	expiresAt, err := utils.LinkExpiry(reqBody.ExpiresIn, reqBody.ExpiresAt)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = utils.ValidateRedirect(reqBody.Redirect); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	domain, code, err := h.linkDomain(req, reqBody.Domain)
+	if err != nil {
+		http.Error(res, err.Error(), code)
+		return
+	}
+
+	// links created by api key are counted in its daily quota
+	if code, err := h.useQuota(req, 1); err != nil {
+		http.Error(res, err.Error(), code)
+		return
+	}
+
 	userID := middlewares.GetContextUserID(req)
 
-	dbURL, err := h.s.SaveLinkDB(ctx, models.UniqUser(userID), models.Origin(reqBody.URL))
+	v := checker.Screen(ctx, h.checker, reqBody.URL, logger.FromContext(ctx))
+
+	opts := models.LinkOptions{
+		Domain:       domain,
+		Alias:        models.ShortURL(reqBody.Alias),
+		ExpiresAt:    expiresAt,
+		RedirectCode: reqBody.Redirect,
+		Blocked:      v.Blocked,
+		BlockReason:  v.Reason,
+	}
+
+	dbURL, err := h.s.SaveLinkDB(ctx, models.UniqUser(userID), models.Origin(reqBody.URL), opts)
+	if err != nil {
+		h.refundQuota(req, 1)
+	}
+	if errors.Is(err, errs.ErrAliasTaken) {
+		http.Error(res, errs.ErrAliasTaken.Error(), http.StatusConflict)
+		return
+	}
+
 	status := http.StatusCreated
 	if errors.Is(err, errs.ErrAlreadyHasShort) {
 		status = http.StatusConflict
@@ -253,7 +525,7 @@ func (h *Handler) SaveJSON(res http.ResponseWriter, req *http.Request) {
 	resBody := struct {
 		Result string `json:"result"`
 	}{
-		Result: fmt.Sprintf("%s/%s", baseURL, dbURL),
+		Result: utils.LinkURL(baseURL, models.LinkKey(domain, dbURL)),
 	}
 
 	js, err := json.Marshal(resBody)
@@ -269,27 +541,21 @@ func (h *Handler) SaveJSON(res http.ResponseWriter, req *http.Request) {
 
 // GetPing godoc
 // @Tags GetPing
-// @Summary Implement ping connection for sql database storage
+// @Summary Implement ping of active storage
 // @Failure 500 {string} string "internal error"
 // @Success 200 {string} string
 // @Router /ping [get]
-// GetPing implement ping connection for sql database storage
+// GetPing implement ping of active storage
 func (h *Handler) GetPing(res http.ResponseWriter, req *http.Request) {
 	ctx, cancel := context.WithCancel(req.Context())
 	defer cancel()
 
-	conn, err := db.SQLDBConnection(h.l)
-	if err == nil {
-		err = conn.Ping(ctx)
-		if err != nil {
-			res.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		res.WriteHeader(http.StatusOK)
-	} else {
-		h.l.Info("not connect to db", zap.Error(err))
+	if err := h.s.Health(ctx); err != nil {
+		logger.FromContext(req.Context()).Info("storage is not ready", zap.Error(err))
 		res.WriteHeader(http.StatusInternalServerError)
+		return
 	}
+	res.WriteHeader(http.StatusOK)
 }
 
 // GetLinks godoc
@@ -335,7 +601,7 @@ func (h *Handler) GetLinks(res http.ResponseWriter, req *http.Request) {
 	// get all links
 	for k, v := range links {
 		lks = append(lks, coupleLinks{
-			Short:  fmt.Sprintf("%s/%s", baseURL, string(k)),
+			Short:  utils.LinkURL(baseURL, k),
 			Origin: string(v),
 		})
 	}
@@ -357,60 +623,124 @@ func (h *Handler) GetLinks(res http.ResponseWriter, req *http.Request) {
 // @Failure 403 {string} string "status forbidden"
 // @Success 200 {object} object
 // @Router /api/internal/stats [get]
-// GetStats get statistics quantity urls and users
+// GetStats get statistics quantity urls and users, trusted subnet is checked by middleware
 func (h *Handler) GetStats(res http.ResponseWriter, req *http.Request) {
 	ctx, cancel := context.WithCancel(req.Context())
 	defer cancel()
 
-	// config instance
-	cfg, err := config.Instance()
-	if errors.Is(err, errs.ErrENVLoading) {
+	userID := middlewares.GetContextUserID(req)
+
+	// get count users and urls from storage
+	foundedStat, err := h.s.GetStats(ctx, models.UniqUser(userID))
+	if errors.Is(err, errs.ErrInternalSrv) {
 		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// config value
-	trustedCIDR, err := cfg.GetCfgValue(config.TrustedSubnet)
-	if errors.Is(err, errs.ErrUnknownEnvOrFlag) {
-		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+	body, err := json.Marshal(foundedStat)
+	if err != nil {
+		http.Error(res, errs.ErrJSONMarshall.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// check if config value is empty, then endpoint blocked
-	if trustedCIDR == "" {
-		http.Error(res, errs.ErrInvalidIP.Error(), http.StatusInternalServerError)
+	res.Header().Add("Content-Type", "application/json; charset=utf-8")
+	res.WriteHeader(http.StatusOK)
+	res.Write(body)
+}
+
+// IssueToken godoc
+// @Tags IssueToken
+// @Summary Issue bearer token to cookie user
+// @Failure 400 {string} string "invalid scope"
+// @Failure 403 {string} string "token can be issued only to cookie user"
+// @Success 201 {object} object
+// @Router /api/user/token [post]
+// IssueToken issue bearer token to cookie user
+func (h *Handler) IssueToken(res http.ResponseWriter, req *http.Request) {
+	// token must not be used for issue of new tokens with longer life
+	if middlewares.IsBearer(req) {
+		http.Error(res, errs.ErrTokenForCookie.Error(), http.StatusForbidden)
 		return
 	}
 
-	// get ip from header and parse with
-	ipHeader := req.Header.Get("X-Real-IP")
-	ip := net.ParseIP(ipHeader)
-	_, privateCIDR, _ := net.ParseCIDR(trustedCIDR)
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, fmt.Errorf("%w: %v", errs.ErrReadAll, err).Error(), http.StatusInternalServerError)
+		return
+	}
 
-	// check if subnet contains ip
-	private := privateCIDR.Contains(ip)
+	reqBody := struct {
+		Scopes    []string `json:"scopes"`
+		ExpiresIn int64    `json:"expires_in"`
+	}{}
 
-	if !private {
-		res.WriteHeader(http.StatusForbidden)
-		return
+	// body is optional, then token has all scopes and default lifetime
+	if len(body) > 0 {
+		decJSON := json.NewDecoder(strings.NewReader(string(body)))
+		decJSON.DisallowUnknownFields()
+
+		if err = decJSON.Decode(&reqBody); err != nil {
+			http.Error(res, fmt.Errorf("%w: %v", errs.ErrFieldsJSON, err).Error(), http.StatusBadRequest)
+			return
+		}
 	}
 
-	userID := middlewares.GetContextUserID(req)
+	scopes := reqBody.Scopes
+	if len(scopes) == 0 {
+		scopes = models.AllScopes
+	}
+	for _, s := range scopes {
+		if !validScope(s) {
+			http.Error(res, fmt.Errorf("%w: %s", errs.ErrInvalidScope, s).Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
-	// get count users and urls from storage
-	foundedStat, err := h.s.GetStats(ctx, models.UniqUser(userID))
-	if errors.Is(err, errs.ErrInternalSrv) {
+	ttl := config.TOKENTTL
+	if reqBody.ExpiresIn != 0 {
+		ttl = time.Duration(reqBody.ExpiresIn) * time.Second
+	}
+	if ttl <= 0 || ttl > config.TOKENMAXTTL {
+		http.Error(res, errs.ErrInvalidExpiry.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expiresAt := time.Now().Add(ttl).UTC().Truncate(time.Second)
+	token, err := utils.IssueToken(string(middlewares.GetContextUserID(req)), scopes, expiresAt)
+	if err != nil {
+		logger.FromContext(req.Context()).Info("issue token error", zap.Error(err))
 		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	body, err := json.Marshal(foundedStat)
+	resBody := struct {
+		Token     string    `json:"token"`
+		Scopes    []string  `json:"scopes"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{
+		Token:     token,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+
+	js, err := json.Marshal(resBody)
 	if err != nil {
 		http.Error(res, errs.ErrJSONMarshall.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	res.Header().Add("Content-Type", "application/json; charset=utf-8")
-	res.WriteHeader(http.StatusOK)
-	res.Write(body)
+	res.Header().Set("content-type", "application/json")
+	res.Header().Set("Cache-Control", "no-store")
+	res.WriteHeader(http.StatusCreated)
+	res.Write(js)
+}
+
+// validScope check if scope is known
+func validScope(scope string) bool {
+	for _, s := range models.AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
@@ -2,24 +2,41 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/cookiejar"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/grishagavrin/link-shortener/internal/checker"
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
 	"github.com/grishagavrin/link-shortener/internal/handlers"
+	"github.com/grishagavrin/link-shortener/internal/handlers/middlewares"
+	"github.com/grishagavrin/link-shortener/internal/health"
 	"github.com/grishagavrin/link-shortener/internal/logger"
+	"github.com/grishagavrin/link-shortener/internal/metrics"
 	"github.com/grishagavrin/link-shortener/internal/routes"
 	"github.com/grishagavrin/link-shortener/internal/storage"
 	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/grishagavrin/link-shortener/internal/tracing"
+	"github.com/grishagavrin/link-shortener/internal/utils"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestHandler_GetLink(t *testing.T) {
-	chBatch := make(chan models.BatchDelete)
+	chBatch := make(chan models.BatchDelete, 16)
 	defer close(chBatch)
 	// создаём новый Recorder
 	w := httptest.NewRecorder()
@@ -30,7 +47,7 @@ func TestHandler_GetLink(t *testing.T) {
 	// создаем handler
 	h := handlers.New(stor.Repository, l)
 	// создаем роутер
-	r := routes.NewRouterFacade(h, l, chBatch)
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
 	// создаем сервер
 	ts := httptest.NewServer(r.HTTPRoute.Route)
 	defer ts.Close()
@@ -59,7 +76,7 @@ func TestHandler_GetLink(t *testing.T) {
 		},
 		{
 			name:        "negative test #2",
-			queryString: "/e409bf5aafc",
+			queryString: "/e4",
 			want: want{
 				code:        400,
 				response:    "enter correct url parameter\n",
@@ -101,7 +118,7 @@ func TestHandler_GetLink(t *testing.T) {
 }
 
 func TestHandler_SaveTXT(t *testing.T) {
-	chBatch := make(chan models.BatchDelete)
+	chBatch := make(chan models.BatchDelete, 16)
 	defer close(chBatch)
 	// создаем логер
 	l, _ := logger.Instance()
@@ -110,7 +127,7 @@ func TestHandler_SaveTXT(t *testing.T) {
 	// создаем handler
 	h := handlers.New(stor.Repository, l)
 	// создаем роутер
-	r := routes.NewRouterFacade(h, l, chBatch)
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
 	// создаем сервер
 	ts := httptest.NewServer(r.HTTPRoute.Route)
 	defer ts.Close()
@@ -188,7 +205,7 @@ func TestHandler_SaveTXT(t *testing.T) {
 }
 
 func TestHandler_SaveJSON(t *testing.T) {
-	chBatch := make(chan models.BatchDelete)
+	chBatch := make(chan models.BatchDelete, 16)
 	// создаем логер
 	l, _ := logger.Instance()
 	// создаем хранение
@@ -196,7 +213,7 @@ func TestHandler_SaveJSON(t *testing.T) {
 	// создаем handler
 	h := handlers.New(stor.Repository, l)
 	// создаем роутер
-	r := routes.NewRouterFacade(h, l, chBatch)
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
 	// создаем сервер
 	ts := httptest.NewServer(r.HTTPRoute.Route)
 	defer ts.Close()
@@ -284,3 +301,1731 @@ func TestHandler_SaveJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_SaveJSONAlias(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// уникальный алиас для каждого запуска
+	alias := fmt.Sprintf("spring-sale-%d", time.Now().UnixNano())
+
+	// определяем структуру теста
+	type want struct {
+		code     int
+		response string
+	}
+
+	// создаём массив тестов: имя и желаемый результат
+	tests := []struct {
+		name        string
+		want        want
+		requestBody string
+	}{
+		// определяем все тесты
+		{
+			name: "negative test #1",
+			want: want{
+				code:     http.StatusBadRequest,
+				response: "invalid alias\n",
+			},
+			requestBody: `"url":"http://yandex.ru/alias","alias":"api"`,
+		},
+		{
+			name: "negative test #2",
+			want: want{
+				code:     http.StatusBadRequest,
+				response: "invalid alias\n",
+			},
+			requestBody: `"url":"http://yandex.ru/alias","alias":"spring/sale"`,
+		},
+		{
+			name: "positive test #1",
+			want: want{
+				code:     http.StatusCreated,
+				response: "/" + alias,
+			},
+			requestBody: fmt.Sprintf(`"url":"http://yandex.ru/%s","alias":"%s","redirect":308`, alias, alias),
+		},
+		{
+			name: "negative test #3",
+			want: want{
+				code:     http.StatusConflict,
+				response: "alias already taken\n",
+			},
+			requestBody: fmt.Sprintf(`"url":"http://yandex.ru/%s/other","alias":"%s"`, alias, alias),
+		},
+		{
+			name: "negative test #4",
+			want: want{
+				code:     http.StatusBadRequest,
+				response: "invalid expiration\n",
+			},
+			requestBody: `"url":"http://yandex.ru/expired","expires_in":-5`,
+		},
+		{
+			name: "negative test #5",
+			want: want{
+				code:     http.StatusBadRequest,
+				response: "invalid expiration\n",
+			},
+			requestBody: `"url":"http://yandex.ru/expired","expires_at":"2020-01-01T00:00:00Z"`,
+		},
+		{
+			name: "negative test #6",
+			want: want{
+				code:     http.StatusBadRequest,
+				response: "invalid redirect code\n",
+			},
+			requestBody: `"url":"http://yandex.ru/redirect","redirect":305`,
+		},
+		{
+			// алиас не применяется к уже сокращенному адресу, отдается существующая ссылка
+			name: "negative test #7",
+			want: want{
+				code:     http.StatusConflict,
+				response: "/" + alias + `"`,
+			},
+			requestBody: fmt.Sprintf(`"url":"http://yandex.ru/%s","alias":"%s-new"`, alias, alias),
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			// создаем тело
+			var jsonData = []byte(fmt.Sprintf("{%v}", tt.requestBody))
+			// создаем запрос
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/shorten", bytes.NewBuffer(jsonData))
+			// делаем запрос
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				l.Fatal("TestSaveJSONAliasHandler", zap.Error(err))
+			}
+			defer res.Body.Close()
+
+			resBody, _ := io.ReadAll(res.Body)
+
+			// проверяем код ответа
+			assert.Equal(t, tt.want.code, res.StatusCode)
+
+			// содержание тела ответа
+			assert.Contains(t, string(resBody), tt.want.response)
+		})
+	}
+
+	// проверяем редирект по алиасу
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := client.Get(ts.URL + "/" + alias)
+	if err != nil {
+		l.Fatal("TestSaveJSONAliasHandler", zap.Error(err))
+	}
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusPermanentRedirect, res.StatusCode)
+	assert.Equal(t, "http://yandex.ru/"+alias, res.Header.Get("Location"))
+	assert.Equal(t, "public, max-age=86400", res.Header.Get("Cache-Control"))
+}
+
+func TestHandler_SaveBatchAlias(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// адрес, который уже сокращен с алиасом
+	alias := fmt.Sprintf("batch-sale-%d", time.Now().UnixNano())
+	res, err := http.Post(ts.URL+"/api/shorten", "application/json",
+		strings.NewReader(fmt.Sprintf(`{"url":"http://yandex.ru/%s","alias":"%s"}`, alias, alias)))
+	if err != nil {
+		l.Fatal("TestSaveBatchAliasHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	// создаём массив тестов: тело запроса и желаемый результат
+	tests := []struct {
+		name        string
+		requestBody string
+		code        int
+		shorts      []string
+	}{
+		{
+			// алиас не применяется к уже сокращенному адресу, отдается существующая ссылка
+			name: "positive test #1",
+			requestBody: fmt.Sprintf(`[{"correlation_id":"1","original_url":"http://yandex.ru/%s","alias":"%s-new"},`+
+				`{"correlation_id":"2","original_url":"http://yandex.ru/%s/batch","alias":"%s-batch"}]`, alias, alias, alias, alias),
+			code:   http.StatusCreated,
+			shorts: []string{"/" + alias, "/" + alias + "-batch"},
+		},
+		{
+			name:        "negative test #1",
+			requestBody: fmt.Sprintf(`[{"correlation_id":"1","original_url":"http://yandex.ru/%s/other","alias":"%s"}]`, alias, alias),
+			code:        http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Post(ts.URL+"/api/shorten/batch", "application/json", strings.NewReader(tt.requestBody))
+			if err != nil {
+				l.Fatal("TestSaveBatchAliasHandler", zap.Error(err))
+			}
+			defer res.Body.Close()
+
+			// проверяем код ответа и короткие ссылки в порядке запроса
+			assert.Equal(t, tt.code, res.StatusCode)
+			if tt.shorts == nil {
+				return
+			}
+			var items []models.BatchResURL
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&items))
+			assert.Len(t, items, len(tt.shorts))
+			for n, item := range items {
+				assert.True(t, strings.HasSuffix(item.Short, tt.shorts[n]), item.Short)
+			}
+		})
+	}
+}
+
+func TestHandler_GetLinkStats(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// клиент владельца ссылки с сохранением cookie
+	jar, _ := cookiejar.New(nil)
+	owner := &http.Client{Jar: jar}
+
+	alias := fmt.Sprintf("stats-%d", time.Now().UnixNano())
+	jsonData := []byte(fmt.Sprintf(`{"url":"http://yandex.ru/%s","alias":"%s"}`, alias, alias))
+	res, err := owner.Post(ts.URL+"/api/shorten", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		l.Fatal("TestGetLinkStatsHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	// создаём массив тестов: имя и желаемый результат
+	tests := []struct {
+		name   string
+		client *http.Client
+		code   int
+	}{
+		{
+			name:   "positive test #1",
+			client: owner,
+			code:   http.StatusOK,
+		},
+		{
+			name:   "negative test #1",
+			client: http.DefaultClient,
+			code:   http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := tt.client.Get(ts.URL + "/api/user/urls/" + alias + "/stats")
+			if err != nil {
+				l.Fatal("TestGetLinkStatsHandler", zap.Error(err))
+			}
+			defer res.Body.Close()
+
+			// проверяем код ответа
+			assert.Equal(t, tt.code, res.StatusCode)
+		})
+	}
+}
+
+func TestHandler_IssueToken(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// получаем токен только на чтение для cookie пользователя
+	res, err := http.Post(ts.URL+"/api/user/token", "application/json", strings.NewReader(`{"scopes":["links:read"]}`))
+	if err != nil {
+		l.Fatal("TestIssueTokenHandler", zap.Error(err))
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	var issued struct {
+		Token string `json:"token"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &issued))
+
+	// создаём массив тестов: имя и желаемый результат
+	tests := []struct {
+		name   string
+		method string
+		target string
+		token  string
+		code   int
+	}{
+		{
+			name:   "positive test #1",
+			method: http.MethodGet,
+			target: "/api/user/urls",
+			token:  issued.Token,
+			code:   http.StatusNoContent,
+		},
+		{
+			name:   "negative test #1",
+			method: http.MethodPost,
+			target: "/api/shorten",
+			token:  issued.Token,
+			code:   http.StatusForbidden,
+		},
+		{
+			name:   "negative test #2",
+			method: http.MethodGet,
+			target: "/api/user/urls",
+			token:  issued.Token + "x",
+			code:   http.StatusUnauthorized,
+		},
+		{
+			name:   "negative test #3",
+			method: http.MethodPost,
+			target: "/api/user/token",
+			token:  issued.Token,
+			code:   http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, ts.URL+tt.target, strings.NewReader(`{"url":"http://yandex.ru"}`))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				l.Fatal("TestIssueTokenHandler", zap.Error(err))
+			}
+			defer res.Body.Close()
+
+			// проверяем код ответа
+			assert.Equal(t, tt.code, res.StatusCode)
+		})
+	}
+}
+
+func TestHandler_APIKeys(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// создаем ключ из доверенной подсети с квотой на одну ссылку
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/internal/keys", strings.NewReader(`{"scopes":["links:write"],"daily_quota":1}`))
+	req.Header.Set("X-Real-IP", "127.0.0.1")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		l.Fatal("TestAPIKeysHandler", zap.Error(err))
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &created))
+
+	// занимаем псевдоним без ключа, отклоненное сохранение не должно расходовать квоту
+	taken := fmt.Sprintf("quota-%d", time.Now().UnixNano())
+	res, err = http.Post(ts.URL+"/api/shorten", "application/json",
+		strings.NewReader(`{"url":"http://yandex.ru/`+taken+`","alias":"`+taken+`"}`))
+	if err != nil {
+		l.Fatal("TestAPIKeysHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	// создаём массив тестов: имя и желаемый результат
+	tests := []struct {
+		name   string
+		method string
+		target string
+		key    string
+		alias  string
+		code   int
+	}{
+		{
+			name:   "negative test #1",
+			method: http.MethodPost,
+			target: "/api/shorten",
+			key:    created.Key,
+			alias:  taken,
+			code:   http.StatusConflict,
+		},
+		{
+			name:   "positive test #1",
+			method: http.MethodPost,
+			target: "/api/shorten",
+			key:    created.Key,
+			code:   http.StatusCreated,
+		},
+		{
+			name:   "negative test #2",
+			method: http.MethodPost,
+			target: "/api/shorten",
+			key:    created.Key,
+			code:   http.StatusTooManyRequests,
+		},
+		{
+			name:   "negative test #3",
+			method: http.MethodGet,
+			target: "/api/user/urls",
+			key:    created.Key,
+			code:   http.StatusForbidden,
+		},
+		{
+			name:   "negative test #4",
+			method: http.MethodPost,
+			target: "/api/shorten",
+			key:    "lsk_unknown",
+			code:   http.StatusUnauthorized,
+		},
+	}
+	for k, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			data := fmt.Sprintf(`{"url":"http://yandex.ru/key-%d-%d","alias":"%s"}`, time.Now().UnixNano(), k, tt.alias)
+			req, _ := http.NewRequest(tt.method, ts.URL+tt.target, strings.NewReader(data))
+			req.Header.Set("X-API-Key", tt.key)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				l.Fatal("TestAPIKeysHandler", zap.Error(err))
+			}
+			defer res.Body.Close()
+
+			// проверяем код ответа
+			assert.Equal(t, tt.code, res.StatusCode)
+		})
+	}
+
+	// управление ключами недоступно вне доверенной подсети, даже с подделанным X-Real-IP
+	assert.Equal(t, http.StatusForbidden, serveUntrusted(r.HTTPRoute.Route, http.MethodGet, "/api/internal/keys", ""))
+}
+
+// serveUntrusted выполняет запрос с адреса вне доверенной подсети с подделанным X-Real-IP
+func serveUntrusted(h http.Handler, method, target, body string) int {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("X-Real-IP", "127.0.0.1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestHandler_RateLimit(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// клиент с сохранением cookie, лимит маршрута считается по пользователю
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+
+	// расходуем весь запас маршрута POST / (по умолчанию 5:20)
+	var res *http.Response
+	var err error
+	for i := 0; i <= 20; i++ {
+		body := strings.NewReader(fmt.Sprintf("http://yandex.ru/limit-%d-%d", time.Now().UnixNano(), i))
+		res, err = client.Post(ts.URL+"/", "text/plain", body)
+		if err != nil {
+			l.Fatal("TestRateLimitHandler", zap.Error(err))
+		}
+		res.Body.Close()
+	}
+
+	// проверяем код ответа и заголовки
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.Equal(t, "1", res.Header.Get("Retry-After"))
+	assert.Equal(t, "20", res.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "0", res.Header.Get("RateLimit-Remaining"))
+}
+
+func TestHandler_SaveTXTNormalize(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	path := fmt.Sprintf("norm-%d", time.Now().UnixNano())
+
+	// создаём массив тестов: имя и желаемый результат
+	tests := []struct {
+		name        string
+		requestBody string
+		code        int
+	}{
+		{
+			name:        "positive test #1",
+			requestBody: "HTTP://Yandex.RU:80/" + path + "?b=2&a=1&utm_source=mail",
+			code:        http.StatusCreated,
+		},
+		{
+			name:        "positive test #2",
+			requestBody: "http://yandex.ru/" + path + "?a=1&b=2",
+			code:        http.StatusConflict,
+		},
+		{
+			name:        "negative test #1",
+			requestBody: "ftp://yandex.ru/" + path,
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "negative test #2",
+			requestBody: "http://127.0.0.1/" + path,
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "negative test #3",
+			requestBody: "http://yandex..ru/" + path,
+			code:        http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Post(ts.URL+"/", "text/plain", strings.NewReader(tt.requestBody))
+			if err != nil {
+				l.Fatal("TestSaveTXTNormalizeHandler", zap.Error(err))
+			}
+			defer res.Body.Close()
+
+			// проверяем код ответа
+			assert.Equal(t, tt.code, res.StatusCode)
+		})
+	}
+}
+
+func TestHandler_BlockedLink(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем сервис проверки, блокирует адреса с "malware"
+	lookup := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		var body struct {
+			URL string `json:"url"`
+		}
+		json.NewDecoder(req.Body).Decode(&body)
+		json.NewEncoder(res).Encode(map[string]interface{}{
+			"blocked": strings.Contains(body.URL, "malware"),
+			"reason":  "malware",
+		})
+	}))
+	defer lookup.Close()
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	h.SetChecker(checker.NewHTTPLookup(lookup.URL, nil))
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// клиент без перехода по редиректам
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// сохраняем заблокированную ссылку
+	origin := fmt.Sprintf("http://yandex.ru/malware-%d", time.Now().UnixNano())
+	res, err := client.Post(ts.URL+"/", "text/plain", strings.NewReader(origin))
+	if err != nil {
+		l.Fatal("TestBlockedLinkHandler", zap.Error(err))
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	short := string(body[strings.LastIndex(string(body), "/")+1:])
+
+	// создаём массив тестов: имя и желаемый результат
+	tests := []struct {
+		name   string
+		method string
+		target string
+		code   int
+	}{
+		{
+			name:   "positive test #1",
+			method: http.MethodGet,
+			target: "/" + short,
+			code:   http.StatusUnavailableForLegalReasons,
+		},
+		{
+			name:   "positive test #2",
+			method: http.MethodGet,
+			target: "/api/internal/blocked",
+			code:   http.StatusOK,
+		},
+		{
+			name:   "positive test #3",
+			method: http.MethodDelete,
+			target: "/api/internal/blocked/" + short,
+			code:   http.StatusNoContent,
+		},
+		{
+			name:   "positive test #4",
+			method: http.MethodGet,
+			target: "/" + short,
+			code:   http.StatusTemporaryRedirect,
+		},
+		{
+			name:   "negative test #1",
+			method: http.MethodDelete,
+			target: "/api/internal/blocked/0000000000000000",
+			code:   http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, ts.URL+tt.target, nil)
+			req.Header.Set("X-Real-IP", "127.0.0.1")
+			res, err := client.Do(req)
+			if err != nil {
+				l.Fatal("TestBlockedLinkHandler", zap.Error(err))
+			}
+			defer res.Body.Close()
+
+			// проверяем код ответа
+			assert.Equal(t, tt.code, res.StatusCode)
+		})
+	}
+
+	// владелец не снимает блокировку сменой адреса
+	jar, _ := cookiejar.New(nil)
+	owner := &http.Client{Jar: jar, CheckRedirect: client.CheckRedirect}
+	alias := fmt.Sprintf("malware-%d", time.Now().UnixNano())
+	res, err = owner.Post(ts.URL+"/api/shorten", "application/json", strings.NewReader(`{"url":"http://yandex.ru/`+alias+`","alias":"`+alias+`"}`))
+	if err != nil {
+		l.Fatal("TestBlockedLinkHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	req, _ := http.NewRequest(http.MethodPatch, ts.URL+"/api/user/urls/"+alias, strings.NewReader(`{"url":"http://yandex.ru/clean-`+alias[len("malware-"):]+`"}`))
+	res, err = owner.Do(req)
+	if err != nil {
+		l.Fatal("TestBlockedLinkHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, err = client.Get(ts.URL + "/" + alias)
+	if err != nil {
+		l.Fatal("TestBlockedLinkHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnavailableForLegalReasons, res.StatusCode)
+}
+
+func TestHandler_Domains(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// клиент без перехода по редиректам
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	now := time.Now().UnixNano()
+	domain := fmt.Sprintf("go%d.brand.com", now)
+	alias := fmt.Sprintf("sale-%d", now)
+	// арендатор домена - пользователь cookie
+	tenant := fmt.Sprintf("brand-%d", now)
+	cookie, _ := utils.Encode(tenant)
+	other, _ := utils.Encode("other-" + tenant)
+
+	// регистрируем домен из доверенной подсети
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/internal/domains", strings.NewReader(`{"host":"`+strings.ToUpper(domain)+`","tenant":"`+tenant+`"}`))
+	req.Header.Set("X-Real-IP", "127.0.0.1")
+	res, err := client.Do(req)
+	if err != nil {
+		l.Fatal("TestDomainsHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	// создаём массив тестов: имя и желаемый результат
+	tests := []struct {
+		name        string
+		requestBody string
+		cookie      string
+		code        int
+		result      string
+	}{
+		{
+			name:        "positive test #1",
+			requestBody: `{"url":"https://brand.com/` + alias + `","alias":"` + alias + `","domain":"` + domain + `"}`,
+			cookie:      cookie,
+			code:        http.StatusCreated,
+			result:      "http://" + domain + "/" + alias,
+		},
+		{
+			name:        "positive test #2",
+			requestBody: `{"url":"https://yandex.ru/` + alias + `","alias":"` + alias + `"}`,
+			cookie:      other,
+			code:        http.StatusCreated,
+			result:      "http://localhost:8080/" + alias,
+		},
+		{
+			name:        "negative test #1",
+			requestBody: `{"url":"https://brand.com/other","alias":"` + alias + `","domain":"` + domain + `"}`,
+			cookie:      cookie,
+			code:        http.StatusConflict,
+		},
+		{
+			name:        "negative test #2",
+			requestBody: `{"url":"https://brand.com/other","domain":"unknown.brand.com"}`,
+			cookie:      cookie,
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "negative test #3",
+			requestBody: `{"url":"https://brand.com/other","domain":"` + domain + `"}`,
+			cookie:      other,
+			code:        http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/shorten", strings.NewReader(tt.requestBody))
+			req.AddCookie(&http.Cookie{Name: "userId", Value: tt.cookie})
+			res, err := client.Do(req)
+			if err != nil {
+				l.Fatal("TestDomainsHandler", zap.Error(err))
+			}
+			body, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+
+			// проверяем код ответа
+			assert.Equal(t, tt.code, res.StatusCode)
+			if tt.result != "" {
+				assert.JSONEq(t, `{"result":"`+tt.result+`"}`, string(body))
+			}
+		})
+	}
+
+	// один ключ на разных доменах ведет на разные адреса
+	for host, origin := range map[string]string{
+		domain:           "https://brand.com/" + alias,
+		"localhost:8080": "https://yandex.ru/" + alias,
+	} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/"+alias, nil)
+		req.Host = host
+		res, err := client.Do(req)
+		if err != nil {
+			l.Fatal("TestDomainsHandler", zap.Error(err))
+		}
+		res.Body.Close()
+
+		assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+		assert.Equal(t, origin, res.Header.Get("Location"))
+	}
+
+	// неизвестный хост (прокси, имя сервиса) обслуживается как домен по умолчанию
+	req, _ = http.NewRequest(http.MethodGet, ts.URL+"/"+alias, nil)
+	req.Host = "shortener.internal.svc"
+	res, err = client.Do(req)
+	if err != nil {
+		l.Fatal("TestDomainsHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+	assert.Equal(t, "https://yandex.ru/"+alias, res.Header.Get("Location"))
+
+	// в строгом режиме ссылки неизвестного хоста не отдаются
+	cfg, _ := config.Instance()
+	cfg.StrictDomains = "true"
+	defer func() { cfg.StrictDomains = "false" }()
+	req, _ = http.NewRequest(http.MethodGet, ts.URL+"/"+alias, nil)
+	req.Host = "unknown.brand.com"
+	res, err = client.Do(req)
+	if err != nil {
+		l.Fatal("TestDomainsHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestHandler_UpdateLink(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// клиент владельца ссылки с сохранением cookie и без перехода по редиректам
+	jar, _ := cookiejar.New(nil)
+	owner := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	alias := fmt.Sprintf("edit-%d", time.Now().UnixNano())
+	jsonData := []byte(fmt.Sprintf(`{"url":"http://yandex.ru/%s","alias":"%s"}`, alias, alias))
+	res, err := owner.Post(ts.URL+"/api/shorten", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		l.Fatal("TestUpdateLinkHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	// создаём массив тестов: имя и желаемый результат
+	tests := []struct {
+		name        string
+		client      *http.Client
+		requestBody string
+		code        int
+		location    string
+	}{
+		{
+			name:        "positive test #1",
+			client:      owner,
+			requestBody: `{"url":"http://yandex.ru/new-` + alias + `","redirect":302}`,
+			code:        http.StatusOK,
+			location:    "http://yandex.ru/new-" + alias,
+		},
+		{
+			name:        "positive test #2",
+			client:      owner,
+			requestBody: `{"active":false}`,
+			code:        http.StatusOK,
+		},
+		{
+			name:        "negative test #1",
+			client:      http.DefaultClient,
+			requestBody: `{"active":true}`,
+			code:        http.StatusNotFound,
+		},
+		{
+			name:        "negative test #2",
+			client:      owner,
+			requestBody: `{}`,
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "negative test #3",
+			client:      owner,
+			requestBody: `{"url":"ftp://yandex.ru"}`,
+			code:        http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPatch, ts.URL+"/api/user/urls/"+alias, strings.NewReader(tt.requestBody))
+			res, err := tt.client.Do(req)
+			if err != nil {
+				l.Fatal("TestUpdateLinkHandler", zap.Error(err))
+			}
+			res.Body.Close()
+
+			// проверяем код ответа
+			assert.Equal(t, tt.code, res.StatusCode)
+
+			// проверяем новый адрес ссылки
+			if tt.location != "" {
+				res, err = owner.Get(ts.URL + "/" + alias)
+				if err != nil {
+					l.Fatal("TestUpdateLinkHandler", zap.Error(err))
+				}
+				res.Body.Close()
+				assert.Equal(t, http.StatusFound, res.StatusCode)
+				assert.Equal(t, tt.location, res.Header.Get("Location"))
+			}
+		})
+	}
+
+	// выключенная ссылка не открывается
+	res, err = owner.Get(ts.URL + "/" + alias)
+	if err != nil {
+		l.Fatal("TestUpdateLinkHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// прежние адреса сохранены в истории
+	res, err = owner.Get(ts.URL + "/api/user/urls/" + alias + "/revisions")
+	if err != nil {
+		l.Fatal("TestUpdateLinkHandler", zap.Error(err))
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	var revisions []models.LinkRevision
+	assert.NoError(t, json.Unmarshal(body, &revisions))
+	if assert.Len(t, revisions, 2) {
+		assert.Equal(t, models.Origin("http://yandex.ru/"+alias), revisions[0].Origin)
+		assert.Equal(t, models.Origin("http://yandex.ru/new-"+alias), revisions[1].Origin)
+	}
+}
+
+func TestHandler_RestoreLink(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// клиент владельца ссылки с сохранением cookie и без перехода по редиректам
+	jar, _ := cookiejar.New(nil)
+	owner := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	alias := fmt.Sprintf("restore-%d", time.Now().UnixNano())
+	jsonData := []byte(fmt.Sprintf(`{"url":"http://yandex.ru/%s","alias":"%s"}`, alias, alias))
+	res, err := owner.Post(ts.URL+"/api/shorten", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		l.Fatal("TestRestoreLinkHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	// ждем пока ссылка получит нужный код ответа
+	waitCode := func(code int) int {
+		var got int
+		for i := 0; i < 50; i++ {
+			res, err := owner.Get(ts.URL + "/" + alias)
+			if err != nil {
+				l.Fatal("TestRestoreLinkHandler", zap.Error(err))
+			}
+			res.Body.Close()
+			got = res.StatusCode
+			if got == code {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		return got
+	}
+
+	// создаём массив тестов: имя и желаемый результат
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		requestBody string
+		code        int
+		linkCode    int
+	}{
+		{
+			name:        "positive test #1",
+			method:      http.MethodDelete,
+			path:        "/api/user/urls",
+			requestBody: `["` + alias + `"]`,
+			code:        http.StatusAccepted,
+			linkCode:    http.StatusGone,
+		},
+		{
+			name:        "positive test #2",
+			method:      http.MethodPost,
+			path:        "/api/user/urls/restore",
+			requestBody: `["` + alias + `"]`,
+			code:        http.StatusAccepted,
+			linkCode:    http.StatusTemporaryRedirect,
+		},
+		{
+			name:        "negative test #1",
+			method:      http.MethodPost,
+			path:        "/api/user/urls/restore",
+			requestBody: `[]`,
+			code:        http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, ts.URL+tt.path, strings.NewReader(tt.requestBody))
+			res, err := owner.Do(req)
+			if err != nil {
+				l.Fatal("TestRestoreLinkHandler", zap.Error(err))
+			}
+			res.Body.Close()
+
+			// проверяем код ответа
+			assert.Equal(t, tt.code, res.StatusCode)
+
+			// проверяем состояние ссылки после обработки пакета
+			if tt.linkCode != 0 {
+				assert.Equal(t, tt.linkCode, waitCode(tt.linkCode))
+			}
+		})
+	}
+
+	// очистка недоступна вне доверенной сети
+	assert.Equal(t, http.StatusForbidden, serveUntrusted(r.HTTPRoute.Route, http.MethodPost, "/api/internal/purge", `{"older_than_days":1}`))
+
+	// очистка только что удаленных ссылок запрещена
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/internal/purge", strings.NewReader(`{"older_than_days":0}`))
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		l.Fatal("TestRestoreLinkHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	// очистка удаленных ссылок из доверенной сети
+	req, _ = http.NewRequest(http.MethodPost, ts.URL+"/api/internal/purge", strings.NewReader(`{"older_than_days":1}`))
+	req.Header.Set("X-Real-IP", "127.0.0.1")
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		l.Fatal("TestRestoreLinkHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	// восстановленная ссылка не удаляется очисткой
+	assert.Equal(t, http.StatusTemporaryRedirect, waitCode(http.StatusTemporaryRedirect))
+}
+
+func TestHandler_DeleteJob(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// клиенты двух пользователей с сохранением cookie
+	newClient := func() *http.Client {
+		jar, _ := cookiejar.New(nil)
+		return &http.Client{Jar: jar}
+	}
+	owner, other := newClient(), newClient()
+
+	// создаем ссылки владельца и другого пользователя
+	alias := fmt.Sprintf("job-%d", time.Now().UnixNano())
+	for _, c := range []struct {
+		client *http.Client
+		alias  string
+	}{{owner, alias}, {other, alias + "-other"}} {
+		jsonData := []byte(fmt.Sprintf(`{"url":"http://yandex.ru/%s","alias":"%s"}`, c.alias, c.alias))
+		res, err := c.client.Post(ts.URL+"/api/shorten", "application/json", bytes.NewBuffer(jsonData))
+		if err != nil {
+			l.Fatal("TestDeleteJobHandler", zap.Error(err))
+		}
+		res.Body.Close()
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+	}
+
+	// удаление возвращает идентификатор задачи
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/api/user/urls",
+		strings.NewReader(`["`+alias+`","`+alias+`-other","`+alias+`-missing"]`))
+	res, err := owner.Do(req)
+	if err != nil {
+		l.Fatal("TestDeleteJobHandler", zap.Error(err))
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	var queued struct {
+		JobID  string `json:"job_id"`
+		Status string `json:"status"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &queued))
+	assert.NotEmpty(t, queued.JobID)
+
+	// ждем завершения задачи
+	var job models.Job
+	for i := 0; i < 50; i++ {
+		res, err = owner.Get(ts.URL + "/api/user/jobs/" + queued.JobID)
+		if err != nil {
+			l.Fatal("TestDeleteJobHandler", zap.Error(err))
+		}
+		body, _ = io.ReadAll(res.Body)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.NoError(t, json.Unmarshal(body, &job))
+		if job.Status == models.JobDone || job.Status == models.JobFailed {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// проверяем результат по каждой ссылке
+	assert.Equal(t, models.JobDone, job.Status)
+	assert.Equal(t, []models.JobResult{
+		{URL: alias, Outcome: models.JobURLDeleted},
+		{URL: alias + "-other", Outcome: models.JobURLNotOwned},
+		{URL: alias + "-missing", Outcome: models.JobURLNotFound},
+	}, job.Results)
+
+	// создаём массив тестов: имя и желаемый результат
+	tests := []struct {
+		name   string
+		client *http.Client
+		id     string
+		code   int
+	}{
+		{
+			name:   "negative test #1",
+			client: other,
+			id:     queued.JobID,
+			code:   http.StatusNotFound,
+		},
+		{
+			name:   "negative test #2",
+			client: owner,
+			id:     "unknown",
+			code:   http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := tt.client.Get(ts.URL + "/api/user/jobs/" + tt.id)
+			if err != nil {
+				l.Fatal("TestDeleteJobHandler", zap.Error(err))
+			}
+			res.Body.Close()
+
+			// проверяем код ответа
+			assert.Equal(t, tt.code, res.StatusCode)
+		})
+	}
+}
+
+func TestHandler_DeleteQueueFull(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// очередь без обработчиков, уже заполненная
+	full := make(chan models.BatchDelete, 1)
+	full <- models.BatchDelete{}
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, full)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// создаём массив тестов: имя и желаемый результат
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		requestBody string
+		code        int
+	}{
+		{
+			name:        "negative test #1",
+			method:      http.MethodDelete,
+			path:        "/api/user/urls",
+			requestBody: `["abc"]`,
+			code:        http.StatusServiceUnavailable,
+		},
+		{
+			name:        "negative test #2",
+			method:      http.MethodPost,
+			path:        "/api/user/urls/restore",
+			requestBody: `["abc"]`,
+			code:        http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, ts.URL+tt.path, strings.NewReader(tt.requestBody))
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				l.Fatal("TestDeleteQueueFullHandler", zap.Error(err))
+			}
+			res.Body.Close()
+
+			// проверяем код ответа и заголовок повтора
+			assert.Equal(t, tt.code, res.StatusCode)
+			assert.Equal(t, "1", res.Header.Get("Retry-After"))
+		})
+	}
+}
+
+func TestHandler_LinkCache(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// клиент владельца ссылки с сохранением cookie и без перехода по редиректам
+	jar, _ := cookiejar.New(nil)
+	owner := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	alias := fmt.Sprintf("cache-%d", time.Now().UnixNano())
+
+	// запрос к ссылке, возвращает код и адрес
+	get := func() (int, string) {
+		res, err := owner.Get(ts.URL + "/" + alias)
+		if err != nil {
+			l.Fatal("TestLinkCacheHandler", zap.Error(err))
+		}
+		res.Body.Close()
+		return res.StatusCode, res.Header.Get("Location")
+	}
+	// счетчики кэша из доверенной сети
+	cacheStats := func() models.CacheStats {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/internal/cache", nil)
+		req.Header.Set("X-Real-IP", "127.0.0.1")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			l.Fatal("TestLinkCacheHandler", zap.Error(err))
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		var stats models.CacheStats
+		assert.NoError(t, json.Unmarshal(body, &stats))
+		return stats
+	}
+
+	// промах кэшируется, но создание ссылки его сбрасывает
+	code, _ := get()
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	jsonData := []byte(fmt.Sprintf(`{"url":"http://yandex.ru/%s","alias":"%s"}`, alias, alias))
+	res, err := owner.Post(ts.URL+"/api/shorten", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		l.Fatal("TestLinkCacheHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	before := cacheStats()
+	code, location := get()
+	assert.Equal(t, http.StatusTemporaryRedirect, code)
+	assert.Equal(t, "http://yandex.ru/"+alias, location)
+	code, _ = get()
+	assert.Equal(t, http.StatusTemporaryRedirect, code)
+
+	// второй запрос берется из кэша
+	after := cacheStats()
+	assert.Equal(t, before.Hits+1, after.Hits)
+	assert.Equal(t, before.Misses+1, after.Misses)
+
+	// изменение ссылки сбрасывает кэш
+	req, _ := http.NewRequest(http.MethodPatch, ts.URL+"/api/user/urls/"+alias, strings.NewReader(`{"url":"http://yandex.ru/new-`+alias+`"}`))
+	res, err = owner.Do(req)
+	if err != nil {
+		l.Fatal("TestLinkCacheHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	code, location = get()
+	assert.Equal(t, http.StatusTemporaryRedirect, code)
+	assert.Equal(t, "http://yandex.ru/new-"+alias, location)
+
+	// удаление ссылки сбрасывает кэш
+	req, _ = http.NewRequest(http.MethodDelete, ts.URL+"/api/user/urls", strings.NewReader(`["`+alias+`"]`))
+	res, err = owner.Do(req)
+	if err != nil {
+		l.Fatal("TestLinkCacheHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	for i := 0; i < 50; i++ {
+		if code, _ = get(); code == http.StatusGone {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.Equal(t, http.StatusGone, code)
+}
+
+func TestHandler_Metrics(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// запрос, который должен попасть в метрики
+	jsonData := []byte(fmt.Sprintf(`{"url":"http://yandex.ru/metrics-%d"}`, time.Now().UnixNano()))
+	res, err := http.Post(ts.URL+"/api/shorten", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		l.Fatal("TestMetricsHandler", zap.Error(err))
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	// создаём массив тестов: обработчик, адрес клиента и желаемый результат
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		addr     string
+		code     int
+		contains []string
+	}{
+		{
+			name:    "positive test #1",
+			handler: middlewares.TrustedSubnet(metrics.Handler()),
+			addr:    "127.0.0.1:40000",
+			code:    http.StatusOK,
+			contains: []string{
+				`shortener_http_requests_total{code="201",method="POST",route="/api/shorten"}`,
+				`shortener_storage_operation_duration_seconds_count{backend="RAMStorage",op="SaveLinkDB"}`,
+			},
+		},
+		{
+			name:    "negative test #1",
+			handler: r.HTTPRoute.Route,
+			addr:    "127.0.0.1:40000",
+			code:    http.StatusBadRequest,
+		},
+		{
+			name:    "negative test #2",
+			handler: middlewares.TrustedSubnet(metrics.Handler()),
+			addr:    "10.1.1.1:40000",
+			code:    http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест, метрики отдаются только доверенной сети и не отдаются публичным роутером
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.RemoteAddr = tt.addr
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+
+			// проверяем код ответа и наличие метрик
+			assert.Equal(t, tt.code, rec.Code)
+			for _, m := range tt.contains {
+				assert.Contains(t, rec.Body.String(), m)
+			}
+		})
+	}
+}
+
+func TestHandler_Tracing(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// пропагатор W3C и провайдер, который запоминает завершенные спаны
+	_, err := tracing.Init(l)
+	if err != nil {
+		l.Fatal("TestTracingHandler", zap.Error(err))
+	}
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// создаём массив тестов: имя и желаемый результат
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		traceID string
+		spans   []string
+	}{
+		{
+			name:    "positive test #1",
+			method:  http.MethodPost,
+			path:    "/api/shorten",
+			body:    fmt.Sprintf(`{"url":"http://yandex.ru/tracing-%d"}`, time.Now().UnixNano()),
+			traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			spans:   []string{"POST /api/shorten", "storage.SaveLinkDB"},
+		},
+		{
+			name:    "positive test #2",
+			method:  http.MethodDelete,
+			path:    "/api/user/urls",
+			body:    `["unknown"]`,
+			traceID: "5bf92f3577b34da6a3ce929d0e0e4736",
+			spans:   []string{"DELETE /api/user/urls", "delete.apply", "storage.BunchUpdateAsDeleted"},
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, ts.URL+tt.path, strings.NewReader(tt.body))
+			req.Header.Set("traceparent", "00-"+tt.traceID+"-00f067aa0ba902b7-01")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				l.Fatal("TestTracingHandler", zap.Error(err))
+			}
+			res.Body.Close()
+
+			// удаление применяется асинхронно, ждем спаны воркера
+			assert.Eventually(t, func() bool {
+				names := map[string]bool{}
+				for _, s := range rec.Ended() {
+					if s.SpanContext().TraceID().String() == tt.traceID {
+						names[s.Name()] = true
+					}
+				}
+				for _, name := range tt.spans {
+					if !names[name] {
+						return false
+					}
+				}
+				return true
+			}, time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestHandler_AccessLog(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер, который запоминает записи
+	l, _ := logger.Instance()
+	core, logs := observer.New(zap.InfoLevel)
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, zap.New(core), chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// создаём массив тестов: имя и желаемый результат
+	tests := []struct {
+		name      string
+		requestID string
+		generated bool
+	}{
+		{
+			name:      "positive test #1",
+			requestID: "req-123_abc.1",
+		},
+		{
+			name:      "positive test #2",
+			generated: true,
+		},
+		{
+			name:      "negative test #1",
+			requestID: "bad id\twith spaces",
+			generated: true,
+		},
+		{
+			name:      "negative test #2",
+			requestID: strings.Repeat("a", 65),
+			generated: true,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			jsonData := []byte(fmt.Sprintf(`{"url":"http://yandex.ru/access-%d"}`, time.Now().UnixNano()))
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/shorten", bytes.NewBuffer(jsonData))
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-ID", tt.requestID)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				l.Fatal("TestAccessLogHandler", zap.Error(err))
+			}
+			res.Body.Close()
+
+			// проверяем id запроса в ответе
+			requestID := res.Header.Get("X-Request-ID")
+			if tt.generated {
+				assert.Len(t, requestID, 36)
+			} else {
+				assert.Equal(t, tt.requestID, requestID)
+			}
+
+			// проверяем запись журнала доступа
+			entries := logs.FilterMessage("http request").FilterField(zap.String("request_id", requestID)).All()
+			if assert.Len(t, entries, 1) {
+				fields := entries[0].ContextMap()
+				assert.Equal(t, "/api/shorten", fields["route"])
+				assert.Equal(t, int64(http.StatusCreated), fields["status"])
+				assert.NotEmpty(t, fields["user_id"])
+				assert.NotZero(t, fields["bytes"])
+			}
+		})
+	}
+}
+
+func TestHandler_Health(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 16)
+	defer close(chBatch)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, _ := storage.Instance(l, chBatch)
+	// создаем проверки готовности с управляемым компонентом
+	failing, degraded := false, false
+	ready := health.New()
+	stor.RegisterChecks(ready)
+	ready.Add("grpc", func(context.Context) error {
+		if failing {
+			return errs.ErrGRPCNotServing
+		}
+		return nil
+	})
+	ready.Add("queue", func(context.Context) error {
+		if degraded {
+			return health.Degraded(errs.ErrQueueFull)
+		}
+		return nil
+	})
+	// создаем handler
+	h := handlers.New(stor.Repository, l)
+	h.SetHealth(ready)
+	// создаем роутер
+	r, _ := routes.NewRouterFacade(h, l, chBatch)
+	// создаем сервер
+	ts := httptest.NewServer(r.HTTPRoute.Route)
+	defer ts.Close()
+
+	// создаём массив тестов: имя и желаемый результат
+	tests := []struct {
+		name       string
+		path       string
+		failing    bool
+		degraded   bool
+		apiKey     string
+		statusCode int
+		status     string
+		components []string
+	}{
+		{
+			name:       "positive test #1",
+			path:       "/healthz",
+			statusCode: http.StatusOK,
+			status:     models.HealthOK,
+		},
+		{
+			name:       "positive test #2",
+			path:       "/readyz",
+			statusCode: http.StatusOK,
+			status:     models.HealthOK,
+			components: []string{"storage", "delete_workers", "grpc"},
+		},
+		{
+			name:       "positive test #3",
+			path:       "/ping",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "positive test #4",
+			path:       "/readyz",
+			degraded:   true,
+			statusCode: http.StatusOK,
+			status:     models.HealthDegraded,
+			components: []string{"queue"},
+		},
+		{
+			name:       "positive test #5",
+			path:       "/readyz",
+			apiKey:     "invalid",
+			statusCode: http.StatusOK,
+			status:     models.HealthOK,
+		},
+		{
+			name:       "negative test #1",
+			path:       "/readyz",
+			failing:    true,
+			statusCode: http.StatusServiceUnavailable,
+			status:     models.HealthFail,
+			components: []string{"grpc"},
+		},
+		{
+			name:       "negative test #2",
+			path:       "/healthz",
+			failing:    true,
+			statusCode: http.StatusOK,
+			status:     models.HealthOK,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			failing, degraded = tt.failing, tt.degraded
+			req, _ := http.NewRequest(http.MethodGet, ts.URL+tt.path, nil)
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				l.Fatal("TestHealthHandler", zap.Error(err))
+			}
+			defer res.Body.Close()
+
+			// проверяем код ответа
+			assert.Equal(t, tt.statusCode, res.StatusCode)
+			if tt.status == "" {
+				return
+			}
+
+			// проверяем статус сервиса и компонентов
+			body, _ := io.ReadAll(res.Body)
+			var report models.HealthReport
+			if err = json.Unmarshal(body, &report); err != nil {
+				l.Fatal("TestHealthHandler", zap.Error(err))
+			}
+			// ошибки компонентов не отдаются клиенту
+			assert.NotContains(t, string(body), "error")
+			assert.Equal(t, tt.status, report.Status)
+			for _, name := range tt.components {
+				assert.Equal(t, tt.status, report.Components[name].Status, name)
+			}
+		})
+	}
+}
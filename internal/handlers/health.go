@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/health"
+	"github.com/grishagavrin/link-shortener/internal/logger"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"go.uber.org/zap"
+)
+
+// SetHealth set readiness checks of service components
+func (h *Handler) SetHealth(ready *health.Registry) {
+	h.ready = ready
+}
+
+// GetHealthz godoc
+// @Tags GetHealthz
+// @Summary Liveness of service
+// @Success 200 {object} models.HealthReport
+// @Router /healthz [get]
+// GetHealthz liveness of service, it answers while process is able to serve requests
+func (h *Handler) GetHealthz(res http.ResponseWriter, req *http.Request) {
+	writeHealth(res, http.StatusOK, models.HealthReport{Status: models.HealthOK})
+}
+
+// GetReadyz godoc
+// @Tags GetReadyz
+// @Summary Readiness of service with status of every component
+// @Failure 503 {object} models.HealthReport
+// @Success 200 {object} models.HealthReport
+// @Router /readyz [get]
+// GetReadyz readiness of service with status of every component, errors of components are only logged
+func (h *Handler) GetReadyz(res http.ResponseWriter, req *http.Request) {
+	report := h.ready.Check(req.Context())
+
+	for name, c := range report.Components {
+		if c.Status != models.HealthOK {
+			logger.FromContext(req.Context()).Info("component is not healthy",
+				zap.String("component", name), zap.String("status", c.Status), zap.String("error", c.Error))
+		}
+	}
+
+	code := http.StatusOK
+	if report.Status == models.HealthFail {
+		code = http.StatusServiceUnavailable
+	}
+	writeHealth(res, code, report)
+}
+
+// writeHealth write report of health as json
+func writeHealth(res http.ResponseWriter, code int, report models.HealthReport) {
+	js, err := json.Marshal(report)
+	if err != nil {
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("content-type", "application/json")
+	res.Header().Set("cache-control", "no-store")
+	res.WriteHeader(code)
+	res.Write(js)
+}
@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/google/uuid"
+	"github.com/grishagavrin/link-shortener/internal/logger"
+	"github.com/grishagavrin/link-shortener/internal/utils"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader header with id of request, it is taken from client or generated
+const RequestIDHeader = "X-Request-ID"
+
+// accessEntryCtxName set context name for entry of access log
+var accessEntryCtxName ContextType = "ctxAccessEntry"
+
+// accessEntry data of request known after auth middlewares
+type accessEntry struct {
+	userID string
+}
+
+// AccessLog assign or propagate request id, put logger of request with correlation fields to context
+// and write one record per request with method, route, status, bytes, latency and user
+func AccessLog(l *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get(RequestIDHeader)
+			if !utils.IsValidRequestID(requestID) {
+				requestID = uuid.New().String()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			reqLog := logger.WithTrace(r.Context(), l.With(zap.String("request_id", requestID)))
+			entry := &accessEntry{}
+			ctx := logger.WithContext(r.Context(), reqLog)
+			ctx = context.WithValue(ctx, accessEntryCtxName, entry)
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			route := unmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			reqLog.Info("http request",
+				zap.String("method", r.Method),
+				zap.String("route", route),
+				zap.String("path", r.URL.Path),
+				zap.Int("status", status),
+				zap.Int("bytes", ww.BytesWritten()),
+				zap.Duration("latency", time.Since(start)),
+				zap.String("user_id", entry.userID),
+			)
+		})
+	}
+}
+
+// withUser put user id to context of request, to logger of request and to access log
+func withUser(ctx context.Context, userID string) context.Context {
+	if entry, ok := ctx.Value(accessEntryCtxName).(*accessEntry); ok {
+		entry.userID = userID
+	}
+	ctx = logger.WithContext(ctx, logger.FromContext(ctx).With(zap.String("user_id", userID)))
+	return context.WithValue(ctx, UserIDCtxName, userID)
+}
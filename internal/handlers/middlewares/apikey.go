@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+)
+
+// APIKeyHeader header with api key of partner system
+var APIKeyHeader = "X-API-Key"
+
+// APIKeyCtxName set context name for api key
+var APIKeyCtxName ContextType = "ctxAPIKey"
+
+// WithAPIKey return request with user id and scopes of api key in context
+func WithAPIKey(req *http.Request, key models.APIKey) *http.Request {
+	ctx := withUser(req.Context(), string(key.UserID))
+	ctx = context.WithValue(ctx, ScopesCtxName, key.Scopes)
+	ctx = context.WithValue(ctx, APIKeyCtxName, key)
+	return req.WithContext(ctx)
+}
+
+// GetContextAPIKey return api key of request if it was presented
+func GetContextAPIKey(req *http.Request) (models.APIKey, bool) {
+	key, ok := req.Context().Value(APIKeyCtxName).(models.APIKey)
+	return key, ok
+}
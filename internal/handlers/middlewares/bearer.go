@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/logger"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/grishagavrin/link-shortener/internal/utils"
+	"go.uber.org/zap"
+)
+
+// ScopesCtxName set context name for scopes of bearer token
+var ScopesCtxName ContextType = "ctxScopes"
+
+// bearerToken get token from Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(header[7:]), true
+}
+
+// serveBearer set user id and scopes from token, invalid token is rejected without cookie fallback
+func serveBearer(w http.ResponseWriter, r *http.Request, next http.Handler, token string) {
+	claims, err := utils.ParseToken(token)
+	if err != nil {
+		logger.FromContext(r.Context()).Info("Bearer token error", zap.Error(err))
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, errs.ErrInvalidToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	ctx := withUser(r.Context(), claims.Subject)
+	ctx = context.WithValue(ctx, ScopesCtxName, claims.Scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// IsBearer check if request is authenticated by bearer token or api key
+func IsBearer(req *http.Request) bool {
+	return req.Context().Value(ScopesCtxName) != nil
+}
+
+// GetContextScopes return scopes of request, cookie user has all scopes
+func GetContextScopes(req *http.Request) []string {
+	if scopes, ok := req.Context().Value(ScopesCtxName).([]string); ok {
+		return scopes
+	}
+	return models.AllScopes
+}
+
+// RequireScope allow request only if credential has scope
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, s := range GetContextScopes(r) {
+				if s == scope {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+			http.Error(w, errs.ErrInsufficientScope.Error(), http.StatusForbidden)
+		})
+	}
+}
@@ -4,9 +4,11 @@ package middlewares
 import (
 	"compress/gzip"
 	"io"
-	"log"
 	"net/http"
 	"strings"
+
+	"github.com/grishagavrin/link-shortener/internal/logger"
+	"go.uber.org/zap"
 )
 
 type gzipWriter struct {
@@ -25,7 +27,7 @@ func GzipMiddleware(next http.Handler) http.Handler {
 		if strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
 			reader, err := gzip.NewReader(r.Body)
 			if err != nil {
-				log.Println("failed decompress gzip data", err)
+				logger.FromContext(r.Context()).Info("failed decompress gzip data", zap.Error(err))
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -35,7 +37,7 @@ func GzipMiddleware(next http.Handler) http.Handler {
 
 		gz, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
 		if err != nil {
-			log.Println("failed compress gzip data", err)
+			logger.FromContext(r.Context()).Info("failed compress gzip data", zap.Error(err))
 			io.WriteString(w, err.Error())
 			return
 		}
@@ -1,7 +1,6 @@
 package middlewares
 
 import (
-	"context"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -20,21 +19,28 @@ var CookieUserIDName = "userId"
 // ContextType set context name for user id
 var UserIDCtxName ContextType = "ctxUserId"
 
-// CooksMiddleware checks and set user token
+// CooksMiddleware checks and set user token, bearer token is used instead of cookie if presented
 func CooksMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// User is already authenticated by api key
+		if _, ok := GetContextAPIKey(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if token, ok := bearerToken(r); ok {
+			serveBearer(w, r, next, token)
+			return
+		}
+
 		userID := uuid.New().String()
 		// Check if set cookie
 		if cookieUserID, err := r.Cookie(CookieUserIDName); err == nil {
-			logger.Info(CookieUserIDName, zap.String(CookieUserIDName, cookieUserID.Value))
 			_ = utils.Decode(cookieUserID.Value, &userID)
 		}
 
 		// Generate hash from userId
 		encoded, err := utils.Encode(userID)
-		logger.Info("UserID", zap.String("ID", userID))
-		// logger.Info("User encoded", zap.String("Encoded", encoded))
-		// fmt.Println("COOKIE VAL:", encoded)
 		if err == nil {
 			cookie := http.Cookie{
 				Name:     "userId",
@@ -44,9 +50,9 @@ func CooksMiddleware(next http.Handler) http.Handler {
 			}
 			http.SetCookie(w, &cookie)
 		} else {
-			logger.Info("Encode cookie error", zap.Error(err))
+			logger.FromContext(r.Context()).Info("Encode cookie error", zap.Error(err))
 		}
-		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserIDCtxName, userID)))
+		next.ServeHTTP(w, r.WithContext(withUser(r.Context(), userID)))
 	})
 }
 
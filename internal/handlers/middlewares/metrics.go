@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/grishagavrin/link-shortener/internal/metrics"
+)
+
+// unmatchedRoute label of requests without route
+const unmatchedRoute = "unmatched"
+
+// Metrics count requests and their latency by route pattern, method and status
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		// Pattern is known after routing, raw path is not used to keep labels bounded
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		metrics.ObserveHTTP(route, r.Method, ww.Status(), time.Since(start))
+	})
+}
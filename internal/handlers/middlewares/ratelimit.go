@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/ratelimit"
+	"github.com/grishagavrin/link-shortener/internal/utils"
+	"go.uber.org/zap"
+)
+
+// RateLimit reject requests over limits of user, ip and route with 429, backend errors don`t block requests
+func RateLimit(lim *ratelimit.Limiter, l *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := lim.Allow(r.Context(), string(GetContextUserID(r)), utils.ClientIP(r), routePattern(r))
+			if err != nil {
+				l.Info("rate limit error", zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Headers are set if any limit was checked
+			if res.Limit > 0 {
+				w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+				w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+				w.Header().Set("RateLimit-Reset", ceilSeconds(res.Reset))
+			}
+
+			if !res.Allowed {
+				w.Header().Set("Retry-After", ceilSeconds(res.RetryAfter))
+				http.Error(w, errs.ErrRateLimited.Error(), http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routePattern get "METHOD /pattern" of route which will serve request
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return r.Method + " " + r.URL.Path
+	}
+
+	tctx := chi.NewRouteContext()
+	if !rctx.Routes.Match(tctx, r.Method, r.URL.Path) {
+		return r.Method + " " + r.URL.Path
+	}
+	return r.Method + " " + tctx.RoutePattern()
+}
+
+// ceilSeconds format duration as whole seconds rounded up
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/utils"
+)
+
+// TrustedSubnet allow requests only from trusted subnet, X-Real-IP is taken only from trusted proxies
+func TrustedSubnet(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// config instance
+		cfg, err := config.Instance()
+		if errors.Is(err, errs.ErrENVLoading) {
+			http.Error(w, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// config value
+		trustedCIDR, err := cfg.GetCfgValue(config.TrustedSubnet)
+		if errors.Is(err, errs.ErrUnknownEnvOrFlag) {
+			http.Error(w, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// check if config value is empty, then endpoint blocked
+		if trustedCIDR == "" {
+			http.Error(w, errs.ErrInvalidIP.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// get ip of client and parse with
+		ip := net.ParseIP(utils.ClientIP(r))
+		_, privateCIDR, err := net.ParseCIDR(trustedCIDR)
+		if err != nil {
+			http.Error(w, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// check if subnet contains ip
+		if !privateCIDR.Contains(ip) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/grishagavrin/link-shortener/internal/checker"
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/handlers/middlewares"
+	"github.com/grishagavrin/link-shortener/internal/logger"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/grishagavrin/link-shortener/internal/utils"
+	"go.uber.org/zap"
+)
+
+// UpdateLink godoc
+// @Tags UpdateLink
+// @Summary Change destination, state or redirect params of user link
+// @Param id path string true "2dace3f162eb9f0d"
+// @Param domain query string false "go.brand.com"
+// @Failure 400 {string} string "bad request"
+// @Failure 404 {string} string "url not found"
+// @Failure 409 {string} string "url already has short"
+// @Failure 410 {string} string "url is gone"
+// @Success 200 {object} object
+// @Router /api/user/urls/{id} [patch]
+// UpdateLink change destination, state or redirect params of user link
+func (h *Handler) UpdateLink(res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	q := chi.URLParam(req, "id")
+
+	if !utils.IsValidShortKey(q) {
+		http.Error(res, errs.ErrCorrectURL.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, fmt.Errorf("%w: %v", errs.ErrReadAll, err).Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// all fields are optional, absent field is not changed
+	reqBody := struct {
+		URL       *string   `json:"url"`
+		Active    *bool     `json:"active"`
+		Redirect  *int      `json:"redirect"`
+		ExpiresIn int64     `json:"expires_in"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{}
+
+	decJSON := json.NewDecoder(strings.NewReader(string(body)))
+	decJSON.DisallowUnknownFields()
+
+	if err = decJSON.Decode(&reqBody); err != nil {
+		http.Error(res, fmt.Errorf("%w: %v", errs.ErrFieldsJSON, err).Error(), http.StatusBadRequest)
+		return
+	}
+
+	upd := models.LinkUpdate{Active: reqBody.Active}
+
+	if reqBody.URL != nil {
+		origin, err := utils.NormalizeURL(*reqBody.URL)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// new destination is screened like on create
+		v := checker.Screen(ctx, h.checker, origin, logger.FromContext(ctx))
+		upd.Origin = (*models.Origin)(&origin)
+		upd.Blocked, upd.BlockReason = v.Blocked, v.Reason
+	}
+
+	if reqBody.Redirect != nil {
+		if err = utils.ValidateRedirect(*reqBody.Redirect); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		upd.RedirectCode = reqBody.Redirect
+	}
+
+	if reqBody.ExpiresIn != 0 || !reqBody.ExpiresAt.IsZero() {
+		expiresAt, err := utils.LinkExpiry(reqBody.ExpiresIn, reqBody.ExpiresAt)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		upd.ExpiresAt = &expiresAt
+	}
+
+	if upd.Origin == nil && upd.Active == nil && upd.RedirectCode == nil && upd.ExpiresAt == nil {
+		http.Error(res, errs.ErrEmptyUpdate.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := linkKey(req, q)
+	link, err := h.s.UpdateLink(ctx, middlewares.GetContextUserID(req), key, upd)
+	switch {
+	case errors.Is(err, errs.ErrNotFoundURL):
+		http.Error(res, errs.ErrURLNotFound.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, errs.ErrURLIsGone):
+		http.Error(res, errs.ErrURLIsGone.Error(), http.StatusGone)
+		return
+	case errors.Is(err, errs.ErrAlreadyHasShort):
+		http.Error(res, errs.ErrAlreadyHasShort.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		logger.FromContext(req.Context()).Info("update link error", zap.Error(err))
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// config instance
+	cfg, err := config.Instance()
+	if errors.Is(err, errs.ErrENVLoading) {
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// config value
+	baseURL, err := cfg.GetCfgValue(config.BaseURL)
+	if errors.Is(err, errs.ErrUnknownEnvOrFlag) {
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resBody := struct {
+		Short     string     `json:"short_url"`
+		Origin    string     `json:"original_url"`
+		Active    bool       `json:"active"`
+		Redirect  int        `json:"redirect"`
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	}{
+		Short:    utils.LinkURL(baseURL, key),
+		Origin:   string(link.Origin),
+		Active:   !link.Inactive,
+		Redirect: link.RedirectCode,
+	}
+	if !link.ExpiresAt.IsZero() {
+		resBody.ExpiresAt = &link.ExpiresAt
+	}
+
+	js, err := json.Marshal(resBody)
+	if err != nil {
+		http.Error(res, errs.ErrJSONMarshall.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("content-type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	res.Write(js)
+}
+
+// GetLinkRevisions godoc
+// @Tags GetLinkRevisions
+// @Summary Get previous states of user link
+// @Param id path string true "2dace3f162eb9f0d"
+// @Param domain query string false "go.brand.com"
+// @Failure 404 {string} string "url not found"
+// @Success 200 {array} models.LinkRevision
+// @Router /api/user/urls/{id}/revisions [get]
+// GetLinkRevisions get previous states of user link
+func (h *Handler) GetLinkRevisions(res http.ResponseWriter, req *http.Request) {
+	q := chi.URLParam(req, "id")
+
+	if !utils.IsValidShortKey(q) {
+		http.Error(res, errs.ErrCorrectURL.Error(), http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.s.LinkRevisions(req.Context(), middlewares.GetContextUserID(req), linkKey(req, q))
+	if errors.Is(err, errs.ErrNotFoundURL) {
+		http.Error(res, errs.ErrURLNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(req.Context()).Info("get link revisions error", zap.Error(err))
+		http.Error(res, errs.ErrInternalSrv.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(revisions)
+	if err != nil {
+		http.Error(res, errs.ErrJSONMarshall.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Add("Content-Type", "application/json; charset=utf-8")
+	res.WriteHeader(http.StatusOK)
+	res.Write(body)
+}
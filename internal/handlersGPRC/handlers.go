@@ -3,89 +3,582 @@ package handlersgrpc
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
+	"github.com/grishagavrin/link-shortener/internal/checker"
 	"github.com/grishagavrin/link-shortener/internal/config"
 	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/handlersGPRC/interceptors"
+	"github.com/grishagavrin/link-shortener/internal/jobs"
+	"github.com/grishagavrin/link-shortener/internal/logger"
 	ls "github.com/grishagavrin/link-shortener/internal/proto"
 	"github.com/grishagavrin/link-shortener/internal/storage/models"
-	"github.com/grishagavrin/link-shortener/internal/utils/db"
+	"github.com/grishagavrin/link-shortener/internal/tracing"
+	"github.com/grishagavrin/link-shortener/internal/utils"
 	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // Repository interface for working with global storage
 type Repository interface {
-	GetLinkDB(context.Context, models.ShortURL) (models.Origin, error)
+	GetLinkDB(context.Context, models.ShortURL) (models.Link, error)
+	SaveLinkDB(context.Context, models.UniqUser, models.Origin, models.LinkOptions) (models.ShortURL, error)
+	LinksByUser(context.Context, models.UniqUser) (models.ShortLinks, error)
+	SaveBatch(context.Context, models.UniqUser, []models.BatchReqURL) ([]models.BatchResURL, error)
+	GetStats(context.Context, models.UniqUser) (models.GetStatsResURL, error)
+	SaveClick(models.ClickEvent)
+	SetBlocked(context.Context, models.ShortURL, bool, string) error
+	DomainByHost(context.Context, string) (models.Domain, error)
+	UpdateLink(context.Context, models.UniqUser, models.ShortURL, models.LinkUpdate) (models.Link, error)
+	Health(context.Context) error
 }
 
 // GRPCHandlers поддерживает все необходимые методы сервера.
 type GRPCHandler struct {
 	ls.UnimplementedApiServiceServer
-	l    *zap.Logger
-	stor Repository
+	l       *zap.Logger
+	stor    Repository
+	chBatch chan models.BatchDelete
+	checker checker.DestinationChecker
 }
 
 // New allocation new grpc handler
-func New(stor Repository, l *zap.Logger) *GRPCHandler {
+func New(stor Repository, l *zap.Logger, chBatch chan models.BatchDelete) *GRPCHandler {
 	return &GRPCHandler{
-		l:    l,
-		stor: stor,
+		l:       l,
+		stor:    stor,
+		chBatch: chBatch,
 	}
 }
 
+// SetChecker set destination checker for create and redirect, nil checker turns screening off
+func (s *GRPCHandler) SetChecker(chk checker.DestinationChecker) {
+	s.checker = chk
+}
+
 // GetLink get original link
 func (s *GRPCHandler) GetLink(ctx context.Context, url *ls.GetLinkReq) (*ls.GetLinkRes, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	var response ls.GetLinkRes
 
-	if len(url.Id) != config.LENHASH {
+	if !utils.IsValidShortKey(url.Id) {
 		return nil, status.Errorf(codes.InvalidArgument, errs.ErrCorrectURL.Error())
 	}
 
-	s.l.Info("Get ID:", zap.String("id", url.Id))
+	// the same key can be used on different domains
+	domain, err := utils.RequestDomain(ctx, s.stor.DomainByHost, url.Domain)
+	if errors.Is(err, errs.ErrDomainNotFound) {
+		return nil, statusError(errs.ErrURLNotFound)
+	}
+	if err != nil {
+		return nil, statusError(err)
+	}
+	key := models.LinkKey(domain, models.ShortURL(url.Id))
 
-	s.l.Info("Get ID:", zap.String("id", url.Id))
-	foundedURL, err := s.stor.GetLinkDB(ctx, models.ShortURL(url.Id))
+	foundedLink, err := s.stor.GetLinkDB(ctx, key)
 
 	if err != nil {
-		if errors.Is(err, errs.ErrURLIsGone) {
-			s.l.Info(errs.ErrURLIsGone.Error(), zap.Error(err))
-			return nil, status.Errorf(codes.AlreadyExists, errs.ErrCorrectURL.Error())
-		}
+		logger.FromContext(ctx).Info(errs.ErrBadRequest.Error(), zap.Error(err))
+		return nil, statusError(err)
+	}
 
-		s.l.Info(errs.ErrBadRequest.Error(), zap.Error(err))
-		return nil, status.Errorf(codes.NotFound, errs.ErrCorrectURL.Error())
+	// link is turned off by owner
+	if foundedLink.Inactive {
+		return nil, statusError(errs.ErrLinkInactive)
+	}
 
+	// destination can be blocked after link was saved, link unblocked by admin is not screened
+	if !foundedLink.Blocked && !foundedLink.Allowed && checker.OnRedirect() {
+		v := checker.Screen(ctx, s.checker, string(foundedLink.Origin), logger.FromContext(ctx))
+		foundedLink.Blocked = v.Blocked
+		// link blocked by failed check is not flagged
+		if v.Blocked && !v.Failed {
+			if err = s.stor.SetBlocked(ctx, key, true, v.Reason); err != nil {
+				logger.FromContext(ctx).Info("flag blocked link error", zap.Error(err))
+			}
+		}
+	}
+	if foundedLink.Blocked {
+		return nil, statusError(errs.ErrLinkBlocked)
 	}
 
-	header := metadata.Pairs("Location", string(foundedURL))
+	// Record click asynchronously
+	s.stor.SaveClick(clickEvent(ctx, key))
+
+	header := metadata.Pairs(
+		"Location", string(foundedLink.Origin),
+		"Cache-Control", utils.CacheControl(foundedLink, time.Now()),
+	)
 	grpc.SendHeader(ctx, header)
 
+	response.RedirectCode = int32(foundedLink.RedirectCode)
+
 	return &response, nil
 }
 
-// GetPing get ping from db if connected
-func (s *GRPCHandler) GetPing(ctx context.Context, empt *emptypb.Empty) (*ls.GetPingRes, error) {
+// Shorten convert link to shorting and store in database
+func (s *GRPCHandler) Shorten(ctx context.Context, in *ls.ShortenReq) (*ls.ShortenRes, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	var response ls.GetPingRes
+	if in.Url == "" {
+		return nil, statusError(errs.ErrEmptyBody)
+	}
+
+	origin, err := utils.NormalizeURL(in.Url)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	opts, err := linkOptions(in.Alias, in.ExpiresIn, in.ExpiresAt, in.RedirectCode)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	opts.Domain, err = utils.ResolveDomain(ctx, s.stor.DomainByHost, in.Domain, interceptors.GetContextUserID(ctx))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	v := checker.Screen(ctx, s.checker, origin, logger.FromContext(ctx))
+	opts.Blocked, opts.BlockReason = v.Blocked, v.Reason
 
-	conn, err := db.SQLDBConnection(s.l)
-	if err == nil {
-		err = conn.Ping(ctx)
+	baseURL, err := baseURL()
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	userID := interceptors.GetContextUserID(ctx)
+
+	shortKey, err := s.stor.SaveLinkDB(ctx, userID, models.Origin(origin), opts)
+	if errors.Is(err, errs.ErrAlreadyHasShort) {
+		// Existing short link is returned in details like in body of REST conflict
+		st, _ := status.New(codes.AlreadyExists, errs.ErrAlreadyHasShort.Error()).
+			WithDetails(&errdetails.ResourceInfo{
+				ResourceType: "short_url",
+				ResourceName: utils.LinkURL(baseURL, models.LinkKey(opts.Domain, shortKey)),
+			})
+		return nil, st.Err()
+	}
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &ls.ShortenRes{
+		Result: utils.LinkURL(baseURL, models.LinkKey(opts.Domain, shortKey)),
+	}, nil
+}
+
+// ShortenBatch save multiply links
+func (s *GRPCHandler) ShortenBatch(ctx context.Context, in *ls.ShortenBatchReq) (*ls.ShortenBatchRes, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	urls := make([]models.BatchReqURL, 0, len(in.Urls))
+	for _, u := range in.Urls {
+		origin, err := utils.NormalizeURL(u.OriginalUrl)
 		if err != nil {
-			return nil, status.Error(codes.Internal, errs.ErrInternalSrv.Error())
+			return nil, status.Errorf(codes.InvalidArgument, "%s: %s", err, u.CorrelationId)
 		}
 
-		return &response, nil
-	} else {
-		s.l.Info("not connect to db", zap.Error(err))
+		opts, err := linkOptions(u.Alias, u.ExpiresIn, u.ExpiresAt, u.RedirectCode)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%s: %s", err, u.CorrelationId)
+		}
+
+		domain, err := utils.ResolveDomain(ctx, s.stor.DomainByHost, u.Domain, interceptors.GetContextUserID(ctx))
+		if errors.Is(err, errs.ErrInvalidDomain) || errors.Is(err, errs.ErrUnknownDomain) {
+			return nil, status.Errorf(codes.InvalidArgument, "%s: %s", err, u.CorrelationId)
+		}
+		if err != nil {
+			return nil, statusError(err)
+		}
+
+		urls = append(urls, models.BatchReqURL{
+			CorrID:    u.CorrelationId,
+			Origin:    origin,
+			Alias:     string(opts.Alias),
+			ExpiresAt: opts.ExpiresAt,
+			Redirect:  opts.RedirectCode,
+			Domain:    domain,
+		})
+	}
+
+	// destinations of batch are checked concurrently
+	origins := make([]string, len(urls))
+	for k := range urls {
+		origins[k] = urls[k].Origin
+	}
+	for k, v := range checker.ScreenAll(ctx, s.checker, origins, logger.FromContext(ctx)) {
+		urls[k].Blocked, urls[k].BlockReason = v.Blocked, v.Reason
+	}
+
+	baseURL, err := baseURL()
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	userID := interceptors.GetContextUserID(ctx)
+
+	shorts, err := s.stor.SaveBatch(ctx, userID, urls)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	response := &ls.ShortenBatchRes{
+		Urls: make([]*ls.ShortenBatchResItem, 0, len(shorts)),
+	}
+	for _, v := range shorts {
+		response.Urls = append(response.Urls, &ls.ShortenBatchResItem{
+			CorrelationId: v.CorrID,
+			ShortUrl:      utils.LinkURL(baseURL, models.LinkKey(v.Domain, models.ShortURL(v.Short))),
+		})
+	}
+
+	return response, nil
+}
+
+// ListUserLinks get all links of user
+func (s *GRPCHandler) ListUserLinks(ctx context.Context, _ *emptypb.Empty) (*ls.ListUserLinksRes, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	baseURL, err := baseURL()
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	userID := interceptors.GetContextUserID(ctx)
+
+	links, err := s.stor.LinksByUser(ctx, userID)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	response := &ls.ListUserLinksRes{
+		Urls: make([]*ls.UserLink, 0, len(links)),
+	}
+	for k, v := range links {
+		response.Urls = append(response.Urls, &ls.UserLink{
+			ShortUrl:    utils.LinkURL(baseURL, k),
+			OriginalUrl: string(v),
+		})
+	}
+
+	return response, nil
+}
+
+// DeleteUserLinks delete links of user with fan in channel
+func (s *GRPCHandler) DeleteUserLinks(ctx context.Context, in *ls.DeleteUserLinksReq) (*ls.DeleteUserLinksRes, error) {
+	if len(in.Urls) == 0 {
+		return nil, statusError(errs.ErrCorrectURL)
+	}
+
+	userID := interceptors.GetContextUserID(ctx)
+
+	jobID, err := s.enqueue(ctx, models.BatchDelete{
+		UserID: string(userID),
+		URLs:   in.Urls,
+		Trace:  tracing.Carrier(ctx),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &ls.DeleteUserLinksRes{JobId: jobID}, nil
+}
+
+// RestoreUserLinks restore soft deleted links of user with fan in channel
+func (s *GRPCHandler) RestoreUserLinks(ctx context.Context, in *ls.RestoreUserLinksReq) (*ls.RestoreUserLinksRes, error) {
+	if len(in.Urls) == 0 {
+		return nil, statusError(errs.ErrCorrectURL)
+	}
+
+	userID := interceptors.GetContextUserID(ctx)
+
+	jobID, err := s.enqueue(ctx, models.BatchDelete{
+		Op:     models.BatchOpRestore,
+		UserID: string(userID),
+		URLs:   in.Urls,
+		Trace:  tracing.Carrier(ctx),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &ls.RestoreUserLinksRes{JobId: jobID}, nil
+}
+
+// GetJob get status of batch job of user
+func (s *GRPCHandler) GetJob(ctx context.Context, in *ls.GetJobReq) (*ls.GetJobRes, error) {
+	userID := interceptors.GetContextUserID(ctx)
+
+	job, err := jobs.Instance().Get(string(userID), in.Id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	response := &ls.GetJobRes{
+		Id:      job.ID,
+		Status:  job.Status,
+		Error:   job.Error,
+		Results: make([]*ls.JobResult, 0, len(job.Results)),
+	}
+	for _, r := range job.Results {
+		response.Results = append(response.Results, &ls.JobResult{Url: r.URL, Outcome: r.Outcome})
+	}
+
+	return response, nil
+}
+
+// enqueue create job for batch and push batch to queue without blocking
+func (s *GRPCHandler) enqueue(ctx context.Context, chStruct models.BatchDelete) (string, error) {
+	job, err := jobs.Instance().Create(chStruct.UserID)
+	if err != nil {
+		return "", err
+	}
+	chStruct.JobID = job.ID
+
+	select {
+	case s.chBatch <- chStruct:
+		logger.FromContext(ctx).Info("new chStruct", zap.String("op", chStruct.Op), zap.String("job", job.ID))
+		return job.ID, nil
+	default:
+		jobs.Instance().Discard(job.ID)
+		return "", errs.ErrQueueFull
+	}
+}
+
+// GetStats get statistics quantity urls and users, trusted subnet is checked by interceptor
+func (s *GRPCHandler) GetStats(ctx context.Context, _ *emptypb.Empty) (*ls.GetStatsRes, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	foundedStat, err := s.stor.GetStats(ctx, "")
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &ls.GetStatsRes{
+		Urls:  int64(foundedStat.URLs),
+		Users: int64(foundedStat.Users),
+	}, nil
+}
+
+// UpdateLink change destination, state or redirect params of user link
+func (s *GRPCHandler) UpdateLink(ctx context.Context, in *ls.UpdateLinkReq) (*ls.UpdateLinkRes, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if !utils.IsValidShortKey(in.Id) {
+		return nil, statusError(errs.ErrCorrectURL)
+	}
+
+	var upd models.LinkUpdate
+
+	if in.Url != nil {
+		origin, err := utils.NormalizeURL(in.GetUrl())
+		if err != nil {
+			return nil, statusError(err)
+		}
+
+		// new destination is screened like on create
+		v := checker.Screen(ctx, s.checker, origin, logger.FromContext(ctx))
+		upd.Origin = (*models.Origin)(&origin)
+		upd.Blocked, upd.BlockReason = v.Blocked, v.Reason
+	}
+
+	if in.Active != nil {
+		active := in.GetActive()
+		upd.Active = &active
+	}
+
+	if in.RedirectCode != nil {
+		code := int(in.GetRedirectCode())
+		if err := utils.ValidateRedirect(code); err != nil {
+			return nil, statusError(err)
+		}
+		upd.RedirectCode = &code
+	}
+
+	at, err := timestampValue(in.ExpiresAt)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	if in.ExpiresIn != 0 || !at.IsZero() {
+		expiresAt, err := utils.LinkExpiry(in.ExpiresIn, at)
+		if err != nil {
+			return nil, statusError(err)
+		}
+		upd.ExpiresAt = &expiresAt
+	}
+
+	if upd.Origin == nil && upd.Active == nil && upd.RedirectCode == nil && upd.ExpiresAt == nil {
+		return nil, statusError(errs.ErrEmptyUpdate)
+	}
+
+	baseURL, err := baseURL()
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	key := models.LinkKey(strings.ToLower(in.Domain), models.ShortURL(in.Id))
+	link, err := s.stor.UpdateLink(ctx, interceptors.GetContextUserID(ctx), key, upd)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	response := &ls.UpdateLinkRes{
+		ShortUrl:     utils.LinkURL(baseURL, key),
+		OriginalUrl:  string(link.Origin),
+		Active:       !link.Inactive,
+		RedirectCode: int32(link.RedirectCode),
+	}
+	if !link.ExpiresAt.IsZero() {
+		response.ExpiresAt = timestamppb.New(link.ExpiresAt)
+	}
+
+	return response, nil
+}
+
+// GetPing ping of active storage
+func (s *GRPCHandler) GetPing(ctx context.Context, empt *emptypb.Empty) (*ls.GetPingRes, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var response ls.GetPingRes
+
+	if err := s.stor.Health(ctx); err != nil {
+		logger.FromContext(ctx).Info("storage is not ready", zap.Error(err))
 		return nil, status.Error(codes.Internal, errs.ErrInternalSrv.Error())
 	}
+
+	return &response, nil
+}
+
+// clickEvent make click event from grpc metadata and peer address
+func clickEvent(ctx context.Context, shortKey models.ShortURL) models.ClickEvent {
+	ev := models.ClickEvent{
+		Short:     shortKey,
+		ClickedAt: time.Now().UTC(),
+	}
+
+	var realIP string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("referer"); len(v) > 0 {
+			ev.Referrer = v[0]
+		}
+		if v := md.Get("user-agent"); len(v) > 0 {
+			ev.UserAgent = v[0]
+		}
+		if v := md.Get("x-real-ip"); len(v) > 0 {
+			realIP = v[0]
+		}
+	}
+
+	// Real ip from metadata is used only from trusted proxy
+	if p, ok := peer.FromContext(ctx); ok {
+		ev.IPBucket = utils.IPBucket(utils.RealIP(p.Addr.String(), realIP))
+	}
+
+	return ev
+}
+
+// linkOptions validate and convert optional params of new link
+func linkOptions(alias string, expiresIn int64, expiresAt *timestamppb.Timestamp, redirect int32) (models.LinkOptions, error) {
+	var opts models.LinkOptions
+
+	if alias != "" {
+		if err := utils.ValidateAlias(alias); err != nil {
+			return opts, err
+		}
+		opts.Alias = models.ShortURL(alias)
+	}
+
+	at, err := timestampValue(expiresAt)
+	if err != nil {
+		return opts, err
+	}
+	expires, err := utils.LinkExpiry(expiresIn, at)
+	if err != nil {
+		return opts, err
+	}
+	opts.ExpiresAt = expires
+
+	if err = utils.ValidateRedirect(int(redirect)); err != nil {
+		return opts, err
+	}
+	opts.RedirectCode = int(redirect)
+
+	return opts, nil
+}
+
+// timestampValue time of optional timestamp, missing or empty timestamp is zero time
+func timestampValue(ts *timestamppb.Timestamp) (time.Time, error) {
+	if ts == nil || (ts.GetSeconds() == 0 && ts.GetNanos() == 0) {
+		return time.Time{}, nil
+	}
+	if !ts.IsValid() {
+		return time.Time{}, errs.ErrInvalidExpiry
+	}
+	return ts.AsTime(), nil
+}
+
+// baseURL get base url of short links from config
+func baseURL() (string, error) {
+	// config instance
+	cfg, err := config.Instance()
+	if err != nil {
+		return "", errs.ErrInternalSrv
+	}
+
+	// config value
+	baseURL, err := cfg.GetCfgValue(config.BaseURL)
+	if err != nil {
+		return "", errs.ErrInternalSrv
+	}
+
+	return baseURL, nil
+}
+
+// statusError map storage and validation errors to grpc status codes
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, errs.ErrURLIsGone):
+		return status.Error(codes.NotFound, errs.ErrURLIsGone.Error())
+	case errors.Is(err, errs.ErrURLNotFound), errors.Is(err, errs.ErrNotFoundURL):
+		return status.Error(codes.NotFound, errs.ErrURLNotFound.Error())
+	case errors.Is(err, errs.ErrLinkInactive):
+		return status.Error(codes.NotFound, errs.ErrLinkInactive.Error())
+	case errors.Is(err, errs.ErrJobNotFound):
+		return status.Error(codes.NotFound, errs.ErrJobNotFound.Error())
+	case errors.Is(err, errs.ErrAlreadyHasShort), errors.Is(err, errs.ErrAliasTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, errs.ErrInvalidAlias),
+		errors.Is(err, errs.ErrInvalidExpiry),
+		errors.Is(err, errs.ErrInvalidRedirect),
+		errors.Is(err, errs.ErrCorrectURL),
+		errors.Is(err, errs.ErrInvalidURL),
+		errors.Is(err, errs.ErrPrivateURL),
+		errors.Is(err, errs.ErrInvalidDomain),
+		errors.Is(err, errs.ErrUnknownDomain),
+		errors.Is(err, errs.ErrEmptyUpdate),
+		errors.Is(err, errs.ErrEmptyBody):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, errs.ErrLinkBlocked), errors.Is(err, errs.ErrDomainForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, errs.ErrQueueFull):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	return status.Error(codes.Internal, errs.ErrInternalSrv.Error())
 }
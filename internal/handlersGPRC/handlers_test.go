@@ -0,0 +1,216 @@
+package handlersgrpc_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"testing"
+	"time"
+
+	handlersgrpc "github.com/grishagavrin/link-shortener/internal/handlersGPRC"
+	"github.com/grishagavrin/link-shortener/internal/handlersGPRC/interceptors"
+	"github.com/grishagavrin/link-shortener/internal/logger"
+	ls "github.com/grishagavrin/link-shortener/internal/proto"
+	"github.com/grishagavrin/link-shortener/internal/storage"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/grishagavrin/link-shortener/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newClient клиент grpc сервера в памяти с хранилищем из конфигурации
+func newClient(t *testing.T) ls.ApiServiceClient {
+	chBatch := make(chan models.BatchDelete, 16)
+	// создаем логер
+	l, _ := logger.Instance()
+	// создаем хранение
+	stor, err := storage.Instance(l, chBatch)
+	assert.NoError(t, err)
+
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		interceptors.UnaryTrustedSubnet,
+		interceptors.UnaryAuth,
+	))
+	ls.RegisterApiServiceServer(srv, handlersgrpc.New(stor.Repository, l, chBatch))
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Stop()
+		close(chBatch)
+	})
+	return ls.NewApiServiceClient(conn)
+}
+
+// userContext контекст вызова от имени пользователя
+func userContext(t *testing.T, userID string) context.Context {
+	encoded, err := utils.Encode(userID)
+	assert.NoError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), interceptors.UserIDMetadataKey, encoded)
+}
+
+func TestGRPCHandler_Shorten(t *testing.T) {
+	client := newClient(t)
+	ctx := userContext(t, "grpc-shorten-user")
+	uniq := time.Now().UnixNano()
+	existing := fmt.Sprintf("http://yandex.ru/grpc-existing-%d", uniq)
+	_, err := client.Shorten(ctx, &ls.ShortenReq{Url: existing})
+	assert.NoError(t, err)
+
+	// создаём массив тестов: запрос и желаемый код ответа
+	tests := []struct {
+		name string
+		req  *ls.ShortenReq
+		code codes.Code
+	}{
+		{
+			name: "positive test #1",
+			req:  &ls.ShortenReq{Url: fmt.Sprintf("http://yandex.ru/grpc-%d", uniq)},
+			code: codes.OK,
+		},
+		{
+			name: "positive test #2",
+			req:  &ls.ShortenReq{Url: fmt.Sprintf("http://yandex.ru/grpc-empty-ts-%d", uniq), ExpiresAt: &timestamppb.Timestamp{}},
+			code: codes.OK,
+		},
+		{
+			name: "positive test #3",
+			req:  &ls.ShortenReq{Url: fmt.Sprintf("http://yandex.ru/grpc-alias-%d", uniq), Alias: fmt.Sprintf("grpc-%d", uniq)},
+			code: codes.OK,
+		},
+		{
+			name: "negative test #1",
+			req:  &ls.ShortenReq{},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "negative test #2",
+			req:  &ls.ShortenReq{Url: fmt.Sprintf("http://yandex.ru/grpc-past-%d", uniq), ExpiresAt: timestamppb.New(time.Now().Add(-time.Hour))},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "negative test #3",
+			req:  &ls.ShortenReq{Url: fmt.Sprintf("http://yandex.ru/grpc-bad-ts-%d", uniq), ExpiresAt: &timestamppb.Timestamp{Nanos: -1}},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "negative test #4",
+			req:  &ls.ShortenReq{Url: existing},
+			code: codes.AlreadyExists,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := client.Shorten(ctx, tt.req)
+			assert.Equal(t, tt.code, status.Code(err))
+			if tt.code != codes.OK {
+				return
+			}
+
+			// короткая ссылка отдается по ключу
+			_, err = client.GetLink(ctx, &ls.GetLinkReq{Id: path.Base(res.Result)})
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestGRPCHandler_UserLinks(t *testing.T) {
+	client := newClient(t)
+	ctx := userContext(t, fmt.Sprintf("grpc-user-%d", time.Now().UnixNano()))
+	other := userContext(t, "grpc-other-user")
+	uniq := time.Now().UnixNano()
+
+	// пакет ссылок пользователя
+	batch, err := client.ShortenBatch(ctx, &ls.ShortenBatchReq{Urls: []*ls.ShortenBatchItem{
+		{CorrelationId: "1", OriginalUrl: fmt.Sprintf("http://yandex.ru/grpc-batch-1-%d", uniq)},
+		{CorrelationId: "2", OriginalUrl: fmt.Sprintf("http://yandex.ru/grpc-batch-2-%d", uniq), ExpiresAt: &timestamppb.Timestamp{}},
+	}})
+	assert.NoError(t, err)
+	assert.Len(t, batch.Urls, 2)
+	first := path.Base(batch.Urls[0].ShortUrl)
+
+	// список ссылок пользователя содержит пакет
+	links, err := client.ListUserLinks(ctx, &emptypb.Empty{})
+	assert.NoError(t, err)
+	assert.Len(t, links.Urls, 2)
+
+	// пустая временная метка не задает срок жизни
+	active := false
+	upd, err := client.UpdateLink(ctx, &ls.UpdateLinkReq{Id: first, Active: &active, ExpiresAt: &timestamppb.Timestamp{}})
+	assert.NoError(t, err)
+	assert.Nil(t, upd.ExpiresAt)
+	_, err = client.GetLink(ctx, &ls.GetLinkReq{Id: first})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// чужую ссылку изменить нельзя, пустое изменение не принимается
+	_, err = client.UpdateLink(other, &ls.UpdateLinkReq{Id: first, Active: &active})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.UpdateLink(ctx, &ls.UpdateLinkReq{Id: first})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// удаление выполняется заданием, которое видно только владельцу
+	del, err := client.DeleteUserLinks(ctx, &ls.DeleteUserLinksReq{Urls: []string{first}})
+	assert.NoError(t, err)
+	job := waitJob(t, client, ctx, del.JobId)
+	assert.Equal(t, []*ls.JobResult{{Url: first, Outcome: models.JobURLDeleted}}, jobResults(job))
+	_, err = client.GetJob(other, &ls.GetJobReq{Id: del.JobId})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	restore, err := client.RestoreUserLinks(ctx, &ls.RestoreUserLinksReq{Urls: []string{first}})
+	assert.NoError(t, err)
+	job = waitJob(t, client, ctx, restore.JobId)
+	assert.Equal(t, []*ls.JobResult{{Url: first, Outcome: models.JobURLRestored}}, jobResults(job))
+
+	// пустой список ссылок не ставится в очередь
+	_, err = client.DeleteUserLinks(ctx, &ls.DeleteUserLinksReq{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// статистика доступна только из доверенной сети
+	_, err = client.GetStats(ctx, &emptypb.Empty{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.GetPing(ctx, &emptypb.Empty{})
+	assert.NoError(t, err)
+}
+
+// waitJob ждет завершения задания пользователя
+func waitJob(t *testing.T, client ls.ApiServiceClient, ctx context.Context, id string) *ls.GetJobRes {
+	for n := 0; n < 100; n++ {
+		job, err := client.GetJob(ctx, &ls.GetJobReq{Id: id})
+		assert.NoError(t, err)
+		if job.Status == models.JobDone || job.Status == models.JobFailed {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s is not finished", id)
+	return nil
+}
+
+// jobResults результаты задания без служебных полей сообщений
+func jobResults(job *ls.GetJobRes) []*ls.JobResult {
+	results := make([]*ls.JobResult, 0, len(job.Results))
+	for _, r := range job.Results {
+		results = append(results, &ls.JobResult{Url: r.Url, Outcome: r.Outcome})
+	}
+	return results
+}
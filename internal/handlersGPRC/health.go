@@ -0,0 +1,75 @@
+package handlersgrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/health"
+	ls "github.com/grishagavrin/link-shortener/internal/proto"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// HealthServer standard grpc.health.v1 service answered by readiness checks of components
+type HealthServer struct {
+	healthpb.UnimplementedHealthServer
+	ready *health.Registry
+}
+
+// NewHealthServer allocation health service with readiness checks
+func NewHealthServer(ready *health.Registry) *HealthServer {
+	return &HealthServer{ready: ready}
+}
+
+// Check status of whole server or of api service
+func (s *HealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if !knownService(req.Service) {
+		return nil, status.Errorf(codes.NotFound, "unknown service %s", req.Service)
+	}
+
+	return &healthpb.HealthCheckResponse{Status: s.status(ctx)}, nil
+}
+
+// Watch send status of service at start and every time it changes
+func (s *HealthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ctx := stream.Context()
+	if !knownService(req.Service) {
+		// By protocol unknown service is reported, it may be registered later
+		return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVICE_UNKNOWN})
+	}
+
+	ticker := time.NewTicker(config.HEALTHWATCH)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		if current := s.status(ctx); current != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+			last = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// status serving status by readiness checks
+func (s *HealthServer) status(ctx context.Context) healthpb.HealthCheckResponse_ServingStatus {
+	if s.ready.Check(ctx).Status == models.HealthFail {
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
+
+// knownService empty name is whole server
+func knownService(name string) bool {
+	return name == "" || name == ls.ApiService_ServiceDesc.ServiceName
+}
@@ -0,0 +1,350 @@
+// Package interceptors consist unary and stream interceptors for grpc server
+package interceptors
+
+import (
+	"context"
+	"net"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/logger"
+	"github.com/grishagavrin/link-shortener/internal/metrics"
+	ls "github.com/grishagavrin/link-shortener/internal/proto"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/grishagavrin/link-shortener/internal/tracing"
+	"github.com/grishagavrin/link-shortener/internal/utils"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Context type
+type ContextType string
+
+// UserIDMetadataKey metadata key with encrypted user id, same as cookie value
+var UserIDMetadataKey = "user-id"
+
+// UserIDCtxName set context name for user id
+var UserIDCtxName ContextType = "ctxUserId"
+
+// InternalMethods methods allowed only from trusted subnet
+var InternalMethods = map[string]bool{
+	ls.ApiService_GetStats_FullMethodName: true,
+}
+
+// wrappedStream server stream with changed context
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context return changed context of stream
+func (w *wrappedStream) Context() context.Context {
+	return w.ctx
+}
+
+// RequestIDMetadataKey metadata key with id of call, it is taken from client or generated
+var RequestIDMetadataKey = "x-request-id"
+
+// callEntryCtxName set context name for entry of call log
+var callEntryCtxName ContextType = "ctxCallEntry"
+
+// callEntry data of call known after auth interceptor
+type callEntry struct {
+	userID string
+}
+
+// UnaryLogger assign or propagate request id, put logger of call to context and log call with status code and latency
+func UnaryLogger(l *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx, reqLog, entry := callContext(ctx, l)
+		if err := grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, requestID(ctx))); err != nil {
+			reqLog.Info("set request id header error", zap.Error(err))
+		}
+
+		resp, err := handler(ctx, req)
+		logRequest(reqLog, info.FullMethod, entry, start, err)
+		return resp, err
+	}
+}
+
+// StreamLogger assign or propagate request id, put logger of call to context and log call with status code and latency
+func StreamLogger(l *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, reqLog, entry := callContext(ss.Context(), l)
+		if err := ss.SetHeader(metadata.Pairs(RequestIDMetadataKey, requestID(ctx))); err != nil {
+			reqLog.Info("set request id header error", zap.Error(err))
+		}
+
+		err := handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+		logRequest(reqLog, info.FullMethod, entry, start, err)
+		return err
+	}
+}
+
+// requestIDCtxName set context name for id of call
+var requestIDCtxName ContextType = "ctxRequestId"
+
+// callContext put id of call, logger with correlation fields and entry of call log to context
+func callContext(ctx context.Context, l *zap.Logger) (context.Context, *zap.Logger, *callEntry) {
+	id := uuid.New().String()
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(RequestIDMetadataKey); len(v) > 0 && utils.IsValidRequestID(v[0]) {
+			id = v[0]
+		}
+	}
+
+	reqLog := logger.WithTrace(ctx, l.With(zap.String("request_id", id)))
+	entry := &callEntry{}
+	ctx = context.WithValue(ctx, requestIDCtxName, id)
+	ctx = context.WithValue(ctx, callEntryCtxName, entry)
+	return logger.WithContext(ctx, reqLog), reqLog, entry
+}
+
+// requestID id of call from context
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxName).(string)
+	return id
+}
+
+// logRequest write call info to log
+func logRequest(l *zap.Logger, method string, entry *callEntry, start time.Time, err error) {
+	l.Info("grpc request",
+		zap.String("method", method),
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("latency", time.Since(start)),
+		zap.String("user_id", entry.userID),
+	)
+}
+
+// UnaryMetrics count unary calls and their latency by method and status code
+func UnaryMetrics(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	metrics.ObserveGRPC(info.FullMethod, status.Code(err).String(), time.Since(start))
+	return resp, err
+}
+
+// StreamMetrics count stream calls and their latency by method and status code
+func StreamMetrics(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	metrics.ObserveGRPC(info.FullMethod, status.Code(err).String(), time.Since(start))
+	return err
+}
+
+// UnaryTracing start span of call with parent from W3C traceparent metadata
+func UnaryTracing(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, span := startSpan(ctx, info.FullMethod)
+	defer span.End()
+
+	resp, err := handler(ctx, req)
+	endSpan(span, err)
+	return resp, err
+}
+
+// StreamTracing start span of call with parent from W3C traceparent metadata
+func StreamTracing(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span := startSpan(ss.Context(), info.FullMethod)
+	defer span.End()
+
+	err := handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+	endSpan(span, err)
+	return err
+}
+
+// startSpan start server span named by full method like /api.ApiService/GetLink
+func startSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	}
+
+	service, method := path.Split(strings.TrimPrefix(fullMethod, "/"))
+	return tracing.Tracer().Start(ctx, fullMethod,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(strings.TrimSuffix(service, "/")),
+			semconv.RPCMethod(method),
+		),
+	)
+}
+
+// endSpan set status code of call to span, server errors mark span as failed
+func endSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	switch code {
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.DeadlineExceeded, codes.Unimplemented:
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+}
+
+// metadataCarrier adapter of grpc metadata for propagator
+type metadataCarrier metadata.MD
+
+// Get value of key
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Set value of key
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys list keys of metadata
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// UnaryAuth set user id from encrypted metadata token, new user gets token in header
+func UnaryAuth(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := authContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamAuth set user id from encrypted metadata token, new user gets token in header
+func StreamAuth(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := authContext(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authContext decode user id from metadata or generate new one
+func authContext(ctx context.Context) (context.Context, error) {
+	userID := uuid.New().String()
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(UserIDMetadataKey); len(v) > 0 {
+			var decoded string
+			if err := utils.Decode(v[0], &decoded); err == nil {
+				return withUser(ctx, decoded), nil
+			}
+		}
+	}
+
+	encoded, err := utils.Encode(userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, errs.ErrInternalSrv.Error())
+	}
+
+	if err = grpc.SetHeader(ctx, metadata.Pairs(UserIDMetadataKey, encoded)); err != nil {
+		return nil, status.Error(codes.Internal, errs.ErrInternalSrv.Error())
+	}
+
+	return withUser(ctx, userID), nil
+}
+
+// withUser put user id to context of call, to logger of call and to call log
+func withUser(ctx context.Context, userID string) context.Context {
+	if entry, ok := ctx.Value(callEntryCtxName).(*callEntry); ok {
+		entry.userID = userID
+	}
+	ctx = logger.WithContext(ctx, logger.FromContext(ctx).With(zap.String("user_id", userID)))
+	return context.WithValue(ctx, UserIDCtxName, userID)
+}
+
+// UnaryTrustedSubnet allow internal methods only from trusted subnet
+func UnaryTrustedSubnet(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := checkTrusted(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamTrustedSubnet allow internal methods only from trusted subnet
+func StreamTrustedSubnet(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := checkTrusted(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// checkTrusted check ip of client is in trusted subnet
+func checkTrusted(ctx context.Context, method string) error {
+	if !InternalMethods[method] {
+		return nil
+	}
+
+	// config instance
+	cfg, err := config.Instance()
+	if err != nil {
+		return status.Error(codes.Internal, errs.ErrInternalSrv.Error())
+	}
+
+	// config value
+	trustedCIDR, err := cfg.GetCfgValue(config.TrustedSubnet)
+	if err != nil {
+		return status.Error(codes.Internal, errs.ErrInternalSrv.Error())
+	}
+
+	// check if config value is empty, then method blocked
+	if trustedCIDR == "" {
+		return status.Error(codes.PermissionDenied, errs.ErrInvalidIP.Error())
+	}
+
+	_, privateCIDR, err := net.ParseCIDR(trustedCIDR)
+	if err != nil {
+		return status.Error(codes.Internal, errs.ErrInternalSrv.Error())
+	}
+
+	// check if subnet contains ip
+	if !privateCIDR.Contains(clientIP(ctx)) {
+		return status.Error(codes.PermissionDenied, errs.ErrInvalidIP.Error())
+	}
+
+	return nil
+}
+
+// clientIP get ip from peer address or from x-real-ip metadata of trusted proxy
+func clientIP(ctx context.Context) net.IP {
+	var remote, realIP string
+	if p, ok := peer.FromContext(ctx); ok {
+		remote = p.Addr.String()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("x-real-ip"); len(v) > 0 {
+			realIP = v[0]
+		}
+	}
+
+	return net.ParseIP(utils.RealIP(remote, realIP))
+}
+
+// GetContextUserID return uniq user id from request context
+func GetContextUserID(ctx context.Context) models.UniqUser {
+	userIDCtx := ctx.Value(UserIDCtxName)
+	userID := "all"
+	if userIDCtx != nil {
+		// Convert interface type to user.UniqUser
+		userID = userIDCtx.(string)
+	}
+
+	return models.UniqUser(userID)
+}
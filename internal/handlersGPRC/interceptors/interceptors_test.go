@@ -0,0 +1,295 @@
+package interceptors
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	ls "github.com/grishagavrin/link-shortener/internal/proto"
+	"github.com/grishagavrin/link-shortener/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// fakeTransport транспорт вызова, запоминает заголовки ответа
+type fakeTransport struct {
+	header metadata.MD
+}
+
+func (f *fakeTransport) Method() string { return ls.ApiService_GetLink_FullMethodName }
+
+func (f *fakeTransport) SetHeader(md metadata.MD) error {
+	f.header = metadata.Join(f.header, md)
+	return nil
+}
+
+func (f *fakeTransport) SendHeader(md metadata.MD) error { return f.SetHeader(md) }
+
+func (f *fakeTransport) SetTrailer(metadata.MD) error { return nil }
+
+// fakeStream серверный поток с контекстом вызова
+type fakeStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (f *fakeStream) Context() context.Context { return f.ctx }
+
+func (f *fakeStream) SetHeader(md metadata.MD) error {
+	f.header = metadata.Join(f.header, md)
+	return nil
+}
+
+// incomingContext контекст входящего вызова с метаданными, адресом клиента и транспортом
+func incomingContext(md metadata.MD, addr string, tr *fakeTransport) context.Context {
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	if addr != "" {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 40000}})
+	}
+	if tr != nil {
+		ctx = grpc.NewContextWithServerTransportStream(ctx, tr)
+	}
+	return ctx
+}
+
+// fakeHandler обработчик вызова, запоминает контекст
+func fakeHandler(got *context.Context) grpc.UnaryHandler {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		*got = ctx
+		return req, nil
+	}
+}
+
+func TestUnaryAuth(t *testing.T) {
+	token, _ := utils.Encode("user-1")
+
+	// создаём массив тестов: метаданные вызова и желаемый результат
+	tests := []struct {
+		name      string
+		md        metadata.MD
+		transport bool
+		user      string
+		newToken  bool
+		code      codes.Code
+	}{
+		{
+			name: "positive test #1",
+			md:   metadata.Pairs(UserIDMetadataKey, token),
+			user: "user-1",
+		},
+		{
+			name:      "positive test #2",
+			md:        metadata.MD{},
+			transport: true,
+			newToken:  true,
+		},
+		{
+			name:      "positive test #3",
+			md:        metadata.Pairs(UserIDMetadataKey, "broken"),
+			transport: true,
+			newToken:  true,
+		},
+		{
+			name: "negative test #1",
+			md:   metadata.MD{},
+			code: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &fakeTransport{}
+			if !tt.transport {
+				tr = nil
+			}
+
+			var got context.Context
+			_, err := UnaryAuth(incomingContext(tt.md, "", tr), "req", &grpc.UnaryServerInfo{}, fakeHandler(&got))
+			assert.Equal(t, tt.code, status.Code(err))
+			if err != nil {
+				return
+			}
+
+			user := string(GetContextUserID(got))
+			if tt.user != "" {
+				assert.Equal(t, tt.user, user)
+			}
+			if !tt.newToken {
+				return
+			}
+
+			// новый пользователь получает токен в заголовке ответа
+			var decoded string
+			assert.NoError(t, utils.Decode(tr.header.Get(UserIDMetadataKey)[0], &decoded))
+			assert.Equal(t, user, decoded)
+		})
+	}
+}
+
+func TestUnaryTrustedSubnet(t *testing.T) {
+	// создаём массив тестов: метод, адрес клиента и желаемый код ответа
+	tests := []struct {
+		name   string
+		method string
+		addr   string
+		realIP string
+		code   codes.Code
+	}{
+		{
+			name:   "positive test #1",
+			method: ls.ApiService_GetLink_FullMethodName,
+			addr:   "10.1.1.1",
+			code:   codes.OK,
+		},
+		{
+			name:   "positive test #2",
+			method: ls.ApiService_GetStats_FullMethodName,
+			addr:   "127.0.0.1",
+			code:   codes.OK,
+		},
+		{
+			name:   "negative test #1",
+			method: ls.ApiService_GetStats_FullMethodName,
+			addr:   "10.1.1.1",
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "negative test #2",
+			method: ls.ApiService_GetStats_FullMethodName,
+			addr:   "10.1.1.1",
+			realIP: "127.0.0.1",
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "negative test #3",
+			method: ls.ApiService_GetStats_FullMethodName,
+			code:   codes.PermissionDenied,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест, x-real-ip от недоверенного адреса не учитывается
+		t.Run(tt.name, func(t *testing.T) {
+			md := metadata.MD{}
+			if tt.realIP != "" {
+				md.Set("x-real-ip", tt.realIP)
+			}
+			ctx := incomingContext(md, tt.addr, nil)
+
+			var got context.Context
+			_, err := UnaryTrustedSubnet(ctx, "req", &grpc.UnaryServerInfo{FullMethod: tt.method}, fakeHandler(&got))
+			assert.Equal(t, tt.code, status.Code(err))
+
+			err = StreamTrustedSubnet(nil, &fakeStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: tt.method},
+				func(interface{}, grpc.ServerStream) error { return nil })
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestUnaryLogger(t *testing.T) {
+	token, _ := utils.Encode("user-1")
+
+	// создаём массив тестов: id вызова от клиента и желаемый результат
+	tests := []struct {
+		name      string
+		requestID string
+		keep      bool
+		err       error
+		code      string
+	}{
+		{
+			name:      "positive test #1",
+			requestID: "client-request-1",
+			keep:      true,
+			code:      codes.OK.String(),
+		},
+		{
+			name: "positive test #2",
+			code: codes.OK.String(),
+		},
+		{
+			name:      "negative test #1",
+			requestID: "bad request id",
+			code:      codes.OK.String(),
+		},
+		{
+			name: "negative test #2",
+			err:  status.Error(codes.NotFound, "not found"),
+			code: codes.NotFound.String(),
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.InfoLevel)
+			md := metadata.Pairs(UserIDMetadataKey, token)
+			if tt.requestID != "" {
+				md.Set(RequestIDMetadataKey, tt.requestID)
+			}
+			tr := &fakeTransport{}
+			info := &grpc.UnaryServerInfo{FullMethod: ls.ApiService_GetLink_FullMethodName}
+
+			var got context.Context
+			_, err := UnaryLogger(zap.New(core))(incomingContext(md, "", tr), "req", info,
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					// пользователь определяется после логгера и попадает в запись вызова
+					_, err := UnaryAuth(ctx, req, info, fakeHandler(&got))
+					assert.NoError(t, err)
+					return nil, tt.err
+				})
+			assert.Equal(t, tt.err, err)
+
+			// id вызова отдается клиенту, некорректный id заменяется новым
+			id := tr.header.Get(RequestIDMetadataKey)[0]
+			assert.Equal(t, id, requestID(got))
+			assert.Equal(t, tt.keep, id == tt.requestID)
+
+			entries := logs.FilterMessage("grpc request").All()
+			assert.Len(t, entries, 1)
+			fields := entries[0].ContextMap()
+			assert.Equal(t, ls.ApiService_GetLink_FullMethodName, fields["method"])
+			assert.Equal(t, tt.code, fields["code"])
+			assert.Equal(t, "user-1", fields["user_id"])
+			assert.Equal(t, id, fields["request_id"])
+		})
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	token, _ := utils.Encode("user-1")
+	core, logs := observer.New(zapcore.InfoLevel)
+	ss := &fakeStream{ctx: incomingContext(metadata.Pairs(UserIDMetadataKey, token), "127.0.0.1", nil)}
+	info := &grpc.StreamServerInfo{FullMethod: ls.ApiService_GetStats_FullMethodName}
+
+	// логгер, доверенная сеть и авторизация потока по цепочке
+	var got context.Context
+	err := StreamLogger(zap.New(core))(nil, ss, info, func(srv interface{}, ss grpc.ServerStream) error {
+		return StreamTrustedSubnet(srv, ss, info, func(srv interface{}, ss grpc.ServerStream) error {
+			return StreamAuth(srv, ss, info, func(_ interface{}, ss grpc.ServerStream) error {
+				got = ss.Context()
+				return nil
+			})
+		})
+	})
+	assert.NoError(t, err)
+
+	// контекст потока содержит пользователя и id вызова, id отдается клиенту
+	assert.Equal(t, "user-1", string(GetContextUserID(got)))
+	assert.Equal(t, ss.header.Get(RequestIDMetadataKey)[0], requestID(got))
+	entries := logs.FilterMessage("grpc request").All()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "user-1", entries[0].ContextMap()["user_id"])
+
+	// поток без пользователя и без транспорта не авторизуется
+	err = StreamAuth(nil, &fakeStream{ctx: incomingContext(metadata.MD{}, "", nil)}, info,
+		func(interface{}, grpc.ServerStream) error { return nil })
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
@@ -0,0 +1,119 @@
+// Package health implement readiness checks of service components
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+)
+
+// Check readiness of component, nil error means component is ready
+type Check func(ctx context.Context) error
+
+// degradedError error of component which still serves requests
+type degradedError struct {
+	err error
+}
+
+func (e degradedError) Error() string { return e.err.Error() }
+
+func (e degradedError) Unwrap() error { return e.err }
+
+// Degraded mark error of check as degraded, service with degraded component stays ready
+func Degraded(err error) error {
+	if err == nil {
+		return nil
+	}
+	return degradedError{err: err}
+}
+
+// Registry named readiness checks of components
+type Registry struct {
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+// New empty registry
+func New() *Registry {
+	return &Registry{checks: map[string]Check{}}
+}
+
+// Add check of component, check with the same name is replaced
+func (r *Registry) Add(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks[name] = check
+}
+
+// Check run checks of components concurrently with timeout,
+// service is ready when no component failed
+func (r *Registry) Check(ctx context.Context) models.HealthReport {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Check, len(names))
+	for n, name := range names {
+		checks[n] = r.checks[name]
+	}
+	r.mu.RUnlock()
+
+	results := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for n, check := range checks {
+		wg.Add(1)
+		go func(n int, check Check) {
+			defer wg.Done()
+			results[n] = run(ctx, check)
+		}(n, check)
+	}
+	wg.Wait()
+
+	report := models.HealthReport{
+		Status:     models.HealthOK,
+		Components: make(map[string]models.ComponentHealth, len(names)),
+	}
+	for n, name := range names {
+		var degraded degradedError
+		switch {
+		case results[n] == nil:
+			report.Components[name] = models.ComponentHealth{Status: models.HealthOK}
+		case errors.As(results[n], &degraded):
+			if report.Status == models.HealthOK {
+				report.Status = models.HealthDegraded
+			}
+			report.Components[name] = models.ComponentHealth{Status: models.HealthDegraded, Error: results[n].Error()}
+		default:
+			report.Status = models.HealthFail
+			report.Components[name] = models.ComponentHealth{Status: models.HealthFail, Error: results[n].Error()}
+		}
+	}
+
+	return report
+}
+
+// run check with timeout, hung check is reported as failed
+func run(ctx context.Context, check Check) error {
+	ctx, cancel := context.WithTimeout(ctx, config.HEALTHTIMEOUT)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return errs.ErrHealthTimeout
+	}
+}
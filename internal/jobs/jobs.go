@@ -0,0 +1,150 @@
+// Package jobs implement tracker of asynchronous batch jobs
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+)
+
+// jobIDSize size of random job id in bytes
+const jobIDSize = 16
+
+// cleanupEvery number of created jobs between scans for expired jobs
+const cleanupEvery = 256
+
+// Tracker keep statuses of batch jobs in memory
+type Tracker struct {
+	mu   sync.Mutex
+	jobs map[string]*models.Job
+	ttl  time.Duration
+	// created count of jobs created since last cleanup
+	created int
+}
+
+// instance singleton for tracker
+var (
+	instance *Tracker
+	once     sync.Once
+)
+
+// Instance get tracker of batch jobs
+func Instance() *Tracker {
+	once.Do(func() {
+		instance = New(config.JOBTTL)
+	})
+	return instance
+}
+
+// New tracker with lifetime of finished jobs
+func New(ttl time.Duration) *Tracker {
+	return &Tracker{
+		jobs: make(map[string]*models.Job),
+		ttl:  ttl,
+	}
+}
+
+// Create new queued job of user
+func (t *Tracker) Create(userID string) (models.Job, error) {
+	b := make([]byte, jobIDSize)
+	if _, err := rand.Read(b); err != nil {
+		return models.Job{}, err
+	}
+
+	now := time.Now().UTC()
+	job := &models.Job{
+		ID:        hex.EncodeToString(b),
+		UserID:    userID,
+		Status:    models.JobQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// expired jobs are not returned by Get, so map is scanned only every cleanupEvery jobs
+	t.created++
+	if t.created >= cleanupEvery {
+		t.created = 0
+		t.cleanup(now)
+	}
+	t.jobs[job.ID] = job
+
+	return *job, nil
+}
+
+// Start mark job as running, empty id is ignored
+func (t *Tracker) Start(id string) {
+	t.update(id, func(job *models.Job) {
+		job.Status = models.JobRunning
+	})
+}
+
+// Finish set outcomes of job, job is failed if error is not nil
+func (t *Tracker) Finish(id string, results []models.JobResult, err error) {
+	t.update(id, func(job *models.Job) {
+		job.Results = results
+		job.Status = models.JobDone
+		if err != nil {
+			job.Status = models.JobFailed
+			job.Error = err.Error()
+		}
+	})
+}
+
+// Discard remove job which was not queued
+func (t *Tracker) Discard(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.jobs, id)
+}
+
+// Get job of user by id, finished jobs older than ttl are not found
+func (t *Tracker) Get(userID, id string) (models.Job, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	job, ok := t.jobs[id]
+	if !ok || job.UserID != userID || t.expired(job, time.Now().UTC()) {
+		return models.Job{}, errs.ErrJobNotFound
+	}
+
+	res := *job
+	res.Results = append([]models.JobResult(nil), job.Results...)
+	return res, nil
+}
+
+// update change job by id under lock
+func (t *Tracker) update(id string, fn func(job *models.Job)) {
+	if id == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if job, ok := t.jobs[id]; ok {
+		fn(job)
+		job.UpdatedAt = time.Now().UTC()
+	}
+}
+
+// cleanup remove finished jobs older than ttl
+func (t *Tracker) cleanup(now time.Time) {
+	for id, job := range t.jobs {
+		if t.expired(job, now) {
+			delete(t.jobs, id)
+		}
+	}
+}
+
+// expired check job is finished and older than ttl
+func (t *Tracker) expired(job *models.Job, now time.Time) bool {
+	finished := job.Status == models.JobDone || job.Status == models.JobFailed
+	return finished && now.Sub(job.UpdatedAt) > t.ttl
+}
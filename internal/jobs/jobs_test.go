@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// errApply ошибка применения пакета
+var errApply = errors.New("apply failed")
+
+func TestTracker(t *testing.T) {
+	results := []models.JobResult{{URL: "abc", Outcome: models.JobURLDeleted}}
+
+	// создаём массив тестов: действия с заданием, кто его запрашивает и желаемый результат
+	tests := []struct {
+		name    string
+		run     func(tr *Tracker, id string)
+		user    string
+		status  string
+		results []models.JobResult
+		errMsg  string
+		err     error
+	}{
+		{
+			name:   "positive test #1",
+			run:    func(*Tracker, string) {},
+			user:   "u1",
+			status: models.JobQueued,
+		},
+		{
+			name:   "positive test #2",
+			run:    func(tr *Tracker, id string) { tr.Start(id) },
+			user:   "u1",
+			status: models.JobRunning,
+		},
+		{
+			name: "positive test #3",
+			run: func(tr *Tracker, id string) {
+				tr.Start(id)
+				tr.Finish(id, results, nil)
+			},
+			user:    "u1",
+			status:  models.JobDone,
+			results: results,
+		},
+		{
+			name:   "positive test #4",
+			run:    func(tr *Tracker, id string) { tr.Finish(id, nil, errApply) },
+			user:   "u1",
+			status: models.JobFailed,
+			errMsg: errApply.Error(),
+		},
+		{
+			name: "negative test #1",
+			run:  func(*Tracker, string) {},
+			user: "u2",
+			err:  errs.ErrJobNotFound,
+		},
+		{
+			name: "negative test #2",
+			run:  func(tr *Tracker, id string) { tr.Discard(id) },
+			user: "u1",
+			err:  errs.ErrJobNotFound,
+		},
+		{
+			name: "negative test #3",
+			run: func(tr *Tracker, id string) {
+				tr.Finish(id, results, nil)
+				time.Sleep(20 * time.Millisecond)
+			},
+			user: "u1",
+			err:  errs.ErrJobNotFound,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест, задание создает пользователь u1
+		t.Run(tt.name, func(t *testing.T) {
+			tr := New(10 * time.Millisecond)
+			job, err := tr.Create("u1")
+			assert.NoError(t, err)
+			assert.Equal(t, models.JobQueued, job.Status)
+
+			tt.run(tr, job.ID)
+
+			// чужое, удаленное и устаревшее задание не находится
+			got, err := tr.Get(tt.user, job.ID)
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			assert.Equal(t, tt.status, got.Status)
+			assert.Equal(t, tt.results, got.Results)
+			assert.Equal(t, tt.errMsg, got.Error)
+		})
+	}
+}
+
+func TestTracker_Cleanup(t *testing.T) {
+	tr := New(10 * time.Millisecond)
+	running, err := tr.Create("u1")
+	assert.NoError(t, err)
+	tr.Start(running.ID)
+	done, err := tr.Create("u1")
+	assert.NoError(t, err)
+	tr.Finish(done.ID, nil, nil)
+	time.Sleep(20 * time.Millisecond)
+
+	// устаревшие завершенные задания удаляются не на каждом создании, а раз в cleanupEvery
+	for n := 0; n < cleanupEvery-3; n++ {
+		_, err = tr.Create("u2")
+		assert.NoError(t, err)
+	}
+	assert.Contains(t, tr.jobs, done.ID)
+
+	_, err = tr.Create("u2")
+	assert.NoError(t, err)
+	assert.NotContains(t, tr.jobs, done.ID)
+
+	// незавершенное задание не удаляется
+	assert.Contains(t, tr.jobs, running.ID)
+}
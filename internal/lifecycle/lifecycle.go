@@ -0,0 +1,109 @@
+// Package lifecycle implement ordered start and graceful stop of app components
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Hook start and stop functions of component, any function may be nil
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+	// Timeout of start and stop, default timeout of manager is used if zero
+	Timeout time.Duration
+}
+
+// Manager start components in order of registration and stop them in reverse order
+type Manager struct {
+	mu      sync.Mutex
+	l       *zap.Logger
+	timeout time.Duration
+	hooks   []Hook
+	started int
+}
+
+// New manager with default timeout of hooks
+func New(l *zap.Logger, timeout time.Duration) *Manager {
+	return &Manager{l: l, timeout: timeout}
+}
+
+// Append register hook of component, components registered later are stopped earlier
+func (m *Manager) Append(h Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, h)
+}
+
+// Start run start hooks in order, already started components are stopped if one of hooks fails
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	hooks := m.hooks[m.started:]
+	m.mu.Unlock()
+
+	for _, h := range hooks {
+		if h.Start != nil {
+			if err := m.call(ctx, h, h.Start); err != nil {
+				m.l.Info("component start error", zap.String("component", h.Name), zap.Error(err))
+				if stopErr := m.Stop(context.Background()); stopErr != nil {
+					m.l.Info("stop after start error", zap.Error(stopErr))
+				}
+				return fmt.Errorf("start %s: %w", h.Name, err)
+			}
+		}
+
+		m.mu.Lock()
+		m.started++
+		m.mu.Unlock()
+		m.l.Info("component started", zap.String("component", h.Name))
+	}
+
+	return nil
+}
+
+// Stop run stop hooks of started components in reverse order, every hook has own timeout,
+// the first error is returned after all hooks are called
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	hooks := m.hooks[:m.started]
+	m.started = 0
+	m.mu.Unlock()
+
+	var first error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		if h.Stop == nil {
+			continue
+		}
+
+		start := time.Now()
+		if err := m.call(ctx, h, h.Stop); err != nil {
+			m.l.Info("component stop error", zap.String("component", h.Name), zap.Error(err))
+			if first == nil {
+				first = fmt.Errorf("stop %s: %w", h.Name, err)
+			}
+			continue
+		}
+		m.l.Info("component stopped", zap.String("component", h.Name), zap.Duration("took", time.Since(start)))
+	}
+
+	return first
+}
+
+// call hook function with timeout of hook
+func (m *Manager) call(ctx context.Context, h Hook, fn func(ctx context.Context) error) error {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = m.timeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return fn(ctx)
+}
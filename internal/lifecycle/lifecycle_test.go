@@ -0,0 +1,107 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// errStart ошибка запуска компонента
+var errStart = errors.New("start failed")
+
+// recorder запоминает вызовы хуков компонентов
+type recorder struct {
+	calls []string
+}
+
+// hook компонент, который записывает запуск и остановку
+func (r *recorder) hook(name string, startErr error) Hook {
+	return Hook{
+		Name: name,
+		Start: func(context.Context) error {
+			r.calls = append(r.calls, "start "+name)
+			return startErr
+		},
+		Stop: func(context.Context) error {
+			r.calls = append(r.calls, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestManager(t *testing.T) {
+	// создаём массив тестов: ошибки запуска компонентов и ожидаемые вызовы
+	tests := []struct {
+		name  string
+		errs  []error
+		err   error
+		calls []string
+	}{
+		{
+			name:  "positive test #1",
+			errs:  []error{nil, nil, nil},
+			calls: []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"},
+		},
+		{
+			name:  "negative test #1",
+			errs:  []error{nil, errStart, nil},
+			err:   errStart,
+			calls: []string{"start a", "start b", "stop a"},
+		},
+		{
+			name:  "negative test #2",
+			errs:  []error{errStart, nil, nil},
+			err:   errStart,
+			calls: []string{"start a"},
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			m := New(zap.NewNop(), time.Second)
+			for n, name := range []string{"a", "b", "c"} {
+				m.Append(rec.hook(name, tt.errs[n]))
+			}
+
+			// при ошибке запуска уже запущенные компоненты останавливаются
+			err := m.Start(context.Background())
+			assert.ErrorIs(t, err, tt.err)
+			if err == nil {
+				assert.NoError(t, m.Stop(context.Background()))
+			}
+			assert.Equal(t, tt.calls, rec.calls)
+
+			// повторная остановка ничего не вызывает
+			assert.NoError(t, m.Stop(context.Background()))
+			assert.Equal(t, tt.calls, rec.calls)
+		})
+	}
+}
+
+func TestManager_StopTimeout(t *testing.T) {
+	rec := &recorder{}
+	m := New(zap.NewNop(), time.Second)
+	m.Append(rec.hook("a", nil))
+	m.Append(Hook{
+		Name: "slow",
+		Stop: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		Timeout: 20 * time.Millisecond,
+	})
+	m.Append(rec.hook("c", nil))
+	assert.NoError(t, m.Start(context.Background()))
+
+	// зависший компонент останавливается по своему таймауту, остальные все равно останавливаются
+	start := time.Now()
+	err := m.Stop(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, []string{"start a", "start c", "stop c", "stop a"}, rec.calls)
+}
@@ -2,22 +2,42 @@
 package logger
 
 import (
+	"context"
+	"fmt"
 	"time"
 
+	"github.com/grishagavrin/link-shortener/internal/config"
 	"github.com/grishagavrin/link-shortener/internal/errs"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// Formats of logs
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Defaults used if config is not available
+const (
+	defaultLevel  = "info"
+	defaultFormat = FormatJSON
+)
+
 // instance singleton for logger
 var instance *zap.Logger
 
+// ctxKey context key of logger of request
+type ctxKey struct{}
+
 // Instance new Config
 func Instance() (*zap.Logger, error) {
 	if instance == nil {
 		instance = new(zap.Logger)
-		logger, err := newLogger("info")
+		level, format := logConfig()
+		logger, err := newLogger(level, format)
 		if err != nil {
 			// return nil, fmt.Errorf("%w: %v", errs.ErrInitLogger, err)
 			err = errors.Wrap(err, errs.ErrInitLogger.Error())
@@ -29,8 +49,28 @@ func Instance() (*zap.Logger, error) {
 	return instance, nil
 }
 
+// logConfig get level and format of logs from config
+func logConfig() (string, string) {
+	cfg, err := config.Instance()
+	if err != nil {
+		return defaultLevel, defaultFormat
+	}
+
+	level, err := cfg.GetCfgValue(config.LogLevel)
+	if err != nil || level == "" {
+		level = defaultLevel
+	}
+
+	format, err := cfg.GetCfgValue(config.LogFormat)
+	if err != nil || format == "" {
+		format = defaultFormat
+	}
+
+	return level, format
+}
+
 // new create new logger
-func newLogger(level string) (*zap.Logger, error) {
+func newLogger(level, format string) (*zap.Logger, error) {
 	// Init config
 	cfg := zap.NewProductionConfig()
 	// Set level
@@ -42,6 +82,13 @@ func newLogger(level string) (*zap.Logger, error) {
 		return nil, errors.Wrap(err, errs.ErrJSONUnMarshall.Error())
 	}
 	cfg.Level = atom
+	// Encoding of records
+	switch format {
+	case FormatJSON, FormatConsole:
+		cfg.Encoding = format
+	default:
+		return nil, fmt.Errorf("%w: %s", errs.ErrLogFormat, format)
+	}
 	// Output set
 	cfg.OutputPaths = []string{"stdout"}
 	// Time format
@@ -62,3 +109,30 @@ func Info(msg string, fields ...zap.Field) {
 		l.Info(msg, fields...)
 	}
 }
+
+// WithContext put logger of request to context
+func WithContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext get logger of request from context, otherwise logger of service with trace of context
+func FromContext(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*zap.Logger); ok {
+		return l
+	}
+
+	l, err := Instance()
+	if err != nil {
+		return zap.NewNop()
+	}
+	return WithTrace(ctx, l)
+}
+
+// WithTrace add ids of trace and span from context to logger
+func WithTrace(ctx context.Context, l *zap.Logger) *zap.Logger {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return l
+	}
+	return l.With(zap.String("trace_id", sc.TraceID().String()), zap.String("span_id", sc.SpanID().String()))
+}
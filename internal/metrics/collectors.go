@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Descriptions of cache metrics
+var (
+	cacheHitsDesc      = prometheus.NewDesc(namespace+"_cache_hits_total", "Count of link lookups served from cache.", nil, nil)
+	cacheMissesDesc    = prometheus.NewDesc(namespace+"_cache_misses_total", "Count of link lookups passed to storage.", nil, nil)
+	cacheEvictionsDesc = prometheus.NewDesc(namespace+"_cache_evictions_total", "Count of links evicted from full cache.", nil, nil)
+	cacheSizeDesc      = prometheus.NewDesc(namespace+"_cache_size", "Count of links in cache.", nil, nil)
+)
+
+// cacheCollector collect counters of link cache on scrape
+type cacheCollector struct {
+	stats func() models.CacheStats
+}
+
+// Describe send descriptions of cache metrics
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheEvictionsDesc
+	ch <- cacheSizeDesc
+}
+
+// Collect send current counters of cache
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue, float64(s.Evictions))
+	ch <- prometheus.MustNewConstMetric(cacheSizeDesc, prometheus.GaugeValue, float64(s.Size))
+}
+
+// Descriptions of pgx pool metrics
+var (
+	poolTotalDesc    = prometheus.NewDesc(namespace+"_pgxpool_total_conns", "Count of connections in pool.", nil, nil)
+	poolAcquiredDesc = prometheus.NewDesc(namespace+"_pgxpool_acquired_conns", "Count of connections in use.", nil, nil)
+	poolIdleDesc     = prometheus.NewDesc(namespace+"_pgxpool_idle_conns", "Count of idle connections.", nil, nil)
+	poolMaxDesc      = prometheus.NewDesc(namespace+"_pgxpool_max_conns", "Max count of connections in pool.", nil, nil)
+	poolAcquiresDesc = prometheus.NewDesc(namespace+"_pgxpool_acquires_total", "Count of successful acquires from pool.", nil, nil)
+	poolEmptyDesc    = prometheus.NewDesc(namespace+"_pgxpool_empty_acquires_total", "Count of acquires which waited for connection.", nil, nil)
+	poolWaitDesc     = prometheus.NewDesc(namespace+"_pgxpool_acquire_duration_seconds_total", "Total time of acquires from pool.", nil, nil)
+)
+
+// poolCollector collect stats of pgx pool on scrape
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+// Describe send descriptions of pool metrics
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolTotalDesc
+	ch <- poolAcquiredDesc
+	ch <- poolIdleDesc
+	ch <- poolMaxDesc
+	ch <- poolAcquiresDesc
+	ch <- poolEmptyDesc
+	ch <- poolWaitDesc
+}
+
+// Collect send current stats of pool
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(poolTotalDesc, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquiredDesc, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxDesc, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquiresDesc, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyDesc, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolWaitDesc, prometheus.CounterValue, s.AcquireDuration().Seconds())
+}
@@ -0,0 +1,144 @@
+// Package metrics implement prometheus collectors of service
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefix of all metrics
+const namespace = "shortener"
+
+// registry of service metrics with go runtime and process collectors
+var registry = prometheus.NewRegistry()
+
+// Collectors of service
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Count of HTTP requests by route pattern, method and status code.",
+	}, []string{"route", "method", "code"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route pattern and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+	grpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "Count of gRPC calls by method and status code.",
+	}, []string{"method", "code"})
+	grpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Latency of gRPC calls by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+	storageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Latency of storage operations by backend and operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"backend", "op"})
+	storageErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_errors_total",
+		Help:      "Count of failed storage operations by backend and operation.",
+	}, []string{"backend", "op"})
+	deleteGroups = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "delete_group_batches",
+		Help:      "Count of batches applied together by delete workers.",
+		Buckets:   []float64{1, 2, 5, 10, 25, 50, 100, 250},
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		grpcRequests,
+		grpcDuration,
+		storageDuration,
+		storageErrors,
+		deleteGroups,
+	)
+}
+
+// Handler of metrics endpoint, it is served by separate listener
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTP count HTTP request
+func ObserveHTTP(route, method string, code int, d time.Duration) {
+	httpRequests.WithLabelValues(route, method, statusCode(code)).Inc()
+	httpDuration.WithLabelValues(route, method).Observe(d.Seconds())
+}
+
+// ObserveGRPC count gRPC call
+func ObserveGRPC(method, code string, d time.Duration) {
+	grpcRequests.WithLabelValues(method, code).Inc()
+	grpcDuration.WithLabelValues(method).Observe(d.Seconds())
+}
+
+// ObserveStorage count storage operation, failed is true for unexpected errors
+func ObserveStorage(backend, op string, d time.Duration, failed bool) {
+	storageDuration.WithLabelValues(backend, op).Observe(d.Seconds())
+	if failed {
+		storageErrors.WithLabelValues(backend, op).Inc()
+	}
+}
+
+// ObserveDeleteGroup count batches applied together by delete worker
+func ObserveDeleteGroup(batches int) {
+	deleteGroups.Observe(float64(batches))
+}
+
+// RegisterDeleteQueue export depth and capacity of delete queue
+func RegisterDeleteQueue(chBatch chan models.BatchDelete) error {
+	depth := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "delete_queue_depth",
+		Help:      "Count of batches waiting in delete queue.",
+	}, func() float64 { return float64(len(chBatch)) })
+	capacity := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "delete_queue_capacity",
+		Help:      "Max count of batches in delete queue.",
+	}, func() float64 { return float64(cap(chBatch)) })
+
+	if err := registry.Register(depth); err != nil {
+		return err
+	}
+	return registry.Register(capacity)
+}
+
+// RegisterCache export counters of link cache
+func RegisterCache(stats func() models.CacheStats) error {
+	return registry.Register(&cacheCollector{stats: stats})
+}
+
+// RegisterPool export stats of pgx pool
+func RegisterPool(pool *pgxpool.Pool) error {
+	return registry.Register(&poolCollector{pool: pool})
+}
+
+// statusCode label of HTTP status, zero status means 200 written implicitly
+func statusCode(code int) string {
+	if code == 0 {
+		code = http.StatusOK
+	}
+	return strconv.Itoa(code)
+}
@@ -20,8 +20,16 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	ApiService_GetLink_FullMethodName = "/api.apiService/GetLink"
-	ApiService_GetPing_FullMethodName = "/api.apiService/GetPing"
+	ApiService_GetLink_FullMethodName          = "/api.apiService/GetLink"
+	ApiService_GetPing_FullMethodName          = "/api.apiService/GetPing"
+	ApiService_Shorten_FullMethodName          = "/api.apiService/Shorten"
+	ApiService_ShortenBatch_FullMethodName     = "/api.apiService/ShortenBatch"
+	ApiService_ListUserLinks_FullMethodName    = "/api.apiService/ListUserLinks"
+	ApiService_DeleteUserLinks_FullMethodName  = "/api.apiService/DeleteUserLinks"
+	ApiService_RestoreUserLinks_FullMethodName = "/api.apiService/RestoreUserLinks"
+	ApiService_GetJob_FullMethodName           = "/api.apiService/GetJob"
+	ApiService_GetStats_FullMethodName         = "/api.apiService/GetStats"
+	ApiService_UpdateLink_FullMethodName       = "/api.apiService/UpdateLink"
 )
 
 // ApiServiceClient is the client API for ApiService service.
@@ -30,6 +38,14 @@ const (
 type ApiServiceClient interface {
 	GetLink(ctx context.Context, in *GetLinkReq, opts ...grpc.CallOption) (*GetLinkRes, error)
 	GetPing(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetPingRes, error)
+	Shorten(ctx context.Context, in *ShortenReq, opts ...grpc.CallOption) (*ShortenRes, error)
+	ShortenBatch(ctx context.Context, in *ShortenBatchReq, opts ...grpc.CallOption) (*ShortenBatchRes, error)
+	ListUserLinks(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListUserLinksRes, error)
+	DeleteUserLinks(ctx context.Context, in *DeleteUserLinksReq, opts ...grpc.CallOption) (*DeleteUserLinksRes, error)
+	RestoreUserLinks(ctx context.Context, in *RestoreUserLinksReq, opts ...grpc.CallOption) (*RestoreUserLinksRes, error)
+	GetJob(ctx context.Context, in *GetJobReq, opts ...grpc.CallOption) (*GetJobRes, error)
+	GetStats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetStatsRes, error)
+	UpdateLink(ctx context.Context, in *UpdateLinkReq, opts ...grpc.CallOption) (*UpdateLinkRes, error)
 }
 
 type apiServiceClient struct {
@@ -58,12 +74,92 @@ func (c *apiServiceClient) GetPing(ctx context.Context, in *emptypb.Empty, opts
 	return out, nil
 }
 
+func (c *apiServiceClient) Shorten(ctx context.Context, in *ShortenReq, opts ...grpc.CallOption) (*ShortenRes, error) {
+	out := new(ShortenRes)
+	err := c.cc.Invoke(ctx, ApiService_Shorten_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiServiceClient) ShortenBatch(ctx context.Context, in *ShortenBatchReq, opts ...grpc.CallOption) (*ShortenBatchRes, error) {
+	out := new(ShortenBatchRes)
+	err := c.cc.Invoke(ctx, ApiService_ShortenBatch_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiServiceClient) ListUserLinks(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListUserLinksRes, error) {
+	out := new(ListUserLinksRes)
+	err := c.cc.Invoke(ctx, ApiService_ListUserLinks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiServiceClient) DeleteUserLinks(ctx context.Context, in *DeleteUserLinksReq, opts ...grpc.CallOption) (*DeleteUserLinksRes, error) {
+	out := new(DeleteUserLinksRes)
+	err := c.cc.Invoke(ctx, ApiService_DeleteUserLinks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiServiceClient) RestoreUserLinks(ctx context.Context, in *RestoreUserLinksReq, opts ...grpc.CallOption) (*RestoreUserLinksRes, error) {
+	out := new(RestoreUserLinksRes)
+	err := c.cc.Invoke(ctx, ApiService_RestoreUserLinks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiServiceClient) GetJob(ctx context.Context, in *GetJobReq, opts ...grpc.CallOption) (*GetJobRes, error) {
+	out := new(GetJobRes)
+	err := c.cc.Invoke(ctx, ApiService_GetJob_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiServiceClient) GetStats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetStatsRes, error) {
+	out := new(GetStatsRes)
+	err := c.cc.Invoke(ctx, ApiService_GetStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiServiceClient) UpdateLink(ctx context.Context, in *UpdateLinkReq, opts ...grpc.CallOption) (*UpdateLinkRes, error) {
+	out := new(UpdateLinkRes)
+	err := c.cc.Invoke(ctx, ApiService_UpdateLink_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ApiServiceServer is the server API for ApiService service.
 // All implementations must embed UnimplementedApiServiceServer
 // for forward compatibility
 type ApiServiceServer interface {
 	GetLink(context.Context, *GetLinkReq) (*GetLinkRes, error)
 	GetPing(context.Context, *emptypb.Empty) (*GetPingRes, error)
+	Shorten(context.Context, *ShortenReq) (*ShortenRes, error)
+	ShortenBatch(context.Context, *ShortenBatchReq) (*ShortenBatchRes, error)
+	ListUserLinks(context.Context, *emptypb.Empty) (*ListUserLinksRes, error)
+	DeleteUserLinks(context.Context, *DeleteUserLinksReq) (*DeleteUserLinksRes, error)
+	RestoreUserLinks(context.Context, *RestoreUserLinksReq) (*RestoreUserLinksRes, error)
+	GetJob(context.Context, *GetJobReq) (*GetJobRes, error)
+	GetStats(context.Context, *emptypb.Empty) (*GetStatsRes, error)
+	UpdateLink(context.Context, *UpdateLinkReq) (*UpdateLinkRes, error)
 	mustEmbedUnimplementedApiServiceServer()
 }
 
@@ -77,6 +173,30 @@ func (UnimplementedApiServiceServer) GetLink(context.Context, *GetLinkReq) (*Get
 func (UnimplementedApiServiceServer) GetPing(context.Context, *emptypb.Empty) (*GetPingRes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetPing not implemented")
 }
+func (UnimplementedApiServiceServer) Shorten(context.Context, *ShortenReq) (*ShortenRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Shorten not implemented")
+}
+func (UnimplementedApiServiceServer) ShortenBatch(context.Context, *ShortenBatchReq) (*ShortenBatchRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ShortenBatch not implemented")
+}
+func (UnimplementedApiServiceServer) ListUserLinks(context.Context, *emptypb.Empty) (*ListUserLinksRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUserLinks not implemented")
+}
+func (UnimplementedApiServiceServer) DeleteUserLinks(context.Context, *DeleteUserLinksReq) (*DeleteUserLinksRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUserLinks not implemented")
+}
+func (UnimplementedApiServiceServer) RestoreUserLinks(context.Context, *RestoreUserLinksReq) (*RestoreUserLinksRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreUserLinks not implemented")
+}
+func (UnimplementedApiServiceServer) GetJob(context.Context, *GetJobReq) (*GetJobRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedApiServiceServer) GetStats(context.Context, *emptypb.Empty) (*GetStatsRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedApiServiceServer) UpdateLink(context.Context, *UpdateLinkReq) (*UpdateLinkRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateLink not implemented")
+}
 func (UnimplementedApiServiceServer) mustEmbedUnimplementedApiServiceServer() {}
 
 // UnsafeApiServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -126,6 +246,150 @@ func _ApiService_GetPing_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ApiService_Shorten_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShortenReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServiceServer).Shorten(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ApiService_Shorten_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServiceServer).Shorten(ctx, req.(*ShortenReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ApiService_ShortenBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShortenBatchReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServiceServer).ShortenBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ApiService_ShortenBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServiceServer).ShortenBatch(ctx, req.(*ShortenBatchReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ApiService_ListUserLinks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServiceServer).ListUserLinks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ApiService_ListUserLinks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServiceServer).ListUserLinks(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ApiService_DeleteUserLinks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserLinksReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServiceServer).DeleteUserLinks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ApiService_DeleteUserLinks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServiceServer).DeleteUserLinks(ctx, req.(*DeleteUserLinksReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ApiService_RestoreUserLinks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreUserLinksReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServiceServer).RestoreUserLinks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ApiService_RestoreUserLinks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServiceServer).RestoreUserLinks(ctx, req.(*RestoreUserLinksReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ApiService_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServiceServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ApiService_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServiceServer).GetJob(ctx, req.(*GetJobReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ApiService_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServiceServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ApiService_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServiceServer).GetStats(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ApiService_UpdateLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateLinkReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServiceServer).UpdateLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ApiService_UpdateLink_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServiceServer).UpdateLink(ctx, req.(*UpdateLinkReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ApiService_ServiceDesc is the grpc.ServiceDesc for ApiService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -141,6 +405,38 @@ var ApiService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetPing",
 			Handler:    _ApiService_GetPing_Handler,
 		},
+		{
+			MethodName: "Shorten",
+			Handler:    _ApiService_Shorten_Handler,
+		},
+		{
+			MethodName: "ShortenBatch",
+			Handler:    _ApiService_ShortenBatch_Handler,
+		},
+		{
+			MethodName: "ListUserLinks",
+			Handler:    _ApiService_ListUserLinks_Handler,
+		},
+		{
+			MethodName: "DeleteUserLinks",
+			Handler:    _ApiService_DeleteUserLinks_Handler,
+		},
+		{
+			MethodName: "RestoreUserLinks",
+			Handler:    _ApiService_RestoreUserLinks_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _ApiService_GetJob_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _ApiService_GetStats_Handler,
+		},
+		{
+			MethodName: "UpdateLink",
+			Handler:    _ApiService_UpdateLink_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "link_shortener.proto",
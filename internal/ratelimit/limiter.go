@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/utils/db"
+	"go.uber.org/zap"
+)
+
+// Backends of limiter
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+// Limiter check limits per user, per ip and per route of user
+type Limiter struct {
+	backend Backend
+	user    *Limit
+	ip      *Limit
+	routes  map[string]Limit
+}
+
+// New create limiter, nil limit disables check
+func New(backend Backend, user, ip *Limit, routes map[string]Limit) *Limiter {
+	return &Limiter{
+		backend: backend,
+		user:    user,
+		ip:      ip,
+		routes:  routes,
+	}
+}
+
+// FromConfig create limiter from config, nil limiter means rate limiting is off
+func FromConfig(l *zap.Logger) (*Limiter, error) {
+	cfg, err := config.Instance()
+	if err != nil {
+		return nil, err
+	}
+
+	backendName, err := cfg.GetCfgValue(config.RateLimitBackend)
+	if err != nil {
+		return nil, err
+	}
+
+	var backend Backend
+	switch backendName {
+	case "", "off":
+		return nil, nil
+	case BackendMemory:
+		backend = NewMemory()
+	case BackendPostgres:
+		dbi, err := db.SQLDBConnection(l)
+		if err != nil {
+			return nil, err
+		}
+		backend = NewPostgres(dbi, l)
+	default:
+		return nil, fmt.Errorf("%w: %s", errs.ErrRateLimitBackend, backendName)
+	}
+
+	user, err := limitValue(cfg, config.RateLimitUser)
+	if err != nil {
+		return nil, err
+	}
+
+	ip, err := limitValue(cfg, config.RateLimitIP)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := cfg.GetCfgValue(config.RateLimitRoutes)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := ParseRoutes(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(backend, user, ip, routes), nil
+}
+
+// limitValue parse limit from config, empty value disables limit
+func limitValue(cfg *config.MyConfig, key string) (*Limit, error) {
+	value, err := cfg.GetCfgValue(key)
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	limit, err := ParseLimit(value)
+	if err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
+
+// Allow take tokens from all buckets of request and return the most restrictive result
+func (lim *Limiter) Allow(ctx context.Context, userID, ip, route string) (Result, error) {
+	type check struct {
+		key   string
+		limit *Limit
+	}
+
+	checks := []check{
+		{key: "user:" + userID, limit: lim.user},
+		{key: "ip:" + ip, limit: lim.ip},
+	}
+	if limit, ok := lim.routes[route]; ok {
+		checks = append(checks, check{key: "route:" + route + ":" + userID, limit: &limit})
+	}
+
+	var res *Result
+	for _, c := range checks {
+		if c.limit == nil {
+			continue
+		}
+
+		r, err := lim.backend.Take(ctx, c.key, *c.limit)
+		if err != nil {
+			return Result{Allowed: true}, err
+		}
+
+		if res == nil || restrictive(r, *res) {
+			res = &r
+		}
+	}
+
+	if res == nil {
+		return Result{Allowed: true}, nil
+	}
+	return *res, nil
+}
+
+// restrictive check if result a is more restrictive than b
+func restrictive(a, b Result) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
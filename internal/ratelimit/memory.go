@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval interval for remove full buckets from memory
+const sweepInterval = time.Minute
+
+// bucket state of token bucket
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// Memory backend with buckets in memory of one instance
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+// NewMemory create memory backend
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]*bucket),
+		swept:   time.Now(),
+		now:     time.Now,
+	}
+}
+
+// Take one token from bucket of key
+func (m *Memory) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		m.buckets[key] = b
+	}
+
+	// Refill bucket for time since last take
+	elapsed := now.Sub(b.updated).Seconds()
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(seconds((float64(limit.Burst) - b.tokens) / limit.Rate))
+
+	return result(allowed, b.tokens, limit), nil
+}
+
+// sweep remove buckets which are full already, they are equal to new ones
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.swept) < sweepInterval {
+		return
+	}
+	m.swept = now
+
+	for key, b := range m.buckets {
+		if !b.full.After(now) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// idleBucket time after which bucket is surely full and its row can be removed
+const idleBucket = time.Hour
+
+// Postgres backend with buckets shared between instances in database
+type Postgres struct {
+	dbi   *pgxpool.Pool
+	l     *zap.Logger
+	mu    sync.Mutex
+	swept time.Time
+}
+
+// NewPostgres create postgreSQL backend, table is created by migrations
+func NewPostgres(dbi *pgxpool.Pool, l *zap.Logger) *Postgres {
+	return &Postgres{
+		dbi:   dbi,
+		l:     l,
+		swept: time.Now(),
+	}
+}
+
+// Take one token from bucket of key by one atomic upsert, time is taken from database
+func (p *Postgres) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	query := `
+	INSERT INTO public.rate_limits AS rl (key, tokens, allowed, updated_at)
+	VALUES ($1, $2 - 1, true, now())
+	ON CONFLICT (key) DO UPDATE SET
+		allowed = LEAST($2, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at) * $3) >= 1,
+		tokens = LEAST($2, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at) * $3)
+			- CASE WHEN LEAST($2, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at) * $3) >= 1 THEN 1 ELSE 0 END,
+		updated_at = now()
+	RETURNING tokens, allowed;
+	`
+
+	p.sweep()
+
+	var tokens float64
+	var allowed bool
+	if err := p.dbi.QueryRow(ctx, query, key, float64(limit.Burst), limit.Rate).Scan(&tokens, &allowed); err != nil {
+		return Result{}, fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+
+	return result(allowed, tokens, limit), nil
+}
+
+// sweep remove idle buckets in background not often than sweep interval
+func (p *Postgres) sweep() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if time.Since(p.swept) < sweepInterval {
+		return
+	}
+	p.swept = time.Now()
+
+	go func() {
+		query := "DELETE FROM public.rate_limits WHERE updated_at < $1"
+		if _, err := p.dbi.Exec(context.Background(), query, time.Now().Add(-idleBucket)); err != nil {
+			p.l.Info("sweep rate limits error", zap.Error(err))
+		}
+	}()
+}
@@ -0,0 +1,99 @@
+// Package ratelimit implements token bucket limits with memory and postgreSQL backends
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+)
+
+// Limit token bucket params: rate of tokens per second and size of bucket
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result result of taking token from bucket
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// Backend storage of buckets
+type Backend interface {
+	// Take one token from bucket of key
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// ParseLimit parse limit in format "rate:burst", rate is count of requests per second
+func ParseLimit(value string) (Limit, error) {
+	parts := strings.SplitN(strings.TrimSpace(value), ":", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("%w: %s", errs.ErrRateLimitConfig, value)
+	}
+
+	rate, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || rate <= 0 {
+		return Limit{}, fmt.Errorf("%w: %s", errs.ErrRateLimitConfig, value)
+	}
+
+	burst, err := strconv.Atoi(parts[1])
+	if err != nil || burst < 1 {
+		return Limit{}, fmt.Errorf("%w: %s", errs.ErrRateLimitConfig, value)
+	}
+
+	return Limit{Rate: rate, Burst: burst}, nil
+}
+
+// ParseRoutes parse route limits in format "METHOD /pattern=rate:burst,..."
+func ParseRoutes(value string) (map[string]Limit, error) {
+	routes := make(map[string]Limit)
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%w: %s", errs.ErrRateLimitConfig, item)
+		}
+
+		limit, err := ParseLimit(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		routes[strings.Join(strings.Fields(parts[0]), " ")] = limit
+	}
+
+	return routes, nil
+}
+
+// result build result of bucket with tokens left after take
+func result(allowed bool, tokens float64, limit Limit) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	return res
+}
+
+// seconds convert float seconds to duration
+func seconds(s float64) time.Duration {
+	if s < 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,183 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock управляемое время для backend в памяти
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestMemory_Take(t *testing.T) {
+	// создаём массив тестов: сдвиг времени перед запросом и желаемый результат
+	tests := []struct {
+		name      string
+		advance   time.Duration
+		allowed   bool
+		remaining int
+		retry     time.Duration
+	}{
+		{
+			name:      "positive test #1",
+			allowed:   true,
+			remaining: 2,
+		},
+		{
+			name:      "positive test #2",
+			allowed:   true,
+			remaining: 1,
+		},
+		{
+			name:      "positive test #3",
+			allowed:   true,
+			remaining: 0,
+		},
+		{
+			name:    "negative test #1",
+			allowed: false,
+			retry:   500 * time.Millisecond,
+		},
+		{
+			name:      "positive test #4",
+			advance:   500 * time.Millisecond,
+			allowed:   true,
+			remaining: 0,
+		},
+		{
+			name:      "positive test #5",
+			advance:   time.Second,
+			allowed:   true,
+			remaining: 1,
+		},
+		{
+			name:      "positive test #6",
+			advance:   time.Hour,
+			allowed:   true,
+			remaining: 2,
+		},
+	}
+
+	// ведро на 3 запроса, пополняется на 2 запроса в секунду
+	clock := &fakeClock{now: time.Now()}
+	m := NewMemory()
+	m.now = clock.Now
+	limit := Limit{Rate: 2, Burst: 3}
+
+	for _, tt := range tests {
+		// запускаем тесты по порядку, они зависят от состояния ведра
+		t.Run(tt.name, func(t *testing.T) {
+			clock.now = clock.now.Add(tt.advance)
+			res, err := m.Take(context.Background(), "key", limit)
+			assert.NoError(t, err)
+
+			// проверяем результат
+			assert.Equal(t, tt.allowed, res.Allowed)
+			assert.Equal(t, tt.remaining, res.Remaining)
+			assert.Equal(t, tt.retry, res.RetryAfter)
+			assert.Equal(t, limit.Burst, res.Limit)
+		})
+	}
+}
+
+func TestMemory_Keys(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	m := NewMemory()
+	m.now = clock.Now
+	limit := Limit{Rate: 1, Burst: 1}
+
+	// расходуем ведро первого ключа
+	res, _ := m.Take(context.Background(), "a", limit)
+	assert.True(t, res.Allowed)
+	res, _ = m.Take(context.Background(), "a", limit)
+	assert.False(t, res.Allowed)
+
+	// ведро второго ключа не тронуто
+	res, _ = m.Take(context.Background(), "b", limit)
+	assert.True(t, res.Allowed)
+
+	// полные ведра удаляются после интервала очистки
+	clock.now = clock.now.Add(sweepInterval + time.Second)
+	m.Take(context.Background(), "c", limit)
+	assert.Len(t, m.buckets, 1)
+}
+
+func TestParseLimit(t *testing.T) {
+	// создаём массив тестов: значение и желаемый результат
+	tests := []struct {
+		name  string
+		value string
+		want  Limit
+		err   error
+	}{
+		{
+			name:  "positive test #1",
+			value: "5:20",
+			want:  Limit{Rate: 5, Burst: 20},
+		},
+		{
+			name:  "positive test #2",
+			value: " 0.5:1 ",
+			want:  Limit{Rate: 0.5, Burst: 1},
+		},
+		{
+			name:  "negative test #1",
+			value: "5",
+			err:   errs.ErrRateLimitConfig,
+		},
+		{
+			name:  "negative test #2",
+			value: "0:10",
+			err:   errs.ErrRateLimitConfig,
+		},
+		{
+			name:  "negative test #3",
+			value: "5:0",
+			err:   errs.ErrRateLimitConfig,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			limit, err := ParseLimit(tt.value)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, limit)
+		})
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	user := Limit{Rate: 1, Burst: 5}
+	ip := Limit{Rate: 1, Burst: 2}
+	lim := New(NewMemory(), &user, &ip, map[string]Limit{"POST /": {Rate: 1, Burst: 1}})
+
+	// результат берется по самому строгому ведру: ip
+	res, err := lim.Allow(context.Background(), "u1", "10.0.0.1", "GET /")
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Limit)
+	assert.Equal(t, 1, res.Remaining)
+
+	// ведро маршрута считается по пользователю
+	res, _ = lim.Allow(context.Background(), "u1", "10.0.0.2", "POST /")
+	assert.True(t, res.Allowed)
+	res, _ = lim.Allow(context.Background(), "u1", "10.0.0.3", "POST /")
+	assert.False(t, res.Allowed)
+	res, _ = lim.Allow(context.Background(), "u2", "10.0.0.4", "POST /")
+	assert.True(t, res.Allowed)
+
+	// ведро ip общее для всех пользователей
+	res, _ = lim.Allow(context.Background(), "u3", "10.0.0.1", "GET /")
+	assert.True(t, res.Allowed)
+	res, _ = lim.Allow(context.Background(), "u4", "10.0.0.1", "GET /")
+	assert.False(t, res.Allowed)
+}
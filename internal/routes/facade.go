@@ -11,13 +11,18 @@ type RouterFacade struct {
 	HTTPRoute HTTPRoute
 }
 
-// NewRouterFacade for return instance
+// NewRouterFacade for return instance, invalid config of rate limits is error
 func NewRouterFacade(
 	h *handlers.Handler,
 	l *zap.Logger,
 	chBatch chan models.BatchDelete,
-) *RouterFacade {
-	return &RouterFacade{
-		HTTPRoute: NewHTTPRouter(h, l, chBatch),
+) (*RouterFacade, error) {
+	route, err := NewHTTPRouter(h, l, chBatch)
+	if err != nil {
+		return nil, err
 	}
+
+	return &RouterFacade{
+		HTTPRoute: route,
+	}, nil
 }
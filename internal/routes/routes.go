@@ -6,6 +6,7 @@ import (
 	"github.com/grishagavrin/link-shortener/internal/handlers"
 	"github.com/grishagavrin/link-shortener/internal/handlers/delete"
 	"github.com/grishagavrin/link-shortener/internal/handlers/middlewares"
+	"github.com/grishagavrin/link-shortener/internal/ratelimit"
 	"github.com/grishagavrin/link-shortener/internal/storage/models"
 	"go.uber.org/zap"
 )
@@ -16,24 +17,64 @@ type HTTPRoute struct {
 }
 
 // ServiceRouter define routes in server
-func NewHTTPRouter(h *handlers.Handler, l *zap.Logger, chBatch chan models.BatchDelete) HTTPRoute {
+func NewHTTPRouter(h *handlers.Handler, l *zap.Logger, chBatch chan models.BatchDelete) (HTTPRoute, error) {
 	r := chi.NewRouter()
 	//  h := handlers.New(stor, l)
 
 	// Middlewares
+	r.Use(middlewares.Tracing)
+	r.Use(middlewares.AccessLog(l))
+	r.Use(middlewares.Metrics)
 	r.Use(middlewares.GzipMiddleware)
-	r.Use(middlewares.CooksMiddleware)
-	// Handlers
-	r.Get("/{id}", h.GetLink)
-	r.Post("/", h.SaveTXT)
-	r.Post("/api/shorten", h.SaveJSON)
-	r.Get("/api/user/urls", h.GetLinks)
-	r.Get("/ping", h.GetPing)
-	r.Post("/api/shorten/batch", h.SaveBatch)
-	r.Delete("/api/user/urls", delete.New(l, chBatch).ServeHTTP)
-	r.Get("/api/internal/stats", h.GetStats)
+
+	// Probes are answered without auth, cookies and rate limits
+	r.Get("/healthz", h.GetHealthz)
+	r.Get("/readyz", h.GetReadyz)
+
+	lim, err := ratelimit.FromConfig(l)
+	if err != nil {
+		return HTTPRoute{}, err
+	}
+
+	r.Group(func(r chi.Router) {
+		r.Use(h.APIKeyAuth)
+		r.Use(middlewares.CooksMiddleware)
+
+		// Rate limits need user of request, so they are checked after auth
+		if lim != nil {
+			r.Use(middlewares.RateLimit(lim, l))
+		}
+		// Handlers
+		r.Get("/{id}", h.GetLink)
+		r.With(middlewares.RequireScope(models.ScopeWrite)).Post("/", h.SaveTXT)
+		r.With(middlewares.RequireScope(models.ScopeWrite)).Post("/api/shorten", h.SaveJSON)
+		r.With(middlewares.RequireScope(models.ScopeRead)).Get("/api/user/urls", h.GetLinks)
+		r.With(middlewares.RequireScope(models.ScopeStats)).Get("/api/user/urls/{id}/stats", h.GetLinkStats)
+		r.With(middlewares.RequireScope(models.ScopeWrite)).Patch("/api/user/urls/{id}", h.UpdateLink)
+		r.With(middlewares.RequireScope(models.ScopeRead)).Get("/api/user/urls/{id}/revisions", h.GetLinkRevisions)
+		r.Post("/api/user/token", h.IssueToken)
+		r.Get("/ping", h.GetPing)
+		r.With(middlewares.RequireScope(models.ScopeWrite)).Post("/api/shorten/batch", h.SaveBatch)
+		r.With(middlewares.RequireScope(models.ScopeDelete)).Delete("/api/user/urls", delete.New(l, chBatch).ServeHTTP)
+		r.With(middlewares.RequireScope(models.ScopeDelete)).Post("/api/user/urls/restore", delete.New(l, chBatch).Restore)
+		r.With(middlewares.RequireScope(models.ScopeRead)).Get("/api/user/jobs/{id}", delete.New(l, chBatch).GetJob)
+		r.Route("/api/internal", func(r chi.Router) {
+			r.Use(middlewares.TrustedSubnet)
+			r.Get("/stats", h.GetStats)
+			r.Get("/cache", h.GetCacheStats)
+			r.Post("/keys", h.CreateAPIKey)
+			r.Get("/keys", h.GetAPIKeys)
+			r.Delete("/keys/{id}", h.DeleteAPIKey)
+			r.Get("/blocked", h.GetBlockedLinks)
+			r.Delete("/blocked/{id}", h.UnblockLink)
+			r.Get("/domains", h.GetDomains)
+			r.Post("/domains", h.CreateDomain)
+			r.Delete("/domains/{host}", h.DeleteDomain)
+			r.Post("/purge", delete.New(l, chBatch).Purge)
+		})
+	})
 
 	return HTTPRoute{
 		Route: r,
-	}
+	}, nil
 }
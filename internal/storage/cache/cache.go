@@ -0,0 +1,242 @@
+// Package cache implement read-through LRU cache of links in front of repository
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/handlers"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+)
+
+// entry cached result of link lookup
+type entry struct {
+	key     models.ShortURL
+	link    models.Link
+	err     error
+	expires time.Time
+}
+
+// Repository decorator with LRU and TTL cache for lookup of links, missed links are cached with negative ttl
+type Repository struct {
+	handlers.Repository
+	mu          sync.Mutex
+	capacity    int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	items       map[models.ShortURL]*list.Element
+	order       *list.List
+	// generation is changed by every invalidation, lookups started before it are not cached
+	generation uint64
+	hits       uint64
+	misses     uint64
+	evictions  uint64
+}
+
+// New cache in front of repository with max count of links and lifetime of entries
+func New(repo handlers.Repository, capacity int, ttl, negativeTTL time.Duration) *Repository {
+	return &Repository{
+		Repository:  repo,
+		capacity:    capacity,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		items:       make(map[models.ShortURL]*list.Element, capacity),
+		order:       list.New(),
+	}
+}
+
+// GetLinkDB get link from cache or repository
+func (c *Repository) GetLinkDB(ctx context.Context, key models.ShortURL) (models.Link, error) {
+	now := time.Now()
+	e, gen, ok := c.get(key, now)
+	if ok {
+		atomic.AddUint64(&c.hits, 1)
+		// Link is expired after it was cached
+		if e.err == nil && !e.link.ExpiresAt.IsZero() && !e.link.ExpiresAt.After(now) {
+			return models.Link{}, errs.ErrURLIsGone
+		}
+		return e.link, e.err
+	}
+	atomic.AddUint64(&c.misses, 1)
+
+	link, err := c.Repository.GetLinkDB(ctx, key)
+	switch {
+	case err == nil, errors.Is(err, errs.ErrURLIsGone):
+		c.put(entry{key: key, link: link, err: err, expires: now.Add(c.ttl)}, gen)
+	case errors.Is(err, errs.ErrURLNotFound), errors.Is(err, errs.ErrNotFoundURL):
+		c.put(entry{key: key, err: err, expires: now.Add(c.negativeTTL)}, gen)
+	}
+
+	return link, err
+}
+
+// SaveLinkDB save link and drop cached miss of its key
+func (c *Repository) SaveLinkDB(
+	ctx context.Context,
+	userID models.UniqUser,
+	origin models.Origin,
+	opts models.LinkOptions,
+) (models.ShortURL, error) {
+	short, err := c.Repository.SaveLinkDB(ctx, userID, origin, opts)
+	if short != "" {
+		c.Invalidate(models.LinkKey(opts.Domain, short))
+	}
+	return short, err
+}
+
+// SaveBatch save links and drop cached misses of their keys
+func (c *Repository) SaveBatch(ctx context.Context, userID models.UniqUser, urls []models.BatchReqURL) ([]models.BatchResURL, error) {
+	shorts, err := c.Repository.SaveBatch(ctx, userID, urls)
+	for _, v := range shorts {
+		c.Invalidate(models.LinkKey(v.Domain, models.ShortURL(v.Short)))
+	}
+	return shorts, err
+}
+
+// BunchUpdateAsDeleted apply batches and drop cached links of them, purge drops whole cache
+func (c *Repository) BunchUpdateAsDeleted(ctx context.Context, batches []models.BatchDelete) error {
+	err := c.Repository.BunchUpdateAsDeleted(ctx, batches)
+
+	for _, v := range batches {
+		if v.Op == models.BatchOpPurge {
+			c.Purge()
+			continue
+		}
+		for _, id := range v.URLs {
+			c.Invalidate(models.ShortURL(id))
+		}
+	}
+	return err
+}
+
+// SetBlocked change block of link and drop it from cache
+func (c *Repository) SetBlocked(ctx context.Context, key models.ShortURL, blocked bool, reason string) error {
+	err := c.Repository.SetBlocked(ctx, key, blocked, reason)
+	c.Invalidate(key)
+	return err
+}
+
+// UpdateLink edit link and drop it from cache
+func (c *Repository) UpdateLink(
+	ctx context.Context,
+	userID models.UniqUser,
+	key models.ShortURL,
+	upd models.LinkUpdate,
+) (models.Link, error) {
+	link, err := c.Repository.UpdateLink(ctx, userID, key, upd)
+	c.Invalidate(key)
+	return link, err
+}
+
+// DeleteDomain remove domain and drop links of domain from cache
+func (c *Repository) DeleteDomain(ctx context.Context, host string) error {
+	err := c.Repository.DeleteDomain(ctx, host)
+	c.InvalidateDomain(host)
+	return err
+}
+
+// InvalidateDomain drop links of domain from cache
+func (c *Repository) InvalidateDomain(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for key, el := range c.items {
+		if domain, _ := models.SplitLinkKey(key); domain == host {
+			c.remove(el)
+		}
+	}
+}
+
+// Invalidate drop link from cache
+func (c *Repository) Invalidate(key models.ShortURL) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Purge drop all links from cache
+func (c *Repository) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.items = make(map[models.ShortURL]*list.Element, c.capacity)
+	c.order.Init()
+}
+
+// CacheStats get counters of cache
+func (c *Repository) CacheStats() models.CacheStats {
+	c.mu.Lock()
+	size := c.order.Len()
+	c.mu.Unlock()
+
+	return models.CacheStats{
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Evictions: atomic.LoadUint64(&c.evictions),
+		Size:      size,
+		Capacity:  c.capacity,
+	}
+}
+
+// get not expired entry and mark it as recently used, generation of cache is returned for miss
+func (c *Repository) get(key models.ShortURL, now time.Time) (entry, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return entry{}, c.generation, false
+	}
+
+	e := el.Value.(entry)
+	if !now.Before(e.expires) {
+		c.remove(el)
+		return entry{}, c.generation, false
+	}
+
+	c.order.MoveToFront(el)
+	return e, c.generation, true
+}
+
+// put entry to cache if there was no invalidation after lookup, the least recently used entry
+// is evicted if cache is full
+func (c *Repository) put(e entry, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.generation {
+		return
+	}
+
+	if el, ok := c.items[e.key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+
+	if c.order.Len() >= c.capacity {
+		if oldest := c.order.Back(); oldest != nil {
+			c.remove(oldest)
+			atomic.AddUint64(&c.evictions, 1)
+		}
+	}
+
+	c.items[e.key] = c.order.PushFront(e)
+}
+
+// remove entry from list and map
+func (c *Repository) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(entry).key)
+}
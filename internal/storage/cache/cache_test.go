@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/handlers"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRepo хранилище ссылок в памяти, считает обращения
+type fakeRepo struct {
+	handlers.Repository
+	links map[models.ShortURL]models.Link
+	calls int
+}
+
+func (r *fakeRepo) GetLinkDB(_ context.Context, key models.ShortURL) (models.Link, error) {
+	r.calls++
+	link, ok := r.links[key]
+	if !ok {
+		return models.Link{}, errs.ErrURLNotFound
+	}
+	return link, nil
+}
+
+func (r *fakeRepo) DeleteDomain(context.Context, string) error {
+	return nil
+}
+
+func TestRepository_GetLinkDB(t *testing.T) {
+	repo := &fakeRepo{links: map[models.ShortURL]models.Link{
+		"abc":                 {Origin: "http://yandex.ru"},
+		"go.brand.com/abc":    {Origin: "http://brand.com"},
+		"go.other.com/abc":    {Origin: "http://other.com"},
+		"expired":             {Origin: "http://yandex.ru", ExpiresAt: time.Now().Add(50 * time.Millisecond)},
+		"go.brand.com/second": {Origin: "http://brand.com/second"},
+	}}
+	c := New(repo, 10, time.Minute, time.Minute)
+	ctx := context.Background()
+
+	// создаём массив тестов: ключ, ожидаемая ошибка и число обращений к хранилищу после запроса
+	tests := []struct {
+		name  string
+		key   models.ShortURL
+		err   error
+		calls int
+	}{
+		{
+			name:  "positive test #1",
+			key:   "go.brand.com/abc",
+			calls: 1,
+		},
+		{
+			name:  "positive test #2",
+			key:   "go.brand.com/abc",
+			calls: 1,
+		},
+		{
+			name:  "positive test #3",
+			key:   "go.other.com/abc",
+			calls: 2,
+		},
+		{
+			name:  "negative test #1",
+			key:   "missing",
+			err:   errs.ErrURLNotFound,
+			calls: 3,
+		},
+		{
+			name:  "negative test #2",
+			key:   "missing",
+			err:   errs.ErrURLNotFound,
+			calls: 3,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем тесты по порядку, они зависят от состояния кэша
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.GetLinkDB(ctx, tt.key)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.calls, repo.calls)
+		})
+	}
+
+	// ссылка, истекшая после кэширования, не отдается
+	_, err := c.GetLinkDB(ctx, "expired")
+	assert.NoError(t, err)
+	time.Sleep(60 * time.Millisecond)
+	_, err = c.GetLinkDB(ctx, "expired")
+	assert.ErrorIs(t, err, errs.ErrURLIsGone)
+
+	// удаление домена сбрасывает только его ссылки
+	c.GetLinkDB(ctx, "go.brand.com/second")
+	calls := repo.calls
+	assert.NoError(t, c.DeleteDomain(ctx, "go.brand.com"))
+	c.GetLinkDB(ctx, "go.other.com/abc")
+	assert.Equal(t, calls, repo.calls)
+	c.GetLinkDB(ctx, "go.brand.com/abc")
+	c.GetLinkDB(ctx, "go.brand.com/second")
+	assert.Equal(t, calls+2, repo.calls)
+}
@@ -0,0 +1,109 @@
+package dbstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveAPIKey save hashed api key
+func (s *PostgreSQLStorage) SaveAPIKey(ctx context.Context, key models.APIKey) error {
+	query := `
+	INSERT INTO public.api_keys (id, key_hash, user_id, scopes, daily_quota, created_at)
+	VALUES (@id, @key_hash, @user_id, @scopes, @daily_quota, @created_at);
+	`
+
+	args := pgx.NamedArgs{
+		"id":          key.ID,
+		"key_hash":    key.Hash,
+		"user_id":     string(key.UserID),
+		"scopes":      key.Scopes,
+		"daily_quota": key.DailyQuota,
+		"created_at":  key.CreatedAt,
+	}
+
+	if _, err := s.dbi.Exec(ctx, query, args); err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+	return nil
+}
+
+// APIKeyByHash get api key by hash of key
+func (s *PostgreSQLStorage) APIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	var key models.APIKey
+
+	query := "SELECT id, key_hash, user_id, scopes, daily_quota, created_at FROM public.api_keys WHERE key_hash=$1"
+	err := s.dbi.QueryRow(ctx, query, hash).Scan(&key.ID, &key.Hash, &key.UserID, &key.Scopes, &key.DailyQuota, &key.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return key, errs.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return key, fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
+	}
+
+	return key, nil
+}
+
+// APIKeys get all api keys
+func (s *PostgreSQLStorage) APIKeys(ctx context.Context) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+
+	query := "SELECT id, key_hash, user_id, scopes, daily_quota, created_at FROM public.api_keys ORDER BY created_at"
+	rows, err := s.dbi.Query(ctx, query)
+	if err != nil {
+		return keys, fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key models.APIKey
+		if err = rows.Scan(&key.ID, &key.Hash, &key.UserID, &key.Scopes, &key.DailyQuota, &key.CreatedAt); err != nil {
+			return keys, fmt.Errorf("%w: %v", errs.ErrDatabaseScanRows, err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// DeleteAPIKey revoke api key with its usage
+func (s *PostgreSQLStorage) DeleteAPIKey(ctx context.Context, id string) error {
+	tag, err := s.dbi.Exec(ctx, "DELETE FROM public.api_keys WHERE id=$1", id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errs.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// UseAPIKeyQuota add n created links to usage of key for today if quota allows it, negative n returns unused quota
+func (s *PostgreSQLStorage) UseAPIKeyQuota(ctx context.Context, id string, n int, quota int) error {
+	if n > quota {
+		return errs.ErrQuotaExceeded
+	}
+
+	query := `
+	INSERT INTO public.api_key_usage (key_id, day, used) VALUES ($1, $2, GREATEST($3, 0))
+	ON CONFLICT (key_id, day) DO UPDATE SET used = GREATEST(api_key_usage.used + $3, 0)
+	WHERE api_key_usage.used + $3 <= $4
+	RETURNING used;
+	`
+
+	var used int
+	err := s.dbi.QueryRow(ctx, query, id, time.Now().UTC().Format("2006-01-02"), n, quota).Scan(&used)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errs.ErrQuotaExceeded
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+
+	return nil
+}
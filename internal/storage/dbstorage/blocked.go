@@ -0,0 +1,63 @@
+package dbstorage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+)
+
+// SetBlocked flag or unflag link as blocked by destination checker, unflagged link is allowed by admin
+func (s *PostgreSQLStorage) SetBlocked(ctx context.Context, key models.ShortURL, blocked bool, reason string) error {
+	if !blocked {
+		reason = ""
+	}
+
+	domain, shortKey := models.SplitLinkKey(key)
+
+	query := `
+	UPDATE public.short_links
+	SET is_blocked=$3, block_reason=$4, is_allowed=NOT $3
+	WHERE domain=$1 AND short=$2;
+	`
+
+	tag, err := s.dbi.Exec(ctx, query, domain, string(shortKey), blocked, nullString(reason))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errs.ErrURLNotFound
+	}
+	return nil
+}
+
+// BlockedLinks get all links flagged by destination checker
+func (s *PostgreSQLStorage) BlockedLinks(ctx context.Context) ([]models.BlockedLink, error) {
+	links := []models.BlockedLink{}
+
+	query := `
+	SELECT domain, short, origin, user_id, coalesce(block_reason, '')
+	FROM public.short_links
+	WHERE is_blocked AND is_deleted=false
+	ORDER BY domain, short;
+	`
+
+	rows, err := s.dbi.Query(ctx, query)
+	if err != nil {
+		return links, fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link models.BlockedLink
+		var domain string
+		if err = rows.Scan(&domain, &link.Short, &link.Origin, &link.UserID, &link.Reason); err != nil {
+			return links, fmt.Errorf("%w: %v", errs.ErrDatabaseScanRows, err)
+		}
+		link.Short = models.LinkKey(domain, link.Short)
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
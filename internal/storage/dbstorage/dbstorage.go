@@ -5,8 +5,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/grishagavrin/link-shortener/internal/config"
 	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/jobs"
+	"github.com/grishagavrin/link-shortener/internal/logger"
+	"github.com/grishagavrin/link-shortener/internal/storage/migrations"
 	"github.com/grishagavrin/link-shortener/internal/storage/models"
 	"github.com/grishagavrin/link-shortener/internal/utils"
 	"github.com/jackc/pgerrcode"
@@ -16,63 +21,76 @@ import (
 	"go.uber.org/zap"
 )
 
+// shortUniqIndex name of unique index for short keys and aliases of domain
+const shortUniqIndex = "short_links_domain_short_uindex"
+
 // PostgreSQLStorage storage
 type PostgreSQLStorage struct {
-	dbi     *pgxpool.Pool
-	l       *zap.Logger
-	chBatch chan models.BatchDelete
+	dbi      *pgxpool.Pool
+	l        *zap.Logger
+	chBatch  chan models.BatchDelete
+	chClicks chan models.ClickEvent
+	domains  domainSet
 }
 
-// New initialize new table in postgreSQL storage
+// New apply schema migrations and initialize postgreSQL storage
 func New(dbi *pgxpool.Pool, l *zap.Logger, ch chan models.BatchDelete) (*PostgreSQLStorage, error) {
-	// Check if scheme exist
-	sql := `
-	CREATE TABLE IF NOT EXISTS public.short_links(
-		id serial,
-		user_id varchar(50),
-		origin  varchar(255) not null,
-		short   varchar(50)  not null,
-		correlation_id varchar(100),
-		is_deleted boolean default false
-	);
-
-	CREATE UNIQUE INDEX IF NOT EXISTS short_links_origin_uindex
-    on public.short_links(origin);
-	`
+	m, err := migrations.New(dbi, l)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
 
-	if _, err := dbi.Exec(context.Background(), sql); err != nil {
+	if err = m.Up(context.Background()); err != nil {
 		return nil, fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
 	}
 
 	return &PostgreSQLStorage{
-		dbi:     dbi,
-		l:       l,
-		chBatch: ch,
+		dbi:      dbi,
+		l:        l,
+		chBatch:  ch,
+		chClicks: make(chan models.ClickEvent, config.CLICKSBUFFER),
 	}, nil
 }
 
 // GetLinkDB get data from storage by short URL
-func (s *PostgreSQLStorage) GetLinkDB(ctx context.Context, shortKey models.ShortURL) (models.Origin, error) {
-	var origin models.Origin
+func (s *PostgreSQLStorage) GetLinkDB(ctx context.Context, key models.ShortURL) (models.Link, error) {
+	var link models.Link
 	var gone bool
+	var expiresAt *time.Time
+	var reason *string
+	var active bool
 
-	query := "SELECT origin, is_deleted FROM public.short_links WHERE short=$1"
-	err := s.dbi.QueryRow(ctx, query, string(shortKey)).Scan(&origin, &gone)
+	domain, shortKey := models.SplitLinkKey(key)
+
+	query := "SELECT origin, is_deleted, expires_at, redirect_code, is_blocked, block_reason, is_allowed, is_active FROM public.short_links WHERE domain=$1 AND short=$2"
+	err := s.dbi.QueryRow(ctx, query, domain, string(shortKey)).Scan(&link.Origin, &gone, &expiresAt, &link.RedirectCode, &link.Blocked, &reason, &link.Allowed, &active)
 
 	if gone {
-		return "", errs.ErrURLIsGone
+		return models.Link{}, errs.ErrURLIsGone
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return models.Link{}, errs.ErrURLIsGone
 	}
 
 	if err != nil {
-		return "", errs.ErrURLNotFound
+		return models.Link{}, errs.ErrURLNotFound
+	}
+
+	if expiresAt != nil {
+		link.ExpiresAt = *expiresAt
+	}
+	if reason != nil {
+		link.BlockReason = *reason
 	}
+	link.Inactive = !active
 
-	return origin, nil
+	return link, nil
 }
 
 // LinksByUser return all user links
 func (s *PostgreSQLStorage) LinksByUser(ctx context.Context, userID models.UniqUser) (models.ShortLinks, error) {
-	query := "SELECT origin, short FROM public.short_links WHERE user_id=$1"
+	query := "SELECT short, origin, domain FROM public.short_links WHERE user_id=$1"
 
 	origins := models.ShortLinks{}
 	rows, err := s.dbi.Query(ctx, query, string(userID))
@@ -83,52 +101,64 @@ func (s *PostgreSQLStorage) LinksByUser(ctx context.Context, userID models.UniqU
 	for rows.Next() {
 		var origin models.Origin
 		var short models.ShortURL
+		var domain string
 
-		err = rows.Scan(&short, &origin)
+		err = rows.Scan(&short, &origin, &domain)
 		if err != nil {
 			return origins, err
 		}
-		origins[short] = origin
+		origins[models.LinkKey(domain, short)] = origin
 	}
 
 	return origins, nil
 }
 
 // SaveLinkDB save url in storage of short links
-func (s *PostgreSQLStorage) SaveLinkDB(ctx context.Context, userID models.UniqUser, url models.Origin) (models.ShortURL, error) {
-
-	shortKey, err := utils.RandStringBytes()
-	if err != nil {
-		return "", err
+func (s *PostgreSQLStorage) SaveLinkDB(ctx context.Context, userID models.UniqUser, url models.Origin, opts models.LinkOptions) (models.ShortURL, error) {
+	shortKey := opts.Alias
+	if shortKey == "" {
+		key, err := utils.RandStringBytes()
+		if err != nil {
+			return "", err
+		}
+		shortKey = key
 	}
 
 	queryInsert := `
-	INSERT INTO public.short_links (user_id, origin, short) 
-	VALUES (@user_id, @origin, @short);
+	INSERT INTO public.short_links (user_id, domain, origin, short, expires_at, redirect_code, is_blocked, block_reason) 
+	VALUES (@user_id, @domain, @origin, @short, @expires_at, @redirect_code, @is_blocked, @block_reason)
+	ON CONFLICT (domain, origin) DO NOTHING
+	RETURNING short;
 	`
 
 	queryGet := `
-	SELECT short FROM public.short_links where origin=$1
+	SELECT short FROM public.short_links where domain=$1 AND origin=$2
 	`
 
 	args := pgx.NamedArgs{
-		"user_id": userID,
-		"origin":  url,
-		"short":   shortKey,
+		"user_id":       userID,
+		"domain":        opts.Domain,
+		"origin":        url,
+		"short":         shortKey,
+		"expires_at":    nullTime(opts.ExpiresAt),
+		"redirect_code": redirectCode(opts.RedirectCode),
+		"is_blocked":    opts.Blocked,
+		"block_reason":  nullString(opts.BlockReason),
 	}
 
 	pgErr := &pgconn.PgError{}
 
-	if _, err := s.dbi.Exec(ctx, queryInsert, args); err != nil {
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == pgerrcode.UniqueViolation {
-				var short models.ShortURL
-				_ = s.dbi.QueryRow(ctx, queryGet, string(url)).Scan(&short)
-
-				return short, errs.ErrAlreadyHasShort
-			}
+	err := s.dbi.QueryRow(ctx, queryInsert, args).Scan(&shortKey)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// Origin already shortened on domain keeps its link, alias is not applied to it
+		var short models.ShortURL
+		if err = s.dbi.QueryRow(ctx, queryGet, opts.Domain, string(url)).Scan(&short); err != nil {
+			return "", fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
 		}
-		return shortKey, nil
+		return short, errs.ErrAlreadyHasShort
+	case errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && opts.Alias != "" && pgErr.ConstraintName == shortUniqIndex:
+		return "", errs.ErrAliasTaken
 	}
 
 	return shortKey, nil
@@ -136,16 +166,39 @@ func (s *PostgreSQLStorage) SaveLinkDB(ctx context.Context, userID models.UniqUs
 
 // SaveBatch save multiply URL
 func (s *PostgreSQLStorage) SaveBatch(ctx context.Context, userID models.UniqUser, urls []models.BatchReqURL) ([]models.BatchResURL, error) {
-	type temp struct{ CorrID, Origin, Short string }
+	type temp struct {
+		CorrID, Domain, Origin, Short, Alias string
+		ExpiresAt                            time.Time
+		RedirectCode                         int
+		Blocked                              bool
+		BlockReason                          string
+	}
 
+	// Duplicate custom aliases in one batch are rejected before insert
+	aliases := make(map[models.ShortURL]struct{})
 	var buffer []temp
 	for _, v := range urls {
-		shortKey, _ := utils.RandStringBytes()
+		shortKey := models.ShortURL(v.Alias)
+		if shortKey == "" {
+			shortKey, _ = utils.RandStringBytes()
+		} else {
+			key := models.LinkKey(v.Domain, shortKey)
+			if _, ok := aliases[key]; ok {
+				return nil, errs.ErrAliasTaken
+			}
+			aliases[key] = struct{}{}
+		}
 
 		var t = temp{
-			CorrID: v.CorrID,
-			Origin: v.Origin,
-			Short:  string(shortKey),
+			CorrID:       v.CorrID,
+			Domain:       v.Domain,
+			Origin:       v.Origin,
+			Short:        string(shortKey),
+			Alias:        v.Alias,
+			ExpiresAt:    v.ExpiresAt,
+			RedirectCode: v.Redirect,
+			Blocked:      v.Blocked,
+			BlockReason:  v.BlockReason,
 		}
 		buffer = append(buffer, t)
 	}
@@ -153,80 +206,389 @@ func (s *PostgreSQLStorage) SaveBatch(ctx context.Context, userID models.UniqUse
 	var shorts []models.BatchResURL
 
 	query := `
-		INSERT INTO public.short_links (user_id, origin, short) 
-		VALUES (@user_id, @origin, @short)
-		ON CONFLICT (origin) DO NOTHING;
+		INSERT INTO public.short_links (user_id, domain, origin, short, expires_at, redirect_code, is_blocked, block_reason) 
+		VALUES (@user_id, @domain, @origin, @short, @expires_at, @redirect_code, @is_blocked, @block_reason)
+		ON CONFLICT (domain, origin) DO NOTHING
+		RETURNING short;
+		`
+
+	queryGet := `
+		SELECT short FROM public.short_links WHERE domain=$1 AND origin=$2;
 		`
 
 	// Start transaction
 	tx, err := s.dbi.Begin(ctx)
-	defer tx.Rollback(ctx)
 	if err != nil {
-		return shorts, err
+		return nil, fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
 	}
+	defer tx.Rollback(ctx)
 
 	for _, v := range buffer {
 		// Add record to transaction
 		args := pgx.NamedArgs{
 			"user_id":        userID,
+			"domain":         v.Domain,
 			"origin":         v.Origin,
 			"short":          v.Short,
 			"correlation_id": v.CorrID,
+			"expires_at":     nullTime(v.ExpiresAt),
+			"redirect_code":  redirectCode(v.RedirectCode),
+			"is_blocked":     v.Blocked,
+			"block_reason":   nullString(v.BlockReason),
 		}
 
-		if _, err = tx.Exec(ctx, query, args); err == nil {
-			shorts = append(shorts, models.BatchResURL{
-				Short:  v.Short,
-				CorrID: v.CorrID,
-			})
-		} else {
-			s.l.Info("Save bunch error", zap.Error(err))
+		// Alias taken by concurrent request is reported by unique index of short
+		short := v.Short
+		pgErr := &pgconn.PgError{}
+		err = tx.QueryRow(ctx, query, args).Scan(&short)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			// Origin already shortened on domain keeps its link, alias is not applied to it
+			if err = tx.QueryRow(ctx, queryGet, v.Domain, v.Origin).Scan(&short); err != nil {
+				return nil, fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
+			}
+		case errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && v.Alias != "" && pgErr.ConstraintName == shortUniqIndex:
+			return nil, errs.ErrAliasTaken
+		case err != nil:
+			logger.FromContext(ctx).Info("Save bunch error", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
 		}
+
+		shorts = append(shorts, models.BatchResURL{
+			Short:  short,
+			CorrID: v.CorrID,
+			Domain: v.Domain,
+		})
 	}
 
-	err = tx.Commit(ctx)
-	if err != nil {
-		return nil, err
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
 	}
 
 	return shorts, nil
 }
 
-// BunchUpdateAsDeleted delete mass URL by fanIN pattern
-func (s *PostgreSQLStorage) BunchUpdateAsDeleted(chBatch chan models.BatchDelete) {
-	for v := range chBatch {
-		if len(v.URLs) == 0 {
-			s.l.Info(errs.ErrCorrelation.Error())
+// Queries of batch delete pipeline
+const (
+	// Owner is read from snapshot before update
+	userLinkQuery = `
+	WITH updated AS (%s)
+	SELECT
+		(SELECT user_id FROM public.short_links WHERE domain=$2 AND short=$3),
+		EXISTS (SELECT 1 FROM updated);
+	`
+	restoreQuery = `
+	UPDATE public.short_links
+	SET is_deleted=false, deleted_at=NULL
+	WHERE user_id=$1 AND domain=$2 AND short=$3 AND is_deleted
+		AND (expires_at IS NULL OR expires_at > now())
+	RETURNING short
+	`
+	deleteQuery = `
+	UPDATE public.short_links
+	SET is_deleted=true, deleted_at=now()
+	WHERE user_id=$1 AND domain=$2 AND short=$3 AND is_deleted=false
+	RETURNING short
+	`
+	// Links are purged with their clicks and revisions
+	purgeQuery = `
+	WITH purged AS (
+		DELETE FROM public.short_links
+		WHERE is_deleted AND deleted_at IS NOT NULL AND deleted_at < $1
+		RETURNING domain, short
+	), revisions AS (
+		DELETE FROM public.link_revisions r USING purged p
+		WHERE r.domain=p.domain AND r.short=p.short
+	), clicks AS (
+		DELETE FROM public.link_clicks c USING purged p
+		WHERE c.domain=p.domain AND c.short=p.short
+	)
+	SELECT count(*) FROM purged;
+	`
+)
+
+// BunchUpdateAsDeleted delete, restore or purge mass URL of coalesced batches,
+// every batch is applied in own transaction, so failed batch does not roll back others.
+// Error of first failed batch is returned
+func (s *PostgreSQLStorage) BunchUpdateAsDeleted(ctx context.Context, batches []models.BatchDelete) error {
+	var failed error
+	for _, v := range batches {
+		jobs.Instance().Start(v.JobID)
+		outcomes, err := s.applyBatch(ctx, v)
+		jobs.Instance().Finish(v.JobID, outcomes, err)
+		if err != nil && failed == nil {
+			failed = err
 		}
+	}
+	return failed
+}
 
-		query := `
-		UPDATE public.short_links
-		SET is_deleted=true
-		WHERE user_id=$1 AND short=$2;
-		`
-		batch := &pgx.Batch{}
+// applyBatch apply one batch with one pgx batch in transaction, nothing is changed if any query fails
+func (s *PostgreSQLStorage) applyBatch(ctx context.Context, v models.BatchDelete) ([]models.JobResult, error) {
+	tx, err := s.dbi.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	switch v.Op {
+	case models.BatchOpPurge:
+		batch.Queue(purgeQuery, v.Before)
+	case models.BatchOpRestore:
+		s.queueUserLinks(ctx, batch, v, fmt.Sprintf(userLinkQuery, restoreQuery))
+	default:
+		s.queueUserLinks(ctx, batch, v, fmt.Sprintf(userLinkQuery, deleteQuery))
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	var outcomes []models.JobResult
+	switch v.Op {
+	case models.BatchOpPurge:
+		err = s.readPurged(ctx, results)
+	case models.BatchOpRestore:
+		outcomes, err = s.readUserLinks(ctx, results, v, models.JobURLRestored)
+	default:
+		outcomes, err = s.readUserLinks(ctx, results, v, models.JobURLDeleted)
+	}
+	if closeErr := results.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("%w: %v", errs.ErrDatabaseExec, closeErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+	return outcomes, nil
+}
+
+// queueUserLinks queue query for every link of user from batch
+func (s *PostgreSQLStorage) queueUserLinks(ctx context.Context, batch *pgx.Batch, v models.BatchDelete, query string) {
+	if len(v.URLs) == 0 {
+		logger.FromContext(ctx).Info(errs.ErrCorrelation.Error())
+	}
 
-		for _, id := range v.URLs {
-			batch.Queue(query, v.UserID, id)
+	for _, id := range v.URLs {
+		domain, shortKey := models.SplitLinkKey(models.ShortURL(id))
+		batch.Queue(query, v.UserID, domain, string(shortKey))
+	}
+}
+
+// readUserLinks read outcomes of user links queued by queueUserLinks
+func (s *PostgreSQLStorage) readUserLinks(
+	ctx context.Context,
+	results pgx.BatchResults,
+	v models.BatchDelete,
+	outcome string,
+) ([]models.JobResult, error) {
+	var failed error
+	outcomes := make([]models.JobResult, 0, len(v.URLs))
+	for _, id := range v.URLs {
+		var owner *string
+		var updated bool
+		// Every result is read to keep order of batch results
+		if err := results.QueryRow().Scan(&owner, &updated); err != nil {
+			logger.FromContext(ctx).Info("unable to update row", zap.String("op", v.Op), zap.String("URL id", id), zap.Error(err))
+			failed = fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+			continue
 		}
 
-		results := s.dbi.SendBatch(context.Background(), batch)
+		res := models.JobResult{URL: id}
+		switch {
+		case owner == nil:
+			res.Outcome = models.JobURLNotFound
+		case *owner != v.UserID:
+			res.Outcome = models.JobURLNotOwned
+		case updated || v.Op == models.BatchOpDelete:
+			// Link deleted before is reported as deleted
+			res.Outcome = outcome
+		default:
+			res.Outcome = models.JobURLUnchanged
+		}
+		outcomes = append(outcomes, res)
+	}
+
+	return outcomes, failed
+}
 
-		for _, id := range v.URLs {
-			_, err := results.Exec()
+// readPurged read count of links purged by purge query
+func (s *PostgreSQLStorage) readPurged(ctx context.Context, results pgx.BatchResults) error {
+	var count int
+	if err := results.QueryRow().Scan(&count); err != nil {
+		logger.FromContext(ctx).Info("purge deleted links error", zap.Error(err))
+		return fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+
+	logger.FromContext(ctx).Info("purge deleted links", zap.Int("count", count))
+	return nil
+}
+
+// SweepExpired mark expired links as deleted with interval until context done
+func (s *PostgreSQLStorage) SweepExpired(ctx context.Context, interval time.Duration) {
+	query := `
+	UPDATE public.short_links
+	SET is_deleted=true, deleted_at=now()
+	WHERE is_deleted=false AND expires_at <= now();
+	`
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tag, err := s.dbi.Exec(ctx, query)
 			if err != nil {
-				var pgErr *pgconn.PgError
-				if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-					s.l.Info("update error on batch delete", zap.String("URL id", id))
-					continue
-				}
-				s.l.Info("unable to delete row ", zap.Error(err))
+				s.l.Info("sweep expired links error", zap.Error(err))
+				continue
+			}
+			if tag.RowsAffected() > 0 {
+				s.l.Info("expired links swept", zap.Int64("count", tag.RowsAffected()))
+			}
+		}
+	}
+}
+
+// SaveClick put click event to buffer, event is dropped if buffer is full
+func (s *PostgreSQLStorage) SaveClick(ev models.ClickEvent) {
+	select {
+	case s.chClicks <- ev:
+	default:
+		s.l.Info("clicks buffer is full", zap.String("short", string(ev.Short)))
+	}
+}
+
+// BunchSaveClicks write buffered click events by batches until context done
+func (s *PostgreSQLStorage) BunchSaveClicks(ctx context.Context) {
+	ticker := time.NewTicker(config.CLICKSFLUSH)
+	defer ticker.Stop()
+
+	events := make([]models.ClickEvent, 0, config.CLICKSBATCH)
+	for {
+		select {
+		case ev := <-s.chClicks:
+			events = append(events, ev)
+			if len(events) < config.CLICKSBATCH {
+				continue
 			}
+		case <-ticker.C:
+		case <-ctx.Done():
+			// Write all events left in buffer
+			for len(s.chClicks) > 0 {
+				events = append(events, <-s.chClicks)
+			}
+			s.flushClicks(events)
+			return
+		}
+
+		s.flushClicks(events)
+		events = events[:0]
+	}
+}
+
+// flushClicks insert click events by one batch
+func (s *PostgreSQLStorage) flushClicks(events []models.ClickEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	query := `
+	INSERT INTO public.link_clicks (domain, short, clicked_at, referrer, user_agent, ip_bucket)
+	VALUES ($1, $2, $3, $4, $5, $6);
+	`
+	batch := &pgx.Batch{}
+	for _, ev := range events {
+		domain, shortKey := models.SplitLinkKey(ev.Short)
+		batch.Queue(query, domain, string(shortKey), ev.ClickedAt, ev.Referrer, ev.UserAgent, ev.IPBucket)
+	}
+
+	results := s.dbi.SendBatch(context.Background(), batch)
+	defer results.Close()
+
+	for range events {
+		if _, err := results.Exec(); err != nil {
+			s.l.Info("unable to save click", zap.Error(err))
 		}
-		results.Close()
 	}
 }
 
+// ClickStats get total and time series of clicks for user link
+func (s *PostgreSQLStorage) ClickStats(ctx context.Context, userID models.UniqUser, key models.ShortURL) (models.ClickStats, error) {
+	stats := models.ClickStats{
+		Short:  string(key),
+		Hourly: []models.ClickPoint{},
+		Daily:  []models.ClickPoint{},
+	}
+
+	domain, shortKey := models.SplitLinkKey(key)
+
+	queryOwner := `
+	SELECT EXISTS(SELECT 1 FROM public.short_links WHERE domain=$1 AND short=$2 AND user_id=$3);
+	`
+	var owner bool
+	if err := s.dbi.QueryRow(ctx, queryOwner, domain, string(shortKey), string(userID)).Scan(&owner); err != nil {
+		return stats, fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
+	}
+	if !owner {
+		return stats, errs.ErrNotFoundURL
+	}
+
+	queryTotal := "SELECT count(*) FROM public.link_clicks WHERE domain=$1 AND short=$2"
+	if err := s.dbi.QueryRow(ctx, queryTotal, domain, string(shortKey)).Scan(&stats.Total); err != nil {
+		return stats, fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
+	}
+
+	querySeries := `
+	SELECT date_trunc($3, clicked_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS bucket, count(*)
+	FROM public.link_clicks
+	WHERE domain=$1 AND short=$2 AND clicked_at >= $4
+	GROUP BY bucket
+	ORDER BY bucket;
+	`
+
+	now := time.Now().UTC()
+	hourly, err := s.clickSeries(ctx, querySeries, key, "hour", now.Add(-config.STATSHOURS*time.Hour))
+	if err != nil {
+		return stats, err
+	}
+	stats.Hourly = hourly
+
+	daily, err := s.clickSeries(ctx, querySeries, key, "day", now.AddDate(0, 0, -config.STATSDAYS))
+	if err != nil {
+		return stats, err
+	}
+	stats.Daily = daily
+
+	return stats, nil
+}
+
+// clickSeries get clicks grouped by time unit since time, buckets are in UTC like in RAM storage
+func (s *PostgreSQLStorage) clickSeries(ctx context.Context, query string, shortKey models.ShortURL, unit string, since time.Time) ([]models.ClickPoint, error) {
+	points := []models.ClickPoint{}
+
+	domain, short := models.SplitLinkKey(shortKey)
+
+	rows, err := s.dbi.Query(ctx, query, domain, string(short), unit, since)
+	if err != nil {
+		return points, fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.ClickPoint
+		if err = rows.Scan(&p.Time, &p.Clicks); err != nil {
+			return points, fmt.Errorf("%w: %v", errs.ErrDatabaseScanRows, err)
+		}
+		points = append(points, p)
+	}
+
+	return points, rows.Err()
+}
+
 // GetStats get statistics quantity urls and users
 func (s *PostgreSQLStorage) GetStats(ctx context.Context, userID models.UniqUser) (models.GetStatsResURL, error) {
 	stat := models.GetStatsResURL{}
@@ -239,3 +601,35 @@ func (s *PostgreSQLStorage) GetStats(ctx context.Context, userID models.UniqUser
 
 	return stat, nil
 }
+
+// nullTime convert zero time to NULL value for database
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// nullString convert empty string to NULL value for database
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// redirectCode set default redirect code if it is not defined
+func redirectCode(code int) int {
+	if code == 0 {
+		return config.DEFAULTREDIRECT
+	}
+	return code
+}
+
+// Health ping database
+func (s *PostgreSQLStorage) Health(ctx context.Context) error {
+	if err := s.dbi.Ping(ctx); err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrStorageUnhealthy, err)
+	}
+	return nil
+}
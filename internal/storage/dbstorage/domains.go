@@ -0,0 +1,128 @@
+package dbstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// domainSet cached registered domains, it is loaded again when it is older than config.DOMAINSREFRESH
+// or changed by this instance
+type domainSet struct {
+	mu       sync.RWMutex
+	hosts    map[string]models.Domain
+	loadedAt time.Time
+}
+
+// lookup get domain from cached set, false is returned if set is stale
+func (d *domainSet) lookup(host string, now time.Time) (models.Domain, bool, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.hosts == nil || now.Sub(d.loadedAt) >= config.DOMAINSREFRESH {
+		return models.Domain{}, false, false
+	}
+	domain, ok := d.hosts[host]
+	return domain, ok, true
+}
+
+// store replace cached set
+func (d *domainSet) store(domains []models.Domain, now time.Time) {
+	hosts := make(map[string]models.Domain, len(domains))
+	for _, v := range domains {
+		hosts[v.Host] = v
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hosts, d.loadedAt = hosts, now
+}
+
+// invalidate drop cached set
+func (d *domainSet) invalidate() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hosts = nil
+}
+
+// SaveDomain register custom domain of tenant
+func (s *PostgreSQLStorage) SaveDomain(ctx context.Context, domain models.Domain) error {
+	defer s.domains.invalidate()
+
+	query := `
+	INSERT INTO public.domains (host, tenant, created_at)
+	VALUES ($1, $2, $3);
+	`
+
+	_, err := s.dbi.Exec(ctx, query, domain.Host, domain.Tenant, domain.CreatedAt)
+	pgErr := &pgconn.PgError{}
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		return errs.ErrDomainExists
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+	return nil
+}
+
+// DomainByHost get registered domain by host from cached set of domains
+func (s *PostgreSQLStorage) DomainByHost(ctx context.Context, host string) (models.Domain, error) {
+	now := time.Now()
+	domain, ok, fresh := s.domains.lookup(host, now)
+	if !fresh {
+		domains, err := s.Domains(ctx)
+		if err != nil {
+			return models.Domain{}, err
+		}
+		s.domains.store(domains, now)
+		domain, ok, _ = s.domains.lookup(host, now)
+	}
+
+	if !ok {
+		return models.Domain{}, errs.ErrDomainNotFound
+	}
+	return domain, nil
+}
+
+// Domains get all registered domains
+func (s *PostgreSQLStorage) Domains(ctx context.Context) ([]models.Domain, error) {
+	domains := []models.Domain{}
+
+	rows, err := s.dbi.Query(ctx, "SELECT host, tenant, created_at FROM public.domains ORDER BY host")
+	if err != nil {
+		return domains, fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var domain models.Domain
+		if err = rows.Scan(&domain.Host, &domain.Tenant, &domain.CreatedAt); err != nil {
+			return domains, fmt.Errorf("%w: %v", errs.ErrDatabaseScanRows, err)
+		}
+		domains = append(domains, domain)
+	}
+
+	return domains, rows.Err()
+}
+
+// DeleteDomain remove domain, links of domain are kept
+func (s *PostgreSQLStorage) DeleteDomain(ctx context.Context, host string) error {
+	defer s.domains.invalidate()
+
+	tag, err := s.dbi.Exec(ctx, "DELETE FROM public.domains WHERE host=$1", host)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errs.ErrDomainNotFound
+	}
+	return nil
+}
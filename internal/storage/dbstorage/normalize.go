@@ -0,0 +1,64 @@
+package dbstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/utils"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// NormalizeOrigins convert origins saved before normalization of urls to canonical form, count
+// of changed links is returned. Origins which are not valid now or become equal to origin
+// of other link of domain are kept.
+func NormalizeOrigins(ctx context.Context, dbi *pgxpool.Pool, l *zap.Logger) (int, error) {
+	type link struct {
+		domain, short, origin string
+	}
+
+	rows, err := dbi.Query(ctx, "SELECT domain, short, origin FROM public.short_links")
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
+	}
+
+	var changed []link
+	for rows.Next() {
+		var v link
+		if err = rows.Scan(&v.domain, &v.short, &v.origin); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("%w: %v", errs.ErrDatabaseScanRows, err)
+		}
+
+		origin, err := utils.CanonicalURL(v.origin)
+		if err != nil || origin == v.origin {
+			continue
+		}
+		v.origin = origin
+		changed = append(changed, v)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
+	}
+
+	count := 0
+	for _, v := range changed {
+		_, err = dbi.Exec(ctx, "UPDATE public.short_links SET origin=$3 WHERE domain=$1 AND short=$2", v.domain, v.short, v.origin)
+		pgErr := &pgconn.PgError{}
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			l.Info("skip origin of other link", zap.String("short", v.short), zap.String("origin", v.origin))
+			continue
+		}
+		if err != nil {
+			return count, fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
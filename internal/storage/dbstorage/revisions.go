@@ -0,0 +1,166 @@
+package dbstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// UpdateLink change link of owner, previous state is kept in revisions
+func (s *PostgreSQLStorage) UpdateLink(ctx context.Context, userID models.UniqUser, key models.ShortURL, upd models.LinkUpdate) (models.Link, error) {
+	var link models.Link
+	var gone, active, allowed bool
+	var expiresAt *time.Time
+	var reason *string
+
+	domain, shortKey := models.SplitLinkKey(key)
+
+	tx, err := s.dbi.Begin(ctx)
+	if err != nil {
+		return link, fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Row is locked until new revision is saved
+	querySelect := `
+	SELECT origin, is_deleted, expires_at, redirect_code, is_blocked, block_reason, is_allowed, is_active
+	FROM public.short_links
+	WHERE domain=$1 AND short=$2 AND user_id=$3
+	FOR UPDATE;
+	`
+	err = tx.QueryRow(ctx, querySelect, domain, string(shortKey), string(userID)).
+		Scan(&link.Origin, &gone, &expiresAt, &link.RedirectCode, &link.Blocked, &reason, &allowed, &active)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Link{}, errs.ErrNotFoundURL
+	}
+	if err != nil {
+		return models.Link{}, fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
+	}
+	if gone || (expiresAt != nil && !expiresAt.After(time.Now())) {
+		return models.Link{}, errs.ErrURLIsGone
+	}
+
+	queryRevision := `
+	INSERT INTO public.link_revisions (domain, short, revision, origin, redirect_code, expires_at, is_active)
+	SELECT $1, $2, coalesce(max(revision), 0) + 1, $3, $4, $5, $6
+	FROM public.link_revisions
+	WHERE domain=$1 AND short=$2;
+	`
+	_, err = tx.Exec(ctx, queryRevision, domain, string(shortKey), string(link.Origin), link.RedirectCode, expiresAt, active)
+	if err != nil {
+		return models.Link{}, fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+
+	// Block is cleared only by admin, unblock of admin is for previous destination
+	if upd.Origin != nil && *upd.Origin != link.Origin {
+		link.Origin = *upd.Origin
+		allowed = false
+	}
+	if upd.Origin != nil && upd.Blocked && !link.Blocked {
+		link.Blocked = true
+		reason = nullString(upd.BlockReason)
+	}
+	if upd.Active != nil {
+		active = *upd.Active
+	}
+	if upd.RedirectCode != nil {
+		link.RedirectCode = redirectCode(*upd.RedirectCode)
+	}
+	if upd.ExpiresAt != nil {
+		expiresAt = nullTime(*upd.ExpiresAt)
+	}
+
+	queryUpdate := `
+	UPDATE public.short_links
+	SET origin=@origin, redirect_code=@redirect_code, expires_at=@expires_at,
+		is_active=@is_active, is_blocked=@is_blocked, block_reason=@block_reason, is_allowed=@is_allowed
+	WHERE domain=@domain AND short=@short;
+	`
+	args := pgx.NamedArgs{
+		"domain":        domain,
+		"short":         string(shortKey),
+		"origin":        string(link.Origin),
+		"redirect_code": link.RedirectCode,
+		"expires_at":    expiresAt,
+		"is_active":     active,
+		"is_blocked":    link.Blocked,
+		"block_reason":  reason,
+		"is_allowed":    allowed,
+	}
+
+	_, err = tx.Exec(ctx, queryUpdate, args)
+	pgErr := &pgconn.PgError{}
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		return models.Link{}, errs.ErrAlreadyHasShort
+	}
+	if err != nil {
+		return models.Link{}, fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return models.Link{}, fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+
+	if expiresAt != nil {
+		link.ExpiresAt = *expiresAt
+	}
+	if reason != nil {
+		link.BlockReason = *reason
+	}
+	link.Allowed = allowed
+	link.Inactive = !active
+
+	return link, nil
+}
+
+// LinkRevisions get previous states of user link, the oldest first
+func (s *PostgreSQLStorage) LinkRevisions(ctx context.Context, userID models.UniqUser, key models.ShortURL) ([]models.LinkRevision, error) {
+	revisions := []models.LinkRevision{}
+
+	domain, shortKey := models.SplitLinkKey(key)
+
+	queryOwner := `
+	SELECT EXISTS(SELECT 1 FROM public.short_links WHERE domain=$1 AND short=$2 AND user_id=$3);
+	`
+	var owner bool
+	if err := s.dbi.QueryRow(ctx, queryOwner, domain, string(shortKey), string(userID)).Scan(&owner); err != nil {
+		return revisions, fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
+	}
+	if !owner {
+		return revisions, errs.ErrNotFoundURL
+	}
+
+	query := `
+	SELECT revision, origin, redirect_code, expires_at, is_active, changed_at
+	FROM public.link_revisions
+	WHERE domain=$1 AND short=$2
+	ORDER BY revision;
+	`
+
+	rows, err := s.dbi.Query(ctx, query, domain, string(shortKey))
+	if err != nil {
+		return revisions, fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rev models.LinkRevision
+		var expiresAt *time.Time
+		if err = rows.Scan(&rev.Revision, &rev.Origin, &rev.RedirectCode, &expiresAt, &rev.Active, &rev.ChangedAt); err != nil {
+			return revisions, fmt.Errorf("%w: %v", errs.ErrDatabaseScanRows, err)
+		}
+		if expiresAt != nil {
+			rev.ExpiresAt = *expiresAt
+		}
+		revisions = append(revisions, rev)
+	}
+
+	return revisions, rows.Err()
+}
@@ -0,0 +1,179 @@
+package filestorage
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/filewrapper"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"go.uber.org/zap"
+)
+
+// Operations of api keys file
+const (
+	keyOpCreate = "create"
+	keyOpDelete = "delete"
+	keyOpUse    = "use"
+)
+
+// keyRecord line of api keys file
+type keyRecord struct {
+	Op   string         `json:"op"`
+	ID   string         `json:"id"`
+	Key  *models.APIKey `json:"key,omitempty"`
+	Hash string         `json:"hash,omitempty"`
+	Day  string         `json:"day,omitempty"`
+	Used int            `json:"used,omitempty"`
+}
+
+// keysPath return path of api keys file
+func keysPath(fs string) string {
+	return fs + ".keys"
+}
+
+// quotaDay return current day of quota in UTC
+func quotaDay() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+// loadKeys replay api keys file to memory
+func (r *RAMStorage) loadKeys(fs string) error {
+	today := quotaDay()
+
+	return filewrapper.ReadJSON(keysPath(fs), func(line []byte) error {
+		var rec keyRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			r.l.Info("skip broken api key record", zap.Error(err))
+			return nil
+		}
+
+		switch rec.Op {
+		case keyOpCreate:
+			if rec.Key == nil {
+				return nil
+			}
+			rec.Key.Hash = rec.Hash
+			r.Keys[rec.ID] = *rec.Key
+		case keyOpDelete:
+			delete(r.Keys, rec.ID)
+			delete(r.keysUsed, rec.ID)
+		case keyOpUse:
+			// Usage of previous days is not needed for quota
+			if rec.Day == today {
+				r.addUsage(rec.ID, rec.Used)
+			}
+		}
+		return nil
+	})
+}
+
+// appendKeys write records to api keys file
+func (r *RAMStorage) appendKeys(recs ...keyRecord) error {
+	// Config instance
+	cfg, _ := config.Instance()
+	// Config value
+	fs, err := cfg.GetCfgValue(config.FileStoragePath)
+	if err != nil || fs == "" {
+		return nil
+	}
+
+	items := make([]interface{}, 0, len(recs))
+	for _, rec := range recs {
+		items = append(items, rec)
+	}
+	return filewrapper.AppendJSON(keysPath(fs), items...)
+}
+
+// SaveAPIKey save hashed api key
+func (r *RAMStorage) SaveAPIKey(_ context.Context, key models.APIKey) error {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	if err := r.appendKeys(keyRecord{Op: keyOpCreate, ID: key.ID, Key: &key, Hash: key.Hash}); err != nil {
+		return err
+	}
+	r.Keys[key.ID] = key
+	return nil
+}
+
+// APIKeyByHash get api key by hash of key
+func (r *RAMStorage) APIKeyByHash(_ context.Context, hash string) (models.APIKey, error) {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	for _, key := range r.Keys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+	return models.APIKey{}, errs.ErrAPIKeyNotFound
+}
+
+// APIKeys get all api keys
+func (r *RAMStorage) APIKeys(_ context.Context) ([]models.APIKey, error) {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	keys := make([]models.APIKey, 0, len(r.Keys))
+	for _, key := range r.Keys {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+// DeleteAPIKey revoke api key with its usage
+func (r *RAMStorage) DeleteAPIKey(_ context.Context, id string) error {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	if _, ok := r.Keys[id]; !ok {
+		return errs.ErrAPIKeyNotFound
+	}
+
+	if err := r.appendKeys(keyRecord{Op: keyOpDelete, ID: id}); err != nil {
+		return err
+	}
+	delete(r.Keys, id)
+	delete(r.keysUsed, id)
+	return nil
+}
+
+// UseAPIKeyQuota add n created links to usage of key for today if quota allows it, negative n returns unused quota
+func (r *RAMStorage) UseAPIKeyQuota(_ context.Context, id string, n int, quota int) error {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	// Usage is reset on the first request of new day
+	today := quotaDay()
+	if r.keysDay != today {
+		r.keysDay = today
+		r.keysUsed = make(map[string]int)
+	}
+
+	if r.keysUsed[id]+n > quota {
+		return errs.ErrQuotaExceeded
+	}
+
+	if err := r.appendKeys(keyRecord{Op: keyOpUse, ID: id, Day: today, Used: n}); err != nil {
+		return err
+	}
+	r.addUsage(id, n)
+	return nil
+}
+
+// addUsage change usage of key for today, returned quota can`t make it negative
+func (r *RAMStorage) addUsage(id string, n int) {
+	r.keysUsed[id] += n
+	if r.keysUsed[id] < 0 {
+		r.keysUsed[id] = 0
+	}
+}
@@ -0,0 +1,71 @@
+package filestorage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/filewrapper"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+)
+
+// SetBlocked flag or unflag link as blocked by destination checker
+func (r *RAMStorage) SetBlocked(_ context.Context, shortKey models.ShortURL, blocked bool, reason string) error {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	op := filewrapper.OpUnblock
+	if blocked {
+		op = filewrapper.OpBlock
+	} else {
+		reason = ""
+	}
+
+	// Link is flagged for its owner and in list of all links
+	var recs []filewrapper.Record
+	for user, shorts := range r.DB {
+		if _, ok := shorts[shortKey]; ok {
+			recs = append(recs, filewrapper.Record{
+				Op:    op,
+				User:  user,
+				Short: shortKey,
+				Link:  models.OriginRAM{BlockReason: reason},
+			})
+		}
+	}
+
+	if len(recs) == 0 {
+		return errs.ErrURLNotFound
+	}
+	return r.commit(recs...)
+}
+
+// BlockedLinks get all links flagged by destination checker
+func (r *RAMStorage) BlockedLinks(_ context.Context) ([]models.BlockedLink, error) {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	links := []models.BlockedLink{}
+	for user, shorts := range r.DB {
+		if user == "all" {
+			continue
+		}
+		for k, v := range shorts {
+			if !v.Blocked || v.IsDeleted {
+				continue
+			}
+			links = append(links, models.BlockedLink{
+				Short:  k,
+				Origin: v.Origin,
+				UserID: user,
+				Reason: v.BlockReason,
+			})
+		}
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Short < links[j].Short
+	})
+
+	return links, nil
+}
@@ -0,0 +1,126 @@
+package filestorage
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/filewrapper"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"go.uber.org/zap"
+)
+
+// Operations of domains file
+const (
+	domainOpCreate = "create"
+	domainOpDelete = "delete"
+)
+
+// domainRecord line of domains file
+type domainRecord struct {
+	Op     string         `json:"op"`
+	Host   string         `json:"host"`
+	Domain *models.Domain `json:"domain,omitempty"`
+}
+
+// domainsPath return path of domains file
+func domainsPath(fs string) string {
+	return fs + ".domains"
+}
+
+// loadDomains replay domains file to memory
+func (r *RAMStorage) loadDomains(fs string) error {
+	return filewrapper.ReadJSON(domainsPath(fs), func(line []byte) error {
+		var rec domainRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			r.l.Info("skip broken domain record", zap.Error(err))
+			return nil
+		}
+
+		switch rec.Op {
+		case domainOpCreate:
+			if rec.Domain != nil {
+				r.Hosts[rec.Host] = *rec.Domain
+			}
+		case domainOpDelete:
+			delete(r.Hosts, rec.Host)
+		}
+		return nil
+	})
+}
+
+// appendDomain write record to domains file
+func (r *RAMStorage) appendDomain(rec domainRecord) error {
+	// Config instance
+	cfg, _ := config.Instance()
+	// Config value
+	fs, err := cfg.GetCfgValue(config.FileStoragePath)
+	if err != nil || fs == "" {
+		return nil
+	}
+
+	return filewrapper.AppendJSON(domainsPath(fs), rec)
+}
+
+// SaveDomain register custom domain of tenant
+func (r *RAMStorage) SaveDomain(_ context.Context, domain models.Domain) error {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	if _, ok := r.Hosts[domain.Host]; ok {
+		return errs.ErrDomainExists
+	}
+
+	if err := r.appendDomain(domainRecord{Op: domainOpCreate, Host: domain.Host, Domain: &domain}); err != nil {
+		return err
+	}
+	r.Hosts[domain.Host] = domain
+	return nil
+}
+
+// DomainByHost get registered domain by host
+func (r *RAMStorage) DomainByHost(_ context.Context, host string) (models.Domain, error) {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	domain, ok := r.Hosts[host]
+	if !ok {
+		return models.Domain{}, errs.ErrDomainNotFound
+	}
+	return domain, nil
+}
+
+// Domains get all registered domains
+func (r *RAMStorage) Domains(_ context.Context) ([]models.Domain, error) {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	domains := make([]models.Domain, 0, len(r.Hosts))
+	for _, d := range r.Hosts {
+		domains = append(domains, d)
+	}
+
+	sort.Slice(domains, func(i, j int) bool {
+		return domains[i].Host < domains[j].Host
+	})
+
+	return domains, nil
+}
+
+// DeleteDomain remove domain, links of domain are kept
+func (r *RAMStorage) DeleteDomain(_ context.Context, host string) error {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	if _, ok := r.Hosts[host]; !ok {
+		return errs.ErrDomainNotFound
+	}
+
+	if err := r.appendDomain(domainRecord{Op: domainOpDelete, Host: host}); err != nil {
+		return err
+	}
+	delete(r.Hosts, host)
+	return nil
+}
@@ -3,12 +3,18 @@ package filestorage
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/grishagavrin/link-shortener/internal/config"
 	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/jobs"
+	"github.com/grishagavrin/link-shortener/internal/logger"
 	"github.com/grishagavrin/link-shortener/internal/storage/filewrapper"
 	"github.com/grishagavrin/link-shortener/internal/storage/models"
 	"github.com/grishagavrin/link-shortener/internal/utils"
@@ -17,18 +23,32 @@ import (
 
 // RAMStorage for file storage
 type RAMStorage struct {
-	MU      sync.Mutex
-	DB      map[models.UniqUser]models.ShortLinksRAM
-	l       *zap.Logger
-	chBatch chan models.BatchDelete
+	MU       sync.Mutex
+	DB       map[models.UniqUser]models.ShortLinksRAM
+	Clicks   map[models.ShortURL][]models.ClickEvent
+	Keys     map[string]models.APIKey
+	Hosts    map[string]models.Domain
+	keysUsed map[string]int
+	keysDay  string
+	l        *zap.Logger
+	chBatch  chan models.BatchDelete
+	chClicks chan models.ClickEvent
+	path     string
+	wal      *filewrapper.WAL
 }
 
 // New instance new storage wit not null fields
 func New(l *zap.Logger, ch chan models.BatchDelete) (*RAMStorage, error) {
 	r := &RAMStorage{
-		DB:      make(map[models.UniqUser]models.ShortLinksRAM),
-		l:       l,
-		chBatch: ch,
+		DB:       make(map[models.UniqUser]models.ShortLinksRAM),
+		Clicks:   make(map[models.ShortURL][]models.ClickEvent),
+		Keys:     make(map[string]models.APIKey),
+		Hosts:    make(map[string]models.Domain),
+		keysUsed: make(map[string]int),
+		keysDay:  quotaDay(),
+		l:        l,
+		chBatch:  ch,
+		chClicks: make(chan models.ClickEvent, config.CLICKSBUFFER),
 	}
 
 	if err := r.Load(); err != nil {
@@ -53,11 +73,191 @@ func (r *RAMStorage) Load() error {
 	if err := filewrapper.Read(fs, &r.DB); err != nil {
 		return err
 	}
+
+	if fs == "" {
+		return nil
+	}
+	r.path = fs
+
+	// Replay log of changes made after last snapshot
+	policy, err := cfg.GetCfgValue(config.WALFsync)
+	if err != nil {
+		return err
+	}
+
+	wal, err := filewrapper.OpenWAL(walPath(fs), policy)
+	if err != nil {
+		return err
+	}
+
+	if err = wal.Replay(r.apply); err != nil {
+		return err
+	}
+	r.wal = wal
+	r.l.Info("file storage log replayed", zap.Int("records", wal.Len()))
+
+	// Links deleted before time of deletion was stored start retention now
+	if err = r.backfillDeletedAt(); err != nil {
+		return err
+	}
+	// Links saved before normalization of urls get canonical origins
+	if err = r.backfillOrigins(); err != nil {
+		return err
+	}
+
+	// Click events are stored next to links file
+	err = filewrapper.ReadJSON(clicksPath(fs), func(line []byte) error {
+		var ev models.ClickEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			r.l.Info("skip broken click event", zap.Error(err))
+			return nil
+		}
+		r.Clicks[ev.Short] = append(r.Clicks[ev.Short], ev)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// API keys and domains are stored next to links file too
+	if err = r.loadKeys(fs); err != nil {
+		return err
+	}
+	return r.loadDomains(fs)
+}
+
+// backfillDeletedAt set time of deletion for deleted links which have no it
+func (r *RAMStorage) backfillDeletedAt() error {
+	now := time.Now().UTC()
+	var recs []filewrapper.Record
+	for user, shorts := range r.DB {
+		for k, v := range shorts {
+			if v.IsDeleted && v.DeletedAt.IsZero() {
+				recs = append(recs, filewrapper.Record{Op: filewrapper.OpDelete, User: user, Short: k, Link: models.OriginRAM{DeletedAt: now}})
+			}
+		}
+	}
+
+	if len(recs) == 0 {
+		return nil
+	}
+	r.l.Info("backfill time of deletion", zap.Int("records", len(recs)))
+	return r.commit(recs...)
+}
+
+// backfillOrigins convert origins to canonical form, origins which are not valid now are kept
+func (r *RAMStorage) backfillOrigins() error {
+	var recs []filewrapper.Record
+	for user, shorts := range r.DB {
+		for k, v := range shorts {
+			origin, err := utils.CanonicalURL(string(v.Origin))
+			if err != nil || models.Origin(origin) == v.Origin {
+				continue
+			}
+			recs = append(recs, filewrapper.Record{Op: filewrapper.OpOrigin, User: user, Short: k, Link: models.OriginRAM{Origin: models.Origin(origin)}})
+		}
+	}
+
+	if len(recs) == 0 {
+		return nil
+	}
+	r.l.Info("backfill canonical origins", zap.Int("records", len(recs)))
+	return r.commit(recs...)
+}
+
+// clicksPath return path of click events file
+func clicksPath(fs string) string {
+	return fs + ".clicks"
+}
+
+// walPath return path of log file
+func walPath(fs string) string {
+	return fs + ".wal"
+}
+
+// apply record of log to memory
+func (r *RAMStorage) apply(rec filewrapper.Record) error {
+	switch rec.Op {
+	case filewrapper.OpCreate:
+		if r.DB[rec.User] == nil {
+			r.DB[rec.User] = models.ShortLinksRAM{}
+		}
+		r.DB[rec.User][rec.Short] = rec.Link
+	case filewrapper.OpDelete, filewrapper.OpExpire:
+		if link, ok := r.DB[rec.User][rec.Short]; ok {
+			link.IsDeleted = true
+			link.DeletedAt = rec.Link.DeletedAt
+			r.DB[rec.User][rec.Short] = link
+		}
+	case filewrapper.OpRestore:
+		if link, ok := r.DB[rec.User][rec.Short]; ok {
+			link.IsDeleted = false
+			link.DeletedAt = time.Time{}
+			r.DB[rec.User][rec.Short] = link
+		}
+	case filewrapper.OpPurge:
+		delete(r.DB[rec.User], rec.Short)
+		if rec.User == "all" {
+			delete(r.Clicks, rec.Short)
+		}
+	case filewrapper.OpUpdate:
+		// Record of update contains link with new revision, records of old format contain all
+		// revisions and known ones are skipped
+		if link, ok := r.DB[rec.User][rec.Short]; ok {
+			revisions := link.Revisions[:len(link.Revisions):len(link.Revisions)]
+			for _, rev := range rec.Link.Revisions {
+				if rev.Revision > len(revisions) {
+					revisions = append(revisions, rev)
+				}
+			}
+			next := rec.Link
+			next.Revisions = revisions
+			r.DB[rec.User][rec.Short] = next
+		}
+	case filewrapper.OpOrigin:
+		if link, ok := r.DB[rec.User][rec.Short]; ok {
+			link.Origin = rec.Link.Origin
+			r.DB[rec.User][rec.Short] = link
+		}
+	case filewrapper.OpBlock, filewrapper.OpUnblock:
+		if link, ok := r.DB[rec.User][rec.Short]; ok {
+			link.Blocked = rec.Op == filewrapper.OpBlock
+			link.BlockReason = rec.Link.BlockReason
+			link.Allowed = rec.Op == filewrapper.OpUnblock
+			r.DB[rec.User][rec.Short] = link
+		}
+	}
+
 	return nil
 }
 
+// commit write records to log and then apply them to memory
+func (r *RAMStorage) commit(recs ...filewrapper.Record) error {
+	if r.wal != nil {
+		if err := r.wal.Append(recs...); err != nil {
+			return fmt.Errorf("%w: %v", errs.ErrFileStorageWrite, err)
+		}
+	}
+
+	for _, rec := range recs {
+		_ = r.apply(rec)
+	}
+	return nil
+}
+
+// createRecords records for link of user and for all links
+func createRecords(userID models.UniqUser, shortKey models.ShortURL, link models.OriginRAM) []filewrapper.Record {
+	return []filewrapper.Record{
+		{Op: filewrapper.OpCreate, User: userID, Short: shortKey, Link: link},
+		{Op: filewrapper.OpCreate, User: "all", Short: shortKey, Link: link},
+	}
+}
+
 // LinksByUser return all user links
 func (r *RAMStorage) LinksByUser(_ context.Context, userID models.UniqUser) (models.ShortLinks, error) {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
 	shorts := models.ShortLinks{}
 	shortsRAM, ok := r.DB[userID]
 	if !ok {
@@ -72,86 +272,106 @@ func (r *RAMStorage) LinksByUser(_ context.Context, userID models.UniqUser) (mod
 }
 
 // SaveLinkDB save url in storage of short links
-func (r *RAMStorage) SaveLinkDB(_ context.Context, userID models.UniqUser, url models.Origin) (models.ShortURL, error) {
+func (r *RAMStorage) SaveLinkDB(_ context.Context, userID models.UniqUser, url models.Origin, opts models.LinkOptions) (models.ShortURL, error) {
 	r.MU.Lock()
 	defer r.MU.Unlock()
 
-	shortKey, err := utils.RandStringBytes()
-	if err != nil {
-		return "", err
+	shortKey := opts.Alias
+	if shortKey == "" {
+		key, err := utils.RandStringBytes()
+		if err != nil {
+			return "", err
+		}
+		shortKey = key
 	}
 
-	currentURLUserRes := models.ShortLinks{}
-	currentURLUserRAM := models.ShortLinksRAM{}
+	// Links of custom domain are stored with host in key
+	key := models.LinkKey(opts.Domain, shortKey)
 
-	if urls, ok := r.DB[userID]; ok {
-		for k, v := range urls {
-			if v.Origin == url {
-				return k, errs.ErrAlreadyHasShort
-			}
-		}
-		currentURLUserRAM = urls
+	// Origin already shortened on domain keeps its link, alias is not applied to it
+	if short, ok := findShortened(r.DB, userID, opts.Domain, url); ok {
+		return short, errs.ErrAlreadyHasShort
 	}
 
-	currentURLUserRAM[shortKey] = models.OriginRAM{
-		Origin:    url,
-		IsDeleted: false,
+	// Custom alias must be unique between all users of domain
+	if _, ok := r.DB["all"][key]; ok && opts.Alias != "" {
+		return "", errs.ErrAliasTaken
 	}
-	currentURLUserRes[shortKey] = url
-
-	r.DB[userID] = currentURLUserRAM
-
-	currentURLAllRes := models.ShortLinks{}
-	currentURLAllRAM := models.ShortLinksRAM{}
 
-	if urls, ok := r.DB["all"]; ok {
-		for k, v := range urls {
-			if v.Origin == url {
-				return k, errs.ErrAlreadyHasShort
-			}
-		}
-		currentURLAllRAM = urls
+	link := models.OriginRAM{
+		Origin:       url,
+		IsDeleted:    false,
+		ExpiresAt:    opts.ExpiresAt,
+		RedirectCode: opts.RedirectCode,
+		Blocked:      opts.Blocked,
+		BlockReason:  opts.BlockReason,
 	}
 
-	currentURLAllRAM[shortKey] = models.OriginRAM{
-		Origin:    url,
-		IsDeleted: false,
+	if err := r.commit(createRecords(userID, key, link)...); err != nil {
+		return "", err
 	}
-	currentURLAllRes[shortKey] = url
 
-	r.DB["all"] = currentURLAllRAM
+	return shortKey, nil
+}
 
-	// Config instance
-	cfg, _ := config.Instance()
-	// Config value
-	fs, err := cfg.GetCfgValue(config.FileStoragePath)
-	if err != nil || fs == "" {
-		return "", errs.ErrUnknownEnvOrFlag
+// findShortened find short key of url on domain between links of user and then all links
+func findShortened(db map[models.UniqUser]models.ShortLinksRAM, userID models.UniqUser, domain string, url models.Origin) (models.ShortURL, bool) {
+	if short, ok := findOrigin(db[userID], domain, url); ok {
+		return short, true
 	}
+	return findOrigin(db["all"], domain, url)
+}
 
-	_ = filewrapper.Write(fs, r.DB)
-
-	return shortKey, nil
+// findOrigin find short key of url on domain
+func findOrigin(shorts models.ShortLinksRAM, domain string, url models.Origin) (models.ShortURL, bool) {
+	for k, v := range shorts {
+		if v.Origin != url {
+			continue
+		}
+		if d, short := models.SplitLinkKey(k); d == domain {
+			return short, true
+		}
+	}
+	return "", false
 }
 
 // GetLinkDB get data from storage by short URL
-func (r *RAMStorage) GetLinkDB(_ context.Context, key models.ShortURL) (models.Origin, error) {
+func (r *RAMStorage) GetLinkDB(_ context.Context, key models.ShortURL) (models.Link, error) {
 	r.MU.Lock()
 	defer r.MU.Unlock()
 
 	allShorts, ok := r.DB["all"]
 	if !ok {
-		return "", errs.ErrNotFoundURL
+		return models.Link{}, errs.ErrNotFoundURL
 	}
 
 	originRAM, ok := allShorts[key]
-	if ok && originRAM.IsDeleted {
-		return "", errs.ErrURLIsGone
+	if ok && (originRAM.IsDeleted || originRAM.Expired(time.Now())) {
+		return models.Link{}, errs.ErrURLIsGone
 	} else if !ok {
-		return "", errs.ErrURLNotFound
+		return models.Link{}, errs.ErrURLNotFound
 	}
 
-	return originRAM.Origin, nil
+	return toLink(originRAM), nil
+}
+
+// toLink get data for redirect from stored link
+func toLink(originRAM models.OriginRAM) models.Link {
+	// Links saved before redirect modes have empty code
+	code := originRAM.RedirectCode
+	if code == 0 {
+		code = config.DEFAULTREDIRECT
+	}
+
+	return models.Link{
+		Origin:       originRAM.Origin,
+		RedirectCode: code,
+		ExpiresAt:    originRAM.ExpiresAt,
+		Blocked:      originRAM.Blocked,
+		BlockReason:  originRAM.BlockReason,
+		Allowed:      originRAM.Allowed,
+		Inactive:     originRAM.Inactive,
+	}
 }
 
 // SaveBatch save multiply URL
@@ -160,87 +380,394 @@ func (r *RAMStorage) SaveBatch(_ context.Context, userID models.UniqUser, urls [
 	defer r.MU.Unlock()
 
 	var shortsRes []models.BatchResURL
+	var recs []filewrapper.Record
 
-	currentURLUserRAM := models.ShortLinksRAM{}
-	currentURLAllRAM := models.ShortLinksRAM{}
+	// Duplicate custom aliases in one batch are rejected before save any url from batch
+	aliases := make(map[models.ShortURL]struct{})
+	for _, url := range urls {
+		if url.Alias == "" {
+			continue
+		}
+		key := models.LinkKey(url.Domain, models.ShortURL(url.Alias))
+		if _, ok := aliases[key]; ok {
+			return nil, errs.ErrAliasTaken
+		}
+		aliases[key] = struct{}{}
+	}
 
-	if r.DB["all"] != nil && r.DB[userID] != nil {
-		currentURLAllRAM = r.DB["all"]
-		currentURLUserRAM = r.DB[userID]
+	// Links of batch are found by origin as saved ones
+	type originKey struct {
+		domain string
+		origin models.Origin
 	}
+	saved := make(map[originKey]models.ShortURL)
 
 	for _, url := range urls {
-		shortKey, _ := utils.RandStringBytes()
+		origin := models.Origin(url.Origin)
 
-		currentURLUserRAM[shortKey] = models.OriginRAM{
-			Origin:    models.Origin(url.Origin),
-			IsDeleted: false,
+		// Origin already shortened on domain keeps its link, alias is not applied to it
+		shortKey, ok := saved[originKey{url.Domain, origin}]
+		if !ok {
+			shortKey, ok = findShortened(r.DB, userID, url.Domain, origin)
 		}
-
-		r.DB[userID] = currentURLUserRAM
-
-		currentURLAllRAM[shortKey] = models.OriginRAM{
-			Origin:    models.Origin(url.Origin),
-			IsDeleted: false,
+		if ok {
+			shortsRes = append(shortsRes, models.BatchResURL{CorrID: url.CorrID, Short: string(shortKey), Domain: url.Domain})
+			continue
 		}
 
-		r.DB["all"] = currentURLAllRAM
+		shortKey = models.ShortURL(url.Alias)
+		if shortKey == "" {
+			shortKey, _ = utils.RandStringBytes()
+		} else if _, ok := r.DB["all"][models.LinkKey(url.Domain, shortKey)]; ok {
+			// Custom alias must be unique between all users of domain
+			return nil, errs.ErrAliasTaken
+		}
+		saved[originKey{url.Domain, origin}] = shortKey
+
+		link := models.OriginRAM{
+			Origin:       origin,
+			IsDeleted:    false,
+			ExpiresAt:    url.ExpiresAt,
+			RedirectCode: url.Redirect,
+			Blocked:      url.Blocked,
+			BlockReason:  url.BlockReason,
+		}
+		recs = append(recs, createRecords(userID, models.LinkKey(url.Domain, shortKey), link)...)
 
 		resItem := models.BatchResURL{
 			CorrID: url.CorrID,
 			Short:  string(shortKey),
+			Domain: url.Domain,
 		}
 
 		shortsRes = append(shortsRes, resItem)
 	}
 
-	// Config instance
-	cfg, _ := config.Instance()
-	// Config value
-	fs, err := cfg.GetCfgValue(config.FileStoragePath)
-	if err != nil || fs == "" {
-		return nil, errs.ErrUnknownEnvOrFlag
+	// All batch is written to log by one append
+	if err := r.commit(recs...); err != nil {
+		return nil, err
 	}
 
-	_ = filewrapper.Write(fs, r.DB)
-
 	return shortsRes, nil
 }
 
-// BunchUpdateAsDeleted delete mass URL by fanIN pattern
-func (r *RAMStorage) BunchUpdateAsDeleted(chBatch chan models.BatchDelete) {
-	for v := range chBatch {
-		r.MU.Lock()
+// BunchUpdateAsDeleted delete, restore or purge mass URL of coalesced batches with one log write
+func (r *RAMStorage) BunchUpdateAsDeleted(ctx context.Context, batches []models.BatchDelete) error {
+	for _, v := range batches {
+		jobs.Instance().Start(v.JobID)
+	}
+
+	r.MU.Lock()
+
+	var recs []filewrapper.Record
+	results := make([][]models.JobResult, len(batches))
+	for i, v := range batches {
+		var batchRecs []filewrapper.Record
+		switch v.Op {
+		case models.BatchOpPurge:
+			batchRecs = r.purgeRecords(ctx, v.Before)
+		case models.BatchOpRestore:
+			batchRecs, results[i] = r.userRecords(ctx, v, filewrapper.OpRestore, models.JobURLRestored, func(link models.OriginRAM) bool {
+				return link.IsDeleted && !link.Expired(time.Now())
+			})
+		default:
+			batchRecs, results[i] = r.userRecords(ctx, v, filewrapper.OpDelete, models.JobURLDeleted, func(models.OriginRAM) bool {
+				return true
+			})
+		}
+		recs = append(recs, batchRecs...)
+	}
+
+	err := r.commit(recs...)
+	if err != nil {
+		logger.FromContext(ctx).Info("unable to update urls", zap.Int("batches", len(batches)), zap.Error(err))
+	}
+	r.MU.Unlock()
+
+	for i, v := range batches {
+		jobs.Instance().Finish(v.JobID, results[i], err)
+	}
+	return err
+}
+
+// userRecords records and outcomes for user links from batch, links are taken only if user owns them
+func (r *RAMStorage) userRecords(
+	ctx context.Context,
+	v models.BatchDelete,
+	op, outcome string,
+	take func(models.OriginRAM) bool,
+) ([]filewrapper.Record, []models.JobResult) {
+	if len(v.URLs) == 0 {
+		logger.FromContext(ctx).Info(errs.ErrCorrelation.Error())
+	}
 
-		// Config instance
-		cfg, _ := config.Instance()
-		// Config value
-		fs, err := cfg.GetCfgValue(config.FileStoragePath)
-		if err != nil || fs == "" {
-			r.l.Info(errs.ErrUnknownEnvOrFlag.Error())
+	deleted := models.OriginRAM{DeletedAt: time.Now().UTC()}
+
+	var recs []filewrapper.Record
+	results := make([]models.JobResult, 0, len(v.URLs))
+	for _, id := range v.URLs {
+		shortKey := models.ShortURL(id)
+		link, ok := r.DB[models.UniqUser(v.UserID)][shortKey]
+		switch {
+		case !ok:
+			if _, exists := r.DB["all"][shortKey]; exists {
+				results = append(results, models.JobResult{URL: id, Outcome: models.JobURLNotOwned})
+			} else {
+				results = append(results, models.JobResult{URL: id, Outcome: models.JobURLNotFound})
+			}
+			continue
+		case !take(link):
+			results = append(results, models.JobResult{URL: id, Outcome: models.JobURLUnchanged})
+			continue
 		}
 
-		if len(v.URLs) == 0 {
-			r.l.Info(errs.ErrCorrelation.Error())
+		results = append(results, models.JobResult{URL: id, Outcome: outcome})
+		recs = append(recs,
+			filewrapper.Record{Op: op, User: models.UniqUser(v.UserID), Short: shortKey, Link: deleted},
+			filewrapper.Record{Op: op, User: "all", Short: shortKey, Link: deleted},
+		)
+	}
+
+	return recs, results
+}
+
+// purgeRecords records for links deleted before time, links without time of deletion are kept
+func (r *RAMStorage) purgeRecords(ctx context.Context, before time.Time) []filewrapper.Record {
+	var recs []filewrapper.Record
+	for user, shorts := range r.DB {
+		for k, v := range shorts {
+			if v.IsDeleted && !v.DeletedAt.IsZero() && v.DeletedAt.Before(before) {
+				recs = append(recs, filewrapper.Record{Op: filewrapper.OpPurge, User: user, Short: k})
+			}
+		}
+	}
+
+	logger.FromContext(ctx).Info("purge deleted links", zap.Int("records", len(recs)))
+	return recs
+}
+
+// SweepExpired mark expired links as deleted with interval until context done
+func (r *RAMStorage) SweepExpired(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.MU.Lock()
+			var recs []filewrapper.Record
+			for user, shorts := range r.DB {
+				for k, v := range shorts {
+					if v.IsDeleted || !v.Expired(now) {
+						continue
+					}
+					recs = append(recs, filewrapper.Record{Op: filewrapper.OpExpire, User: user, Short: k, Link: models.OriginRAM{DeletedAt: now.UTC()}})
+				}
+			}
+
+			if len(recs) > 0 {
+				if err := r.commit(recs...); err != nil {
+					r.l.Info("sweep expired links error", zap.Error(err))
+				} else {
+					r.l.Info("expired links swept", zap.Int("count", len(recs)))
+				}
+			}
+			r.MU.Unlock()
 		}
+	}
+}
 
-		shortUser := r.DB[models.UniqUser(v.UserID)]
-		shortAll := r.DB["all"]
+// RunLogMaintenance sync log by fsync policy and compact it into snapshot until context done
+func (r *RAMStorage) RunLogMaintenance(ctx context.Context, interval time.Duration) {
+	if r.wal == nil {
+		return
+	}
+
+	syncTicker := time.NewTicker(config.WALSYNC)
+	defer syncTicker.Stop()
+	compactTicker := time.NewTicker(interval)
+	defer compactTicker.Stop()
 
-		for _, v := range v.URLs {
-			if su, ok := shortUser[models.ShortURL(v)]; ok {
-				su.IsDeleted = true
-				shortUser[models.ShortURL(v)] = su
+	for {
+		select {
+		case <-ctx.Done():
+			if err := r.Compact(); err != nil {
+				r.l.Info("file storage compaction error", zap.Error(err))
+			}
+			return
+		case <-syncTicker.C:
+			if err := r.wal.Sync(); err != nil {
+				r.l.Info("file storage log sync error", zap.Error(err))
+			}
+			if r.wal.Len() < config.WALCOMPACTRECORDS {
+				continue
 			}
+		case <-compactTicker.C:
+		}
+
+		if err := r.Compact(); err != nil {
+			r.l.Info("file storage compaction error", zap.Error(err))
+		}
+	}
+}
+
+// Compact write snapshot of all links and truncate log
+func (r *RAMStorage) Compact() error {
+	if r.wal == nil {
+		return nil
+	}
+
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	if r.wal.Len() == 0 {
+		return nil
+	}
+
+	if err := filewrapper.WriteSnapshot(r.path, r.DB); err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrFileStorageWrite, err)
+	}
+
+	// Records of log are idempotent, so crash before reset only replays them again
+	if err := r.wal.Reset(); err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrFileStorageWrite, err)
+	}
+	return nil
+}
+
+// Close sync and close file storage log, it is called after log maintenance is stopped
+func (r *RAMStorage) Close() error {
+	if r.wal == nil {
+		return nil
+	}
+
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	if err := r.wal.Close(); err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrFileStorageWrite, err)
+	}
+	return nil
+}
+
+// SaveClick put click event to buffer, event is dropped if buffer is full
+func (r *RAMStorage) SaveClick(ev models.ClickEvent) {
+	select {
+	case r.chClicks <- ev:
+	default:
+		r.l.Info("clicks buffer is full", zap.String("short", string(ev.Short)))
+	}
+}
 
-			if sa, ok := shortAll[models.ShortURL(v)]; ok {
-				sa.IsDeleted = true
-				shortAll[models.ShortURL(v)] = sa
+// BunchSaveClicks write buffered click events by batches until context done
+func (r *RAMStorage) BunchSaveClicks(ctx context.Context) {
+	ticker := time.NewTicker(config.CLICKSFLUSH)
+	defer ticker.Stop()
+
+	events := make([]models.ClickEvent, 0, config.CLICKSBATCH)
+	for {
+		select {
+		case ev := <-r.chClicks:
+			events = append(events, ev)
+			if len(events) < config.CLICKSBATCH {
+				continue
 			}
+		case <-ticker.C:
+		case <-ctx.Done():
+			// Write all events left in buffer
+			for len(r.chClicks) > 0 {
+				events = append(events, <-r.chClicks)
+			}
+			r.flushClicks(events)
+			return
+		}
+
+		r.flushClicks(events)
+		events = events[:0]
+	}
+}
+
+// flushClicks save click events to memory and append them to file
+func (r *RAMStorage) flushClicks(events []models.ClickEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	items := make([]interface{}, 0, len(events))
+	for _, ev := range events {
+		r.Clicks[ev.Short] = append(r.Clicks[ev.Short], ev)
+		items = append(items, ev)
+	}
+
+	// Config instance
+	cfg, _ := config.Instance()
+	// Config value
+	fs, err := cfg.GetCfgValue(config.FileStoragePath)
+	if err != nil || fs == "" {
+		return
+	}
+
+	if err = filewrapper.AppendJSON(clicksPath(fs), items...); err != nil {
+		r.l.Info("unable to save clicks", zap.Error(err))
+	}
+}
+
+// ClickStats get total and time series of clicks for user link
+func (r *RAMStorage) ClickStats(_ context.Context, userID models.UniqUser, shortKey models.ShortURL) (models.ClickStats, error) {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	stats := models.ClickStats{
+		Short:  string(shortKey),
+		Hourly: []models.ClickPoint{},
+		Daily:  []models.ClickPoint{},
+	}
+
+	if _, ok := r.DB[userID][shortKey]; !ok {
+		return stats, errs.ErrNotFoundURL
+	}
+
+	now := time.Now().UTC()
+	hourSince := now.Add(-config.STATSHOURS * time.Hour)
+	daySince := now.AddDate(0, 0, -config.STATSDAYS)
+
+	hourly := map[time.Time]int{}
+	daily := map[time.Time]int{}
+	for _, ev := range r.Clicks[shortKey] {
+		stats.Total++
+
+		at := ev.ClickedAt.UTC()
+		if !at.Before(hourSince) {
+			hourly[at.Truncate(time.Hour)]++
+		}
+		if !at.Before(daySince) {
+			daily[time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)]++
 		}
-		_ = filewrapper.Write(fs, r.DB)
-		r.MU.Unlock()
 	}
+
+	stats.Hourly = clickSeries(hourly)
+	stats.Daily = clickSeries(daily)
+
+	return stats, nil
+}
+
+// clickSeries convert grouped clicks to sorted time series
+func clickSeries(buckets map[time.Time]int) []models.ClickPoint {
+	points := make([]models.ClickPoint, 0, len(buckets))
+	for t, n := range buckets {
+		points = append(points, models.ClickPoint{Time: t, Clicks: n})
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+
+	return points
 }
 
 // GetStats get statistics quantity urls and users
@@ -253,3 +780,22 @@ func (r *RAMStorage) GetStats(_ context.Context, userID models.UniqUser) (models
 		URLs:  len(r.DB["all"]), // r.DB["all"] contains all hashed links
 	}, nil
 }
+
+// Health check last write to file storage log succeeded
+func (r *RAMStorage) Health(context.Context) error {
+	if r.wal == nil {
+		return nil
+	}
+	if err := r.wal.Err(); err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrStorageUnhealthy, err)
+	}
+	return nil
+}
+
+// CheckFile check new files can be written next to file storage, storage without file is always ready
+func (r *RAMStorage) CheckFile(context.Context) error {
+	if r.path == "" {
+		return nil
+	}
+	return filewrapper.CheckWritable(filepath.Dir(r.path))
+}
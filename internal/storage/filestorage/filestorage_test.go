@@ -0,0 +1,50 @@
+package filestorage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRAMStorage_LinksByUserDuringBatch(t *testing.T) {
+	r := &RAMStorage{
+		DB:     make(map[models.UniqUser]models.ShortLinksRAM),
+		Clicks: make(map[models.ShortURL][]models.ClickEvent),
+		l:      zap.NewNop(),
+	}
+
+	// ссылки пользователя без журнала на диске
+	urls := make([]string, 0, 50)
+	for n := 0; n < 50; n++ {
+		short := models.ShortURL(fmt.Sprintf("key%d", n))
+		link := models.OriginRAM{Origin: models.Origin(fmt.Sprintf("http://yandex.ru/%d", n))}
+		assert.NoError(t, r.commit(createRecords("u1", short, link)...))
+		urls = append(urls, string(short))
+	}
+
+	// удаление и восстановление применяются, пока список ссылок читается (проверяется с -race)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 50; n++ {
+			op := models.BatchOpDelete
+			if n%2 == 1 {
+				op = models.BatchOpRestore
+			}
+			assert.NoError(t, r.BunchUpdateAsDeleted(context.Background(), []models.BatchDelete{{Op: op, UserID: "u1", URLs: urls}}))
+		}
+	}()
+
+	for n := 0; n < 50; n++ {
+		links, err := r.LinksByUser(context.Background(), "u1")
+		assert.NoError(t, err)
+		assert.Len(t, links, len(urls))
+	}
+	wg.Wait()
+}
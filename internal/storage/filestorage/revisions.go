@@ -0,0 +1,87 @@
+package filestorage
+
+import (
+	"context"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/filewrapper"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+)
+
+// UpdateLink change link of owner, previous state is kept in revisions
+func (r *RAMStorage) UpdateLink(_ context.Context, userID models.UniqUser, key models.ShortURL, upd models.LinkUpdate) (models.Link, error) {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	link, ok := r.DB[userID][key]
+	if !ok || userID == "all" {
+		return models.Link{}, errs.ErrNotFoundURL
+	}
+
+	now := time.Now()
+	if link.IsDeleted || link.Expired(now) {
+		return models.Link{}, errs.ErrURLIsGone
+	}
+
+	// Origin must stay unique on domain
+	if upd.Origin != nil && *upd.Origin != link.Origin {
+		domain, _ := models.SplitLinkKey(key)
+		if _, ok := findOrigin(r.DB["all"], domain, *upd.Origin); ok {
+			return models.Link{}, errs.ErrAlreadyHasShort
+		}
+	}
+
+	// Record of update contains only new revision, it is appended to revisions on apply
+	prev := toLink(link)
+	next := link
+	next.Revisions = []models.LinkRevision{{
+		Revision:     len(link.Revisions) + 1,
+		Origin:       prev.Origin,
+		RedirectCode: prev.RedirectCode,
+		ExpiresAt:    prev.ExpiresAt,
+		Active:       !prev.Inactive,
+		ChangedAt:    now.UTC(),
+	}}
+
+	// Block is cleared only by admin, unblock of admin is for previous destination
+	if upd.Origin != nil && *upd.Origin != link.Origin {
+		next.Origin = *upd.Origin
+		next.Allowed = false
+	}
+	if upd.Origin != nil && upd.Blocked && !link.Blocked {
+		next.Blocked, next.BlockReason = true, upd.BlockReason
+	}
+	if upd.Active != nil {
+		next.Inactive = !*upd.Active
+	}
+	if upd.RedirectCode != nil {
+		next.RedirectCode = *upd.RedirectCode
+	}
+	if upd.ExpiresAt != nil {
+		next.ExpiresAt = *upd.ExpiresAt
+	}
+
+	err := r.commit(
+		filewrapper.Record{Op: filewrapper.OpUpdate, User: userID, Short: key, Link: next},
+		filewrapper.Record{Op: filewrapper.OpUpdate, User: "all", Short: key, Link: next},
+	)
+	if err != nil {
+		return models.Link{}, err
+	}
+
+	return toLink(next), nil
+}
+
+// LinkRevisions get previous states of user link, the oldest first
+func (r *RAMStorage) LinkRevisions(_ context.Context, userID models.UniqUser, key models.ShortURL) ([]models.LinkRevision, error) {
+	r.MU.Lock()
+	defer r.MU.Unlock()
+
+	link, ok := r.DB[userID][key]
+	if !ok || userID == "all" {
+		return nil, errs.ErrNotFoundURL
+	}
+
+	return append([]models.LinkRevision{}, link.Revisions...), nil
+}
@@ -4,15 +4,38 @@ package filewrapper
 import (
 	"bufio"
 	"encoding/gob"
+	"encoding/json"
 	"io"
 	"os"
 
 	"github.com/grishagavrin/link-shortener/internal/errs"
 )
 
-// Write data to path
-func Write(path string, data interface{}) error {
-	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0777)
+// Read data from path to data variable
+func Read(path string, data interface{}) error {
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0777)
+	if err != nil {
+		return err
+	}
+	// handle for file close
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			panic(errs.ErrFileStorageNotClose)
+		}
+	}(f)
+	gd := gob.NewDecoder(f)
+	if err := gd.Decode(data); err != nil {
+		if err != io.EOF {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendJSON append items to path as json lines
+func AppendJSON(path string, items ...interface{}) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0777)
 	if err != nil {
 		return err
 	}
@@ -24,19 +47,18 @@ func Write(path string, data interface{}) error {
 		}
 	}(f)
 
-	// Convert to gob
 	buffer := bufio.NewWriter(f)
-	ge := gob.NewEncoder(buffer)
-	// encode
-	if err := ge.Encode(data); err != nil {
-		return err
+	je := json.NewEncoder(buffer)
+	for _, item := range items {
+		if err := je.Encode(item); err != nil {
+			return err
+		}
 	}
-	_ = buffer.Flush()
-	return nil
+	return buffer.Flush()
 }
 
-// Read data from path to data variable
-func Read(path string, data interface{}) error {
+// ReadJSON read json lines from path and call fn for every line
+func ReadJSON(path string, fn func(line []byte) error) error {
 	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0777)
 	if err != nil {
 		return err
@@ -48,11 +70,31 @@ func Read(path string, data interface{}) error {
 			panic(errs.ErrFileStorageNotClose)
 		}
 	}(f)
-	gd := gob.NewDecoder(f)
-	if err := gd.Decode(data); err != nil {
-		if err != io.EOF {
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := fn(scanner.Bytes()); err != nil {
 			return err
 		}
 	}
-	return nil
+	return scanner.Err()
+}
+
+// CheckWritable check new file can be written to dir
+func CheckWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".healthcheck*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write([]byte("ok")); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
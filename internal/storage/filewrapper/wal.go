@@ -0,0 +1,284 @@
+package filewrapper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+)
+
+// Operations of log records
+const (
+	OpCreate  = "create"
+	OpDelete  = "delete"
+	OpExpire  = "expire"
+	OpBlock   = "block"
+	OpUnblock = "unblock"
+	OpUpdate  = "update"
+	OpRestore = "restore"
+	OpPurge   = "purge"
+	OpOrigin  = "origin"
+)
+
+// Fsync policies of log
+const (
+	SyncAlways   = "always"
+	SyncInterval = "interval"
+	SyncNever    = "never"
+)
+
+// Record typed record of append-only log
+type Record struct {
+	Op    string           `json:"op"`
+	User  models.UniqUser  `json:"user"`
+	Short models.ShortURL  `json:"short"`
+	Link  models.OriginRAM `json:"link"`
+}
+
+// WAL append-only log, every line is "<crc32 hex> <json array of records>" written by one Append,
+// so batch is replayed whole or not at all. Lines with one json record are written by previous versions
+type WAL struct {
+	mu      sync.Mutex
+	policy  string
+	f       *os.File
+	w       *bufio.Writer
+	records int
+	dirty   bool
+	// err last error of write or sync, it is reset by next successful one
+	err error
+}
+
+// OpenWAL open or create log file with fsync policy
+func OpenWAL(path string, policy string) (*WAL, error) {
+	switch policy {
+	case SyncAlways, SyncInterval, SyncNever:
+	default:
+		return nil, fmt.Errorf("%w: %s", errs.ErrWALPolicy, policy)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WAL{
+		policy: policy,
+		f:      f,
+		w:      bufio.NewWriter(f),
+	}, nil
+}
+
+// Append write records to the end of log, with always policy they are synced to disk
+func (l *WAL) Append(recs ...Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.err = l.append(recs)
+	return l.err
+}
+
+// append write records as one line without lock
+func (l *WAL) append(recs []Record) error {
+	if len(recs) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(recs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(l.w, "%08x %s\n", crc32.ChecksumIEEE(body), body)
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	l.records += len(recs)
+	l.dirty = true
+
+	if l.policy == SyncAlways {
+		return l.sync()
+	}
+	return nil
+}
+
+// Sync flush log to disk if there are not synced records
+func (l *WAL) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.policy == SyncNever {
+		return nil
+	}
+	l.err = l.sync()
+	return l.err
+}
+
+// Err last error of write or sync of log
+func (l *WAL) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
+
+// sync log file without lock
+func (l *WAL) sync() error {
+	if !l.dirty {
+		return nil
+	}
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+	l.dirty = false
+	return nil
+}
+
+// Len count of records since last reset
+func (l *WAL) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.records
+}
+
+// Replay read records of all valid lines, torn or broken tail after crash is cut off
+func (l *WAL) Replay(fn func(Record) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(l.f)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Line without end is a torn write
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		recs, ok := parseLine(line)
+		if !ok {
+			break
+		}
+
+		for _, rec := range recs {
+			if err = fn(rec); err != nil {
+				return err
+			}
+		}
+		offset += int64(len(line))
+		l.records += len(recs)
+	}
+
+	// Cut off everything after last valid record
+	if err := l.f.Truncate(offset); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseLine check crc and decode records from log line
+func parseLine(line []byte) ([]Record, bool) {
+	parts := bytes.SplitN(bytes.TrimSuffix(line, []byte("\n")), []byte(" "), 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return nil, false
+	}
+
+	crc, err := strconv.ParseUint(string(parts[0]), 16, 32)
+	if err != nil || crc32.ChecksumIEEE(parts[1]) != uint32(crc) {
+		return nil, false
+	}
+
+	if parts[1][0] != '[' {
+		var rec Record
+		if err = json.Unmarshal(parts[1], &rec); err != nil {
+			return nil, false
+		}
+		return []Record{rec}, true
+	}
+
+	var recs []Record
+	if err = json.Unmarshal(parts[1], &recs); err != nil {
+		return nil, false
+	}
+	return recs, true
+}
+
+// Reset truncate log after snapshot is written
+func (l *WAL) Reset() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	if err := l.f.Truncate(0); err != nil {
+		return err
+	}
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+	l.records = 0
+	l.dirty = false
+	return nil
+}
+
+// Close sync and close log file
+func (l *WAL) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	if err := l.sync(); err != nil {
+		return err
+	}
+	return l.f.Close()
+}
+
+// WriteSnapshot write data to path atomically: temp file, fsync and rename
+func WriteSnapshot(path string, data interface{}) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	// Remove temp file if rename was not done
+	defer os.Remove(tmp.Name())
+
+	buffer := bufio.NewWriter(tmp)
+	if err = gob.NewEncoder(buffer).Encode(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = buffer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
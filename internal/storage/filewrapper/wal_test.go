@@ -0,0 +1,140 @@
+package filewrapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// replay все записи журнала по порядку
+func replay(t *testing.T, path string) []Record {
+	wal, err := OpenWAL(path, SyncNever)
+	assert.NoError(t, err)
+	defer wal.Close()
+
+	var recs []Record
+	assert.NoError(t, wal.Replay(func(rec Record) error {
+		recs = append(recs, rec)
+		return nil
+	}))
+	return recs
+}
+
+// create запись создания ссылки
+func create(short string) Record {
+	return Record{Op: OpCreate, User: "u1", Short: models.ShortURL(short), Link: models.OriginRAM{Origin: "http://yandex.ru/" + models.Origin(short)}}
+}
+
+func TestWAL_Replay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.wal")
+	wal, err := OpenWAL(path, SyncAlways)
+	assert.NoError(t, err)
+	assert.NoError(t, wal.Append(create("a"), create("b")))
+	assert.NoError(t, wal.Append(create("c")))
+	assert.Equal(t, 3, wal.Len())
+	assert.NoError(t, wal.Close())
+
+	// записи восстанавливаются в порядке записи
+	assert.Equal(t, []Record{create("a"), create("b"), create("c")}, replay(t, path))
+
+	// неизвестная политика синхронизации
+	_, err = OpenWAL(path, "sometimes")
+	assert.ErrorIs(t, err, errs.ErrWALPolicy)
+}
+
+func TestWAL_TornTail(t *testing.T) {
+	// строка старого формата с одной записью
+	body, _ := json.Marshal(create("legacy"))
+	legacy := fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(body), body)
+
+	// создаём массив тестов: хвост журнала после сбоя и записи, которые должны остаться
+	tests := []struct {
+		name string
+		tail string
+		want []Record
+	}{
+		{
+			name: "positive test #1",
+			want: []Record{create("a"), create("b")},
+		},
+		{
+			name: "positive test #2",
+			tail: legacy,
+			want: []Record{create("a"), create("b"), create("legacy")},
+		},
+		{
+			name: "negative test #1",
+			tail: legacy[:len(legacy)-10],
+			want: []Record{create("a"), create("b")},
+		},
+		{
+			name: "negative test #2",
+			tail: "00000000" + legacy[8:],
+			want: []Record{create("a"), create("b")},
+		},
+		{
+			name: "negative test #3",
+			tail: "garbage\n" + legacy,
+			want: []Record{create("a"), create("b")},
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "links.wal")
+			wal, err := OpenWAL(path, SyncNever)
+			assert.NoError(t, err)
+			assert.NoError(t, wal.Append(create("a"), create("b")))
+			assert.NoError(t, wal.Close())
+
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+			assert.NoError(t, err)
+			f.WriteString(tt.tail)
+			f.Close()
+
+			// пакет не применяется частично, испорченный хвост отрезается
+			assert.Equal(t, tt.want, replay(t, path))
+
+			// после отрезания журнал продолжает писаться
+			wal, err = OpenWAL(path, SyncNever)
+			assert.NoError(t, err)
+			assert.NoError(t, wal.Replay(func(Record) error { return nil }))
+			assert.NoError(t, wal.Append(create("d")))
+			assert.NoError(t, wal.Close())
+			assert.Equal(t, append(tt.want, create("d")), replay(t, path))
+		})
+	}
+}
+
+func TestWAL_Compaction(t *testing.T) {
+	dir := t.TempDir()
+	snapshot := filepath.Join(dir, "links")
+	path := filepath.Join(dir, "links.wal")
+
+	wal, err := OpenWAL(path, SyncInterval)
+	assert.NoError(t, err)
+	assert.NoError(t, wal.Append(create("a"), create("b")))
+	assert.NoError(t, wal.Sync())
+
+	// снимок содержит записи журнала, журнал после сброса пуст
+	db := map[models.UniqUser]models.ShortLinksRAM{"u1": {"a": create("a").Link, "b": create("b").Link}}
+	assert.NoError(t, WriteSnapshot(snapshot, db))
+	assert.NoError(t, wal.Reset())
+	assert.Equal(t, 0, wal.Len())
+
+	// записи после сброса пишутся с начала журнала
+	assert.NoError(t, wal.Append(create("c")))
+	assert.NoError(t, wal.Close())
+	assert.Equal(t, []Record{create("c")}, replay(t, path))
+
+	var got map[models.UniqUser]models.ShortLinksRAM
+	assert.NoError(t, Read(snapshot, &got))
+	assert.Equal(t, db, got)
+}
@@ -0,0 +1,235 @@
+// Package instrumented implement repository decorator which trace storage operations and measure their latency and errors
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/handlers"
+	"github.com/grishagavrin/link-shortener/internal/metrics"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/grishagavrin/link-shortener/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Repository decorator with spans and metrics of storage operations
+type Repository struct {
+	repo    handlers.Repository
+	backend string
+}
+
+// New decorator of repository, backend is label of metrics
+func New(repo handlers.Repository, backend string) *Repository {
+	return &Repository{repo: repo, backend: backend}
+}
+
+// start span of operation, returned function end span and write latency of operation,
+// unexpected error marks them as failed
+func (r *Repository) start(ctx context.Context, op string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, "storage."+op,
+		trace.WithAttributes(attribute.String("storage.backend", r.backend)),
+	)
+
+	return ctx, func(err error) {
+		isFailed := failed(err)
+		metrics.ObserveStorage(r.backend, op, time.Since(start), isFailed)
+		if isFailed {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// failed check error is not a regular result of lookup or validation
+func failed(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, errs.ErrURLNotFound),
+		errors.Is(err, errs.ErrNotFoundURL),
+		errors.Is(err, errs.ErrURLIsGone),
+		errors.Is(err, errs.ErrAlreadyHasShort),
+		errors.Is(err, errs.ErrAliasTaken),
+		errors.Is(err, errs.ErrDomainExists),
+		errors.Is(err, errs.ErrDomainNotFound),
+		errors.Is(err, errs.ErrAPIKeyNotFound),
+		errors.Is(err, errs.ErrQuotaExceeded):
+		return false
+	}
+	return true
+}
+
+// GetLinkDB measured lookup of link
+func (r *Repository) GetLinkDB(ctx context.Context, key models.ShortURL) (link models.Link, err error) {
+	ctx, end := r.start(ctx, "GetLinkDB")
+	defer func() { end(err) }()
+	return r.repo.GetLinkDB(ctx, key)
+}
+
+// SaveLinkDB measured save of link
+func (r *Repository) SaveLinkDB(
+	ctx context.Context,
+	userID models.UniqUser,
+	origin models.Origin,
+	opts models.LinkOptions,
+) (short models.ShortURL, err error) {
+	ctx, end := r.start(ctx, "SaveLinkDB")
+	defer func() { end(err) }()
+	return r.repo.SaveLinkDB(ctx, userID, origin, opts)
+}
+
+// LinksByUser measured list of user links
+func (r *Repository) LinksByUser(ctx context.Context, userID models.UniqUser) (links models.ShortLinks, err error) {
+	ctx, end := r.start(ctx, "LinksByUser")
+	defer func() { end(err) }()
+	return r.repo.LinksByUser(ctx, userID)
+}
+
+// SaveBatch measured save of links
+func (r *Repository) SaveBatch(
+	ctx context.Context,
+	userID models.UniqUser,
+	urls []models.BatchReqURL,
+) (res []models.BatchResURL, err error) {
+	ctx, end := r.start(ctx, "SaveBatch")
+	defer func() { end(err) }()
+	return r.repo.SaveBatch(ctx, userID, urls)
+}
+
+// BunchUpdateAsDeleted measured apply of delete batches
+func (r *Repository) BunchUpdateAsDeleted(ctx context.Context, batches []models.BatchDelete) (err error) {
+	ctx, end := r.start(ctx, "BunchUpdateAsDeleted")
+	defer func() { end(err) }()
+	return r.repo.BunchUpdateAsDeleted(ctx, batches)
+}
+
+// GetStats measured statistics
+func (r *Repository) GetStats(ctx context.Context, userID models.UniqUser) (stats models.GetStatsResURL, err error) {
+	ctx, end := r.start(ctx, "GetStats")
+	defer func() { end(err) }()
+	return r.repo.GetStats(ctx, userID)
+}
+
+// SaveClick put click to buffer of storage, it is not measured
+func (r *Repository) SaveClick(ev models.ClickEvent) {
+	r.repo.SaveClick(ev)
+}
+
+// ClickStats measured statistics of clicks
+func (r *Repository) ClickStats(ctx context.Context, userID models.UniqUser, key models.ShortURL) (stats models.ClickStats, err error) {
+	ctx, end := r.start(ctx, "ClickStats")
+	defer func() { end(err) }()
+	return r.repo.ClickStats(ctx, userID, key)
+}
+
+// SaveAPIKey measured save of api key
+func (r *Repository) SaveAPIKey(ctx context.Context, key models.APIKey) (err error) {
+	ctx, end := r.start(ctx, "SaveAPIKey")
+	defer func() { end(err) }()
+	return r.repo.SaveAPIKey(ctx, key)
+}
+
+// APIKeyByHash measured lookup of api key
+func (r *Repository) APIKeyByHash(ctx context.Context, hash string) (key models.APIKey, err error) {
+	ctx, end := r.start(ctx, "APIKeyByHash")
+	defer func() { end(err) }()
+	return r.repo.APIKeyByHash(ctx, hash)
+}
+
+// APIKeys measured list of api keys
+func (r *Repository) APIKeys(ctx context.Context) (keys []models.APIKey, err error) {
+	ctx, end := r.start(ctx, "APIKeys")
+	defer func() { end(err) }()
+	return r.repo.APIKeys(ctx)
+}
+
+// DeleteAPIKey measured delete of api key
+func (r *Repository) DeleteAPIKey(ctx context.Context, id string) (err error) {
+	ctx, end := r.start(ctx, "DeleteAPIKey")
+	defer func() { end(err) }()
+	return r.repo.DeleteAPIKey(ctx, id)
+}
+
+// UseAPIKeyQuota measured use of api key quota
+func (r *Repository) UseAPIKeyQuota(ctx context.Context, id string, n, quota int) (err error) {
+	ctx, end := r.start(ctx, "UseAPIKeyQuota")
+	defer func() { end(err) }()
+	return r.repo.UseAPIKeyQuota(ctx, id, n, quota)
+}
+
+// SetBlocked measured change of link block
+func (r *Repository) SetBlocked(ctx context.Context, key models.ShortURL, blocked bool, reason string) (err error) {
+	ctx, end := r.start(ctx, "SetBlocked")
+	defer func() { end(err) }()
+	return r.repo.SetBlocked(ctx, key, blocked, reason)
+}
+
+// BlockedLinks measured list of blocked links
+func (r *Repository) BlockedLinks(ctx context.Context) (links []models.BlockedLink, err error) {
+	ctx, end := r.start(ctx, "BlockedLinks")
+	defer func() { end(err) }()
+	return r.repo.BlockedLinks(ctx)
+}
+
+// SaveDomain measured save of domain
+func (r *Repository) SaveDomain(ctx context.Context, d models.Domain) (err error) {
+	ctx, end := r.start(ctx, "SaveDomain")
+	defer func() { end(err) }()
+	return r.repo.SaveDomain(ctx, d)
+}
+
+// DomainByHost measured lookup of domain
+func (r *Repository) DomainByHost(ctx context.Context, host string) (d models.Domain, err error) {
+	ctx, end := r.start(ctx, "DomainByHost")
+	defer func() { end(err) }()
+	return r.repo.DomainByHost(ctx, host)
+}
+
+// Domains measured list of domains
+func (r *Repository) Domains(ctx context.Context) (domains []models.Domain, err error) {
+	ctx, end := r.start(ctx, "Domains")
+	defer func() { end(err) }()
+	return r.repo.Domains(ctx)
+}
+
+// DeleteDomain measured delete of domain
+func (r *Repository) DeleteDomain(ctx context.Context, host string) (err error) {
+	ctx, end := r.start(ctx, "DeleteDomain")
+	defer func() { end(err) }()
+	return r.repo.DeleteDomain(ctx, host)
+}
+
+// UpdateLink measured edit of link
+func (r *Repository) UpdateLink(
+	ctx context.Context,
+	userID models.UniqUser,
+	key models.ShortURL,
+	upd models.LinkUpdate,
+) (link models.Link, err error) {
+	ctx, end := r.start(ctx, "UpdateLink")
+	defer func() { end(err) }()
+	return r.repo.UpdateLink(ctx, userID, key, upd)
+}
+
+// LinkRevisions measured history of link
+func (r *Repository) LinkRevisions(
+	ctx context.Context,
+	userID models.UniqUser,
+	key models.ShortURL,
+) (revisions []models.LinkRevision, err error) {
+	ctx, end := r.start(ctx, "LinkRevisions")
+	defer func() { end(err) }()
+	return r.repo.LinkRevisions(ctx, userID, key)
+}
+
+// Health measured check of storage
+func (r *Repository) Health(ctx context.Context) (err error) {
+	ctx, end := r.start(ctx, "Health")
+	defer func() { end(err) }()
+	return r.repo.Health(ctx)
+}
@@ -0,0 +1,34 @@
+package instrumented
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/handlers"
+	"github.com/grishagavrin/link-shortener/internal/metrics"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRepo хранилище, которое не может применить пакеты удаления
+type fakeRepo struct {
+	handlers.Repository
+}
+
+func (fakeRepo) BunchUpdateAsDeleted(context.Context, []models.BatchDelete) error {
+	return errs.ErrDatabaseExec
+}
+
+func TestRepository_BunchUpdateAsDeleted(t *testing.T) {
+	repo := New(fakeRepo{}, "fake")
+	err := repo.BunchUpdateAsDeleted(context.Background(), []models.BatchDelete{{UserID: "u1", URLs: []string{"abc"}}})
+	assert.ErrorIs(t, err, errs.ErrDatabaseExec)
+
+	// неудачное применение пакетов учитывается в ошибках хранилища
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `shortener_storage_errors_total{backend="fake",op="BunchUpdateAsDeleted"} 1`)
+}
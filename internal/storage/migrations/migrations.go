@@ -0,0 +1,279 @@
+// Package migrations implements versioned migrations of postgreSQL schema
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// lockID key of advisory lock, so only one instance migrates schema
+const lockID = 7346271
+
+//go:embed sql/*.sql
+var sqlFS embed.FS
+
+// migration one version of schema
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status state of migration version
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator apply and rollback migrations
+type Migrator struct {
+	dbi        *pgxpool.Pool
+	l          *zap.Logger
+	migrations []migration
+}
+
+// New instance of migrator with embedded migrations
+func New(dbi *pgxpool.Pool, l *zap.Logger) (*Migrator, error) {
+	list, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		dbi:        dbi,
+		l:          l,
+		migrations: list,
+	}, nil
+}
+
+// load parse embedded files named like 0001_name.up.sql and 0001_name.down.sql
+func load() ([]migration, error) {
+	files, err := fs.ReadDir(sqlFS, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, f := range files {
+		name := f.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		parts := strings.SplitN(base, "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%w: bad file name %s", errs.ErrMigration, name)
+		}
+
+		version, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad version in %s", errs.ErrMigration, name)
+		}
+
+		body, err := sqlFS.ReadFile("sql/" + name)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: parts[1]}
+			byVersion[version] = m
+		}
+
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	list := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("%w: no up migration for version %d", errs.ErrMigration, m.Version)
+		}
+		list = append(list, *m)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+
+	return list, nil
+}
+
+// Up apply all not applied migrations
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.locked(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mg := range m.migrations {
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+
+			query := `
+			INSERT INTO public.schema_migrations (version, name) VALUES ($1, $2);
+			`
+			err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, mg.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, query, mg.Version, mg.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("%w: up %04d_%s: %v", errs.ErrMigration, mg.Version, mg.Name, err)
+			}
+
+			m.l.Info("migration applied", zap.Int("version", mg.Version), zap.String("name", mg.Name))
+		}
+
+		return nil
+	})
+}
+
+// Down rollback last applied migration
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.locked(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; !ok {
+				continue
+			}
+
+			if mg.Down == "" {
+				return fmt.Errorf("%w: no down migration for version %d", errs.ErrMigration, mg.Version)
+			}
+
+			query := `
+			DELETE FROM public.schema_migrations WHERE version=$1;
+			`
+			err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, mg.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, query, mg.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("%w: down %04d_%s: %v", errs.ErrMigration, mg.Version, mg.Name, err)
+			}
+
+			m.l.Info("migration rolled back", zap.Int("version", mg.Version), zap.String("name", mg.Name))
+			return nil
+		}
+
+		return nil
+	})
+}
+
+// Status return state of all known migrations
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mg := range m.migrations {
+			at, ok := applied[mg.Version]
+			statuses = append(statuses, Status{
+				Version:   mg.Version,
+				Name:      mg.Name,
+				Applied:   ok,
+				AppliedAt: at,
+			})
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// locked run fn on one connection under advisory lock
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.dbi.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDatabaseNotAvaliable, err)
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lockID); err != nil {
+			m.l.Info("unable to release migrations lock", zap.Error(err))
+		}
+	}()
+
+	query := `
+	CREATE TABLE IF NOT EXISTS public.schema_migrations(
+		version integer primary key,
+		name varchar(255) not null,
+		applied_at timestamptz not null default now()
+	);
+	`
+	if _, err = conn.Exec(ctx, query); err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrDatabaseExec, err)
+	}
+
+	return fn(conn)
+}
+
+// applied return versions already applied to database
+func (m *Migrator) applied(ctx context.Context, conn *pgxpool.Conn) (map[int]time.Time, error) {
+	versions := make(map[int]time.Time)
+
+	rows, err := conn.Query(ctx, "SELECT version, applied_at FROM public.schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errs.ErrDatabaseQuery, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err = rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("%w: %v", errs.ErrDatabaseScanRows, err)
+		}
+		versions[version] = at
+	}
+
+	return versions, rows.Err()
+}
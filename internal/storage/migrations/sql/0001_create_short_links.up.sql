@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS public.short_links(
+	id serial,
+	user_id varchar(50),
+	origin  varchar(255) not null,
+	short   varchar(50)  not null,
+	correlation_id varchar(100),
+	is_deleted boolean default false
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS short_links_origin_uindex
+on public.short_links(origin);
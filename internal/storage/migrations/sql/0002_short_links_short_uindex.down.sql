@@ -0,0 +1 @@
+DROP INDEX IF EXISTS public.short_links_short_uindex;
@@ -0,0 +1,2 @@
+CREATE UNIQUE INDEX IF NOT EXISTS short_links_short_uindex
+on public.short_links(short);
@@ -0,0 +1,2 @@
+ALTER TABLE public.short_links
+ADD COLUMN IF NOT EXISTS expires_at timestamptz;
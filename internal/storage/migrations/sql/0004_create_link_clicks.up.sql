@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS public.link_clicks(
+	id bigserial,
+	short varchar(50) not null,
+	clicked_at timestamptz not null default now(),
+	referrer text,
+	user_agent text,
+	ip_bucket varchar(50)
+);
+
+CREATE INDEX IF NOT EXISTS link_clicks_short_index
+on public.link_clicks(short, clicked_at);
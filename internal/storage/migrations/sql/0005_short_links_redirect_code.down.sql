@@ -0,0 +1,2 @@
+ALTER TABLE public.short_links
+DROP COLUMN IF EXISTS redirect_code;
@@ -0,0 +1,2 @@
+ALTER TABLE public.short_links
+ADD COLUMN IF NOT EXISTS redirect_code smallint not null default 307;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS public.short_links_user_id_index;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS short_links_user_id_index
+on public.short_links(user_id);
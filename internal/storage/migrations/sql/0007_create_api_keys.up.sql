@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS public.api_keys(
+	id varchar(32) primary key,
+	key_hash char(64) not null,
+	user_id varchar(50) not null,
+	scopes text[] not null,
+	daily_quota integer not null default 0,
+	created_at timestamptz not null default now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS api_keys_key_hash_uindex
+on public.api_keys(key_hash);
+
+CREATE TABLE IF NOT EXISTS public.api_key_usage(
+	key_id varchar(32) not null references public.api_keys(id) on delete cascade,
+	day date not null,
+	used integer not null default 0,
+	primary key (key_id, day)
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS public.rate_limits(
+	key varchar(255) primary key,
+	tokens double precision not null,
+	allowed boolean not null default true,
+	updated_at timestamptz not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_updated_at_index
+on public.rate_limits(updated_at);
@@ -0,0 +1 @@
+ALTER TABLE public.short_links ALTER COLUMN origin TYPE varchar(255);
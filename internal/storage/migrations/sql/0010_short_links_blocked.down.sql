@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public.short_links_blocked_index;
+ALTER TABLE public.short_links DROP COLUMN IF EXISTS block_reason;
+ALTER TABLE public.short_links DROP COLUMN IF EXISTS is_blocked;
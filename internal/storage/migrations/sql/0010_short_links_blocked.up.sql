@@ -0,0 +1,5 @@
+ALTER TABLE public.short_links ADD COLUMN IF NOT EXISTS is_blocked boolean not null default false;
+ALTER TABLE public.short_links ADD COLUMN IF NOT EXISTS block_reason text;
+
+CREATE INDEX IF NOT EXISTS short_links_blocked_index
+on public.short_links(short) WHERE is_blocked;
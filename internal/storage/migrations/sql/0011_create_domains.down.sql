@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS public.link_clicks_domain_short_index;
+CREATE INDEX IF NOT EXISTS link_clicks_short_index
+on public.link_clicks(short, clicked_at);
+
+DROP INDEX IF EXISTS public.short_links_domain_origin_uindex;
+CREATE UNIQUE INDEX IF NOT EXISTS short_links_origin_uindex
+on public.short_links(origin);
+
+DROP INDEX IF EXISTS public.short_links_domain_short_uindex;
+CREATE UNIQUE INDEX IF NOT EXISTS short_links_short_uindex
+on public.short_links(short);
+
+ALTER TABLE public.link_clicks DROP COLUMN IF EXISTS domain;
+ALTER TABLE public.short_links DROP COLUMN IF EXISTS domain;
+
+DROP TABLE IF EXISTS public.domains;
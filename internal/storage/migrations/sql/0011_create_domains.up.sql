@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS public.domains(
+	host varchar(253) primary key,
+	tenant varchar(100) not null,
+	created_at timestamptz not null default now()
+);
+
+ALTER TABLE public.short_links ADD COLUMN IF NOT EXISTS domain varchar(253) not null default '';
+ALTER TABLE public.link_clicks ADD COLUMN IF NOT EXISTS domain varchar(253) not null default '';
+
+DROP INDEX IF EXISTS public.short_links_short_uindex;
+CREATE UNIQUE INDEX IF NOT EXISTS short_links_domain_short_uindex
+on public.short_links(domain, short);
+
+DROP INDEX IF EXISTS public.short_links_origin_uindex;
+CREATE UNIQUE INDEX IF NOT EXISTS short_links_domain_origin_uindex
+on public.short_links(domain, origin);
+
+DROP INDEX IF EXISTS public.link_clicks_short_index;
+CREATE INDEX IF NOT EXISTS link_clicks_domain_short_index
+on public.link_clicks(domain, short, clicked_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.link_revisions;
+ALTER TABLE public.short_links DROP COLUMN IF EXISTS is_active;
@@ -0,0 +1,13 @@
+ALTER TABLE public.short_links ADD COLUMN IF NOT EXISTS is_active boolean not null default true;
+
+CREATE TABLE IF NOT EXISTS public.link_revisions(
+	domain varchar(253) not null default '',
+	short varchar(50) not null,
+	revision integer not null,
+	origin varchar(2048) not null,
+	redirect_code integer not null,
+	expires_at timestamptz,
+	is_active boolean not null,
+	changed_at timestamptz not null default now(),
+	primary key (domain, short, revision)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS public.short_links_deleted_index;
+ALTER TABLE public.short_links DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,4 @@
+ALTER TABLE public.short_links ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS short_links_deleted_index
+on public.short_links(deleted_at) WHERE is_deleted;
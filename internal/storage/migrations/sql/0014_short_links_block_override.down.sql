@@ -0,0 +1 @@
+ALTER TABLE public.short_links DROP COLUMN IF EXISTS is_allowed;
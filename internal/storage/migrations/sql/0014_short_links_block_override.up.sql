@@ -0,0 +1,2 @@
+-- Link unblocked by admin is not screened again on redirect
+ALTER TABLE public.short_links ADD COLUMN IF NOT EXISTS is_allowed boolean not null default false;
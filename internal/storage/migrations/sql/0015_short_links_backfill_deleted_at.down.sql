@@ -0,0 +1,2 @@
+-- Backfilled deletion time can not be told from real one, it is kept
+SELECT 1;
@@ -0,0 +1,2 @@
+-- Retention of links deleted before deletion time was stored starts now
+UPDATE public.short_links SET deleted_at = now() WHERE is_deleted AND deleted_at IS NULL;
@@ -1,6 +1,11 @@
 // Package models implements Repository pattern
 package models
 
+import (
+	"strings"
+	"time"
+)
+
 // UniqUser unique user type
 type UniqUser string
 
@@ -15,29 +20,200 @@ type ShortLinks map[ShortURL]Origin
 
 // OriginRAM for bool delete in origin
 type OriginRAM struct {
-	Origin    Origin
-	IsDeleted bool
+	Origin       Origin
+	IsDeleted    bool
+	DeletedAt    time.Time
+	ExpiresAt    time.Time
+	RedirectCode int
+	Blocked      bool
+	BlockReason  string
+	// Allowed link is unblocked by admin and is not screened on redirect
+	Allowed bool
+	// Inactive link is kept but not redirected, zero value is active link
+	Inactive  bool
+	Revisions []LinkRevision
+}
+
+// Expired check if link has expiration and it is over
+func (o OriginRAM) Expired(now time.Time) bool {
+	return !o.ExpiresAt.IsZero() && !o.ExpiresAt.After(now)
 }
 
 // ShortLinksRAM RAM storage
 type ShortLinksRAM map[ShortURL]OriginRAM
 
+// LinkKey key of link in storage, links of custom domains are prefixed with host
+func LinkKey(domain string, short ShortURL) ShortURL {
+	if domain == "" {
+		return short
+	}
+	return ShortURL(domain + "/" + string(short))
+}
+
+// SplitLinkKey get domain and short key from key of link, default domain is empty
+func SplitLinkKey(key ShortURL) (string, ShortURL) {
+	i := strings.LastIndex(string(key), "/")
+	if i < 0 {
+		return "", key
+	}
+	return string(key[:i]), key[i+1:]
+}
+
+// Domain custom short domain of tenant. Tenant is one user id, not a group of users:
+// only this user can create links on domain, members of brand use api keys issued for this user id
+type Domain struct {
+	Host      string    `json:"host" example:"go.brand.com"`
+	Tenant    string    `json:"tenant" example:"4b9c1f0e-brand-user-id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LinkOptions optional params for save link
+type LinkOptions struct {
+	Domain       string
+	Alias        ShortURL
+	ExpiresAt    time.Time
+	RedirectCode int
+	Blocked      bool
+	BlockReason  string
+}
+
+// Link short link data for redirect
+type Link struct {
+	Origin       Origin
+	RedirectCode int
+	ExpiresAt    time.Time
+	Blocked      bool
+	BlockReason  string
+	Allowed      bool
+	Inactive     bool
+}
+
+// LinkUpdate changes of link by owner, nil fields are not changed
+type LinkUpdate struct {
+	Origin       *Origin
+	Active       *bool
+	RedirectCode *int
+	ExpiresAt    *time.Time
+	// Verdict of destination checker for new origin, edit can block link but never unblocks it
+	Blocked     bool
+	BlockReason string
+}
+
+// LinkRevision previous state of edited link
+type LinkRevision struct {
+	Revision     int       `json:"revision" example:"1"`
+	Origin       Origin    `json:"original_url" example:"http://yandex.ru"`
+	RedirectCode int       `json:"redirect" example:"307"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+	Active       bool      `json:"active" example:"true"`
+	ChangedAt    time.Time `json:"changed_at"`
+}
+
+// BlockedLink link flagged by destination checker
+type BlockedLink struct {
+	Short  ShortURL `json:"short_url"`
+	Origin Origin   `json:"original_url"`
+	UserID UniqUser `json:"user_id"`
+	Reason string   `json:"reason"`
+}
+
+// Operations of batch channel, empty operation is delete
+const (
+	BatchOpDelete  = ""
+	BatchOpRestore = "restore"
+	BatchOpPurge   = "purge"
+)
+
 // BatchDelete response struct
 type BatchDelete struct {
+	Op     string
 	UserID string
 	URLs   []string
+	// Links deleted before this time are purged
+	Before time.Time
+	// JobID job for report of batch status, may be empty
+	JobID string
+	// Trace W3C trace context of request which queued batch, may be empty
+	Trace map[string]string
+}
+
+// Statuses of batch job
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// Outcomes of link in batch job
+const (
+	JobURLDeleted   = "deleted"
+	JobURLRestored  = "restored"
+	JobURLUnchanged = "unchanged"
+	JobURLNotFound  = "not_found"
+	JobURLNotOwned  = "not_owned"
+)
+
+// JobResult outcome of link in batch job
+type JobResult struct {
+	URL     string `json:"url"`
+	Outcome string `json:"outcome"`
+}
+
+// Job status of asynchronous batch job
+type Job struct {
+	ID        string      `json:"id"`
+	UserID    string      `json:"-"`
+	Status    string      `json:"status"`
+	Error     string      `json:"error,omitempty"`
+	Results   []JobResult `json:"results,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
 }
 
 // BatchReqURL request
 type BatchReqURL struct {
-	CorrID string `json:"correlation_id" example:"1237978947"`
-	Origin string `json:"original_url" example:"http://yandex.ru"`
+	CorrID    string    `json:"correlation_id" example:"1237978947"`
+	Origin    string    `json:"original_url" example:"http://yandex.ru"`
+	Alias     string    `json:"alias,omitempty" example:"spring-sale"`
+	ExpiresIn int64     `json:"expires_in,omitempty" example:"3600"`
+	ExpiresAt time.Time `json:"expires_at" example:"2024-01-01T00:00:00Z"`
+	Redirect  int       `json:"redirect,omitempty" example:"301"`
+	Domain    string    `json:"domain,omitempty" example:"go.brand.com"`
+	// Verdict of destination checker, it is not a part of request
+	Blocked     bool   `json:"-"`
+	BlockReason string `json:"-"`
 }
 
 // BatchResURL response
 type BatchResURL struct {
 	CorrID string `json:"correlation_id"`
 	Short  string `json:"short_url"`
+	Domain string `json:"-"`
+}
+
+// ClickEvent redirect event for analytics
+type ClickEvent struct {
+	// Key of link, links of custom domains are prefixed with host
+	Short     ShortURL  `json:"short"`
+	ClickedAt time.Time `json:"clicked_at"`
+	Referrer  string    `json:"referrer"`
+	UserAgent string    `json:"user_agent"`
+	IPBucket  string    `json:"ip_bucket"`
+}
+
+// ClickPoint point of clicks time series
+type ClickPoint struct {
+	Time   time.Time `json:"time" example:"2023-11-04T10:00:00Z"`
+	Clicks int       `json:"clicks" example:"7"`
+}
+
+// ClickStats response with clicks of short link
+type ClickStats struct {
+	Short  string       `json:"short_url" example:"2dace3f162eb9f0d"`
+	Total  int          `json:"total" example:"42"`
+	Hourly []ClickPoint `json:"hourly"`
+	Daily  []ClickPoint `json:"daily"`
 }
 
 // GetStatsReqURL request
@@ -45,3 +221,53 @@ type GetStatsResURL struct {
 	URLs  int `json:"urls" example:"12"`
 	Users int `json:"users" example:"5"`
 }
+
+// Scopes of bearer tokens
+const (
+	ScopeRead   = "links:read"
+	ScopeWrite  = "links:write"
+	ScopeDelete = "links:delete"
+	ScopeStats  = "links:stats"
+)
+
+// AllScopes scopes of cookie user
+var AllScopes = []string{ScopeRead, ScopeWrite, ScopeDelete, ScopeStats}
+
+// APIKey key of partner system, only hash of key is stored
+type APIKey struct {
+	ID         string    `json:"id"`
+	Hash       string    `json:"-"`
+	UserID     UniqUser  `json:"user_id"`
+	Scopes     []string  `json:"scopes"`
+	DailyQuota int       `json:"daily_quota"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CacheStats counters of link cache
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+}
+
+// Statuses of health check
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthFail     = "fail"
+)
+
+// ComponentHealth status of one component of service
+type ComponentHealth struct {
+	Status string `json:"status"`
+	// Error is only logged, it is not returned to caller
+	Error string `json:"-"`
+}
+
+// HealthReport status of service with statuses of components
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
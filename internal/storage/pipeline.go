@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/health"
+	"github.com/grishagavrin/link-shortener/internal/jobs"
+	"github.com/grishagavrin/link-shortener/internal/metrics"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/grishagavrin/link-shortener/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Default values of delete pipeline used if values in config are invalid
+const (
+	defaultDeleteWorkers = 2
+	defaultDeleteQueue   = 1024
+	defaultDeleteFlush   = 50 * time.Millisecond
+)
+
+// minShardQueue minimal size of channel of one delete worker
+const minShardQueue = 64
+
+// NewBatchQueue create bounded channel for batch delete with size from config
+func NewBatchQueue(l *zap.Logger) chan models.BatchDelete {
+	return make(chan models.BatchDelete, intValue(l, config.DeleteQueue, defaultDeleteQueue))
+}
+
+// DrainDeletes stop dispatch of batch channel and wait until delete workers apply batches
+// which are in it or context done. Channel is not closed, handlers which still run
+// after timeout of servers may send to it without panic, their batches are not applied
+func (i *InstanceStruct) DrainDeletes(ctx context.Context) error {
+	i.drainOnce.Do(func() { close(i.drained) })
+	if err := wait(ctx, &i.deletes); err != nil {
+		return fmt.Errorf("%w: %d batches left", err, len(i.chBatch))
+	}
+	return nil
+}
+
+// checkDeletes check delete workers are running and queue of batches is not full
+func (i *InstanceStruct) checkDeletes(context.Context) error {
+	if atomic.LoadInt32(&i.alive) == 0 {
+		return errs.ErrDeleteWorkersStopped
+	}
+	if cap(i.chBatch) > 0 && len(i.chBatch) == cap(i.chBatch) {
+		return health.Degraded(errs.ErrQueueFull)
+	}
+	return nil
+}
+
+// startDeleteWorkers start workers which coalesce batches from channel and apply them by groups,
+// batches of one user are applied by one worker in order of channel
+func (i *InstanceStruct) startDeleteWorkers(
+	l *zap.Logger,
+	chBatch chan models.BatchDelete,
+	apply func(context.Context, []models.BatchDelete) error,
+) {
+	workers := intValue(l, config.DeleteWorkers, defaultDeleteWorkers)
+	flush := durationValue(l, config.DeleteFlush, defaultDeleteFlush)
+
+	// every worker can hold whole queue, so its channel is full only if the worker is stuck
+	size := cap(chBatch)
+	if size < minShardQueue {
+		size = minShardQueue
+	}
+
+	shards := make([]chan models.BatchDelete, workers)
+	for n := range shards {
+		shards[n] = make(chan models.BatchDelete, size)
+		i.deletes.Add(1)
+		atomic.AddInt32(&i.alive, 1)
+		go func(ch <-chan models.BatchDelete) {
+			defer i.deletes.Done()
+			defer atomic.AddInt32(&i.alive, -1)
+			coalesce(ch, flush, config.DELETEBATCH, apply)
+		}(shards[n])
+	}
+
+	i.deletes.Add(1)
+	go func() {
+		defer i.deletes.Done()
+		dispatch(l, chBatch, i.drained, shards)
+	}()
+}
+
+// dispatch route batches to workers by user until channel is closed or drain is started,
+// on drain batches left in channel are routed, then channels of workers are closed.
+// Dispatch never waits for worker: if channel of worker is full, job of batch is failed
+// with ErrQueueFull, so slow worker does not stall deletes of users of other workers
+func dispatch(l *zap.Logger, chBatch <-chan models.BatchDelete, drained <-chan struct{}, shards []chan models.BatchDelete) {
+	defer func() {
+		for _, ch := range shards {
+			close(ch)
+		}
+	}()
+	route := func(v models.BatchDelete) {
+		n := shardOf(v.UserID, len(shards))
+		select {
+		case shards[n] <- v:
+		default:
+			l.Info("delete worker is full, batch is rejected",
+				zap.Int("worker", n), zap.String("op", v.Op), zap.String("job", v.JobID))
+			jobs.Instance().Finish(v.JobID, nil, errs.ErrQueueFull)
+		}
+	}
+
+	for {
+		select {
+		case v, ok := <-chBatch:
+			if !ok {
+				return
+			}
+			route(v)
+		case <-drained:
+			for {
+				select {
+				case v, ok := <-chBatch:
+					if !ok {
+						return
+					}
+					route(v)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// shardOf get number of worker for user
+func shardOf(userID string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(userID))
+	return int(h.Sum32() % uint32(n))
+}
+
+// coalesce collect batches during flush interval or until limit of urls and apply them together
+func coalesce(
+	chBatch <-chan models.BatchDelete,
+	flush time.Duration,
+	limit int,
+	apply func(context.Context, []models.BatchDelete) error,
+) {
+	for first := range chBatch {
+		group := []models.BatchDelete{first}
+		size := len(first.URLs)
+		timer := time.NewTimer(flush)
+
+	collect:
+		for size < limit {
+			select {
+			case v, ok := <-chBatch:
+				if !ok {
+					break collect
+				}
+				group = append(group, v)
+				size += len(v.URLs)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		metrics.ObserveDeleteGroup(len(group))
+		applyGroup(group, size, apply)
+	}
+}
+
+// applyGroup apply batches in span which continue trace of first request and link traces of others
+func applyGroup(group []models.BatchDelete, size int, apply func(context.Context, []models.BatchDelete) error) {
+	links := make([]trace.Link, 0, len(group))
+	for _, v := range group {
+		if sc := tracing.FromCarrier(v.Trace); sc.IsValid() {
+			links = append(links, trace.Link{SpanContext: sc})
+		}
+	}
+
+	ctx := context.Background()
+	if len(links) > 0 {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, links[0].SpanContext)
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "delete.apply",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.Int("delete.batches", len(group)),
+			attribute.Int("delete.urls", size),
+		),
+	)
+	defer span.End()
+
+	if err := apply(ctx, group); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// intValue get positive integer from config or default value
+func intValue(l *zap.Logger, key string, def int) int {
+	// Config instance
+	cfg, err := config.Instance()
+	if err != nil {
+		return def
+	}
+
+	// Config value
+	value, err := cfg.GetCfgValue(key)
+	if err != nil {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		l.Info("invalid value in config, set default", zap.String(key, value))
+		return def
+	}
+
+	return n
+}
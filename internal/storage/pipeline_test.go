@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/jobs"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestDispatch(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 100)
+	shards := make([]chan models.BatchDelete, 3)
+	for n := range shards {
+		shards[n] = make(chan models.BatchDelete, 100)
+	}
+
+	// применяем группы в воркерах и запоминаем порядок пакетов каждого пользователя
+	var mu sync.Mutex
+	applied := map[string][]string{}
+	var wg sync.WaitGroup
+	for _, ch := range shards {
+		wg.Add(1)
+		go func(ch <-chan models.BatchDelete) {
+			defer wg.Done()
+			coalesce(ch, time.Millisecond, 3, func(_ context.Context, group []models.BatchDelete) error {
+				mu.Lock()
+				defer mu.Unlock()
+				for _, v := range group {
+					applied[v.UserID] = append(applied[v.UserID], v.Op+":"+v.URLs[0])
+				}
+				return nil
+			})
+		}(ch)
+	}
+
+	// удаление и восстановление одних ссылок чередуются
+	want := map[string][]string{}
+	for n := 0; n < 20; n++ {
+		for _, user := range []string{"u1", "u2", "u3", "u4"} {
+			op := models.BatchOpDelete
+			if n%2 == 1 {
+				op = models.BatchOpRestore
+			}
+			url := fmt.Sprintf("%s-%d", user, n/2)
+			chBatch <- models.BatchDelete{Op: op, UserID: user, URLs: []string{url}}
+			want[user] = append(want[user], op+":"+url)
+		}
+	}
+	close(chBatch)
+
+	dispatch(zap.NewNop(), chBatch, nil, shards)
+	wg.Wait()
+
+	// пакеты каждого пользователя применены в порядке очереди
+	assert.Equal(t, want, applied)
+}
+
+func TestDispatch_SlowWorker(t *testing.T) {
+	// пользователи разных воркеров
+	slow, fast := "u0", "u1"
+	for n := 1; shardOf(fast, 2) == shardOf(slow, 2); n++ {
+		fast = fmt.Sprintf("u%d", n)
+	}
+
+	// воркер медленного пользователя не читает свой канал
+	shards := make([]chan models.BatchDelete, 2)
+	for n := range shards {
+		shards[n] = make(chan models.BatchDelete, 1)
+	}
+	job, err := jobs.Instance().Create(slow)
+	assert.NoError(t, err)
+
+	chBatch := make(chan models.BatchDelete, 4)
+	chBatch <- models.BatchDelete{UserID: slow, URLs: []string{"a"}}
+	chBatch <- models.BatchDelete{UserID: slow, URLs: []string{"b"}, JobID: job.ID}
+	chBatch <- models.BatchDelete{UserID: fast, URLs: []string{"c"}}
+	close(chBatch)
+
+	// диспетчер не ждет заполненный воркер, пакет другого пользователя доходит до своего воркера
+	dispatch(zap.NewNop(), chBatch, nil, shards)
+	assert.Equal(t, []string{"c"}, (<-shards[shardOf(fast, 2)]).URLs)
+	assert.Equal(t, []string{"a"}, (<-shards[shardOf(slow, 2)]).URLs)
+
+	// задание пакета, который не поместился, завершается ошибкой
+	got, err := jobs.Instance().Get(slow, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.JobFailed, got.Status)
+	assert.Equal(t, errs.ErrQueueFull.Error(), got.Error)
+}
+
+func TestShardOf(t *testing.T) {
+	// пользователь всегда попадает в один воркер
+	for _, user := range []string{"", "all", "u1", "4b9c1f0e-user"} {
+		n := shardOf(user, 4)
+		assert.True(t, n >= 0 && n < 4)
+		assert.Equal(t, n, shardOf(user, 4))
+	}
+}
+
+func TestDrainDeletes(t *testing.T) {
+	chBatch := make(chan models.BatchDelete, 4)
+	i := &InstanceStruct{chBatch: chBatch, drained: make(chan struct{})}
+
+	var mu sync.Mutex
+	var applied []string
+	i.startDeleteWorkers(zap.NewNop(), chBatch, func(_ context.Context, group []models.BatchDelete) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, v := range group {
+			applied = append(applied, v.URLs[0])
+		}
+		return nil
+	})
+
+	// пакет до остановки применяется
+	chBatch <- models.BatchDelete{UserID: "u1", URLs: []string{"before"}}
+	assert.NoError(t, i.DrainDeletes(context.Background()))
+	assert.Equal(t, []string{"before"}, applied)
+
+	// пакет после остановки не вызывает панику и не применяется, повторная остановка допустима
+	assert.NotPanics(t, func() {
+		select {
+		case chBatch <- models.BatchDelete{UserID: "u1", URLs: []string{"after"}}:
+		default:
+		}
+	})
+	assert.NoError(t, i.DrainDeletes(context.Background()))
+	assert.Equal(t, []string{"before"}, applied)
+}
@@ -2,28 +2,73 @@
 package storage
 
 import (
+	"context"
 	"errors"
+	"sync"
+	"time"
 
+	"github.com/grishagavrin/link-shortener/internal/config"
 	"github.com/grishagavrin/link-shortener/internal/errs"
 	"github.com/grishagavrin/link-shortener/internal/handlers"
+	"github.com/grishagavrin/link-shortener/internal/health"
+	"github.com/grishagavrin/link-shortener/internal/storage/cache"
 	"github.com/grishagavrin/link-shortener/internal/storage/dbstorage"
 	"github.com/grishagavrin/link-shortener/internal/storage/filestorage"
+	"github.com/grishagavrin/link-shortener/internal/storage/instrumented"
 	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"github.com/grishagavrin/link-shortener/internal/utils"
 	"github.com/grishagavrin/link-shortener/internal/utils/db"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
+// Default intervals used if values in config are invalid
+const (
+	defaultSweepInterval   = time.Minute
+	defaultCompactInterval = 5 * time.Minute
+	defaultPurgeInterval   = 24 * time.Hour
+)
+
+// Default values of cache of links used if values in config are invalid
+const (
+	defaultCacheSize        = 10000
+	defaultCacheTTL         = time.Minute
+	defaultCacheNegativeTTL = 5 * time.Second
+)
+
 // InstanceStruct instance struct for repository & pgpool connection
 type InstanceStruct struct {
 	Repository handlers.Repository
 	SQLDB      *pgxpool.Pool
+	// periodic workers which change links: sweeper of expired links and purge of deleted links
+	periodic *workerGroup
+	// writers of buffered data: clicks writer and file storage log
+	writers *workerGroup
+	// deletes workers of batch delete pipeline
+	deletes sync.WaitGroup
+	// alive count of running delete workers
+	alive   int32
+	chBatch chan models.BatchDelete
+	// drained closed on drain of delete pipeline, batch channel itself is never closed by app
+	drained   chan struct{}
+	drainOnce sync.Once
+	// checkFile check of file storage directory, nil for SQL database
+	checkFile health.Check
+	// closeFile close file storage log, nil for SQL database
+	closeFile func() error
 }
 
 // Instance initialize storage with channel for batch delete
 func Instance(l *zap.Logger, chBatch chan models.BatchDelete) (*InstanceStruct, error) {
 	dbi, err := db.SQLDBConnection(l)
-	instanceDB := &InstanceStruct{}
+	instanceDB := &InstanceStruct{
+		periodic: newWorkerGroup(),
+		writers:  newWorkerGroup(),
+		chBatch:  chBatch,
+		drained:  make(chan struct{}),
+	}
+
+	interval := durationValue(l, config.SweepInterval, defaultSweepInterval)
 
 	if err == nil {
 		stor, err := dbstorage.New(dbi, l, chBatch)
@@ -33,10 +78,18 @@ func Instance(l *zap.Logger, chBatch chan models.BatchDelete) (*InstanceStruct,
 			return instanceDB, err
 		}
 
-		// Butch delete listener for SQL database
-		go stor.BunchUpdateAsDeleted(chBatch)
+		// Cache of links for SQL database
+		repo := withCache(l, instrumented.New(stor, "PostgreSQLStorage"), false)
+		// Butch delete workers for SQL database
+		instanceDB.startDeleteWorkers(l, chBatch, repo.BunchUpdateAsDeleted)
+		// Sweeper of expired links for SQL database
+		instanceDB.periodic.run(func(ctx context.Context) { stor.SweepExpired(ctx, interval) })
+		// Purge of old deleted links for SQL database
+		instanceDB.periodic.run(func(ctx context.Context) { runPurge(ctx, l, chBatch) })
+		// Clicks writer for SQL database
+		instanceDB.writers.run(stor.BunchSaveClicks)
 		l.Info("Connected to DB")
-		instanceDB.Repository = stor
+		instanceDB.Repository = repo
 		instanceDB.SQLDB = dbi
 		return instanceDB, nil
 	} else {
@@ -47,11 +100,134 @@ func Instance(l *zap.Logger, chBatch chan models.BatchDelete) (*InstanceStruct,
 			return instanceDB, err
 		}
 
-		// Butch delete listener for RAM database
-		go stor.BunchUpdateAsDeleted(chBatch)
+		// Cache of links for RAM database
+		repo := withCache(l, instrumented.New(stor, "RAMStorage"), true)
+		// Butch delete workers for RAM database
+		instanceDB.startDeleteWorkers(l, chBatch, repo.BunchUpdateAsDeleted)
+		// Sweeper of expired links for RAM database
+		instanceDB.periodic.run(func(ctx context.Context) { stor.SweepExpired(ctx, interval) })
+		// Purge of old deleted links for RAM database
+		instanceDB.periodic.run(func(ctx context.Context) { runPurge(ctx, l, chBatch) })
+		// Clicks writer for RAM database
+		instanceDB.writers.run(stor.BunchSaveClicks)
+		// Log sync and compaction for RAM database
+		compact := durationValue(l, config.WALCompact, defaultCompactInterval)
+		instanceDB.writers.run(func(ctx context.Context) { stor.RunLogMaintenance(ctx, compact) })
+		// Writability of file storage directory
+		instanceDB.checkFile = stor.CheckFile
+		instanceDB.closeFile = stor.Close
 		l.Info("Set RAM handler")
-		instanceDB.Repository = stor
+		instanceDB.Repository = repo
 		instanceDB.SQLDB = nil
 		return instanceDB, nil
 	}
 }
+
+// withCache put cache of links in front of repository, zero size of cache disables it.
+// Cache is invalidated only by this instance, so without size in config it is enabled
+// only for storage which is not shared by instances.
+func withCache(l *zap.Logger, repo handlers.Repository, local bool) handlers.Repository {
+	cfg, err := config.Instance()
+	if err != nil {
+		return repo
+	}
+
+	value, err := cfg.GetCfgValue(config.CacheSize)
+	if err != nil || value == "0" || (value == "" && !local) {
+		l.Info("cache of links is disabled")
+		return repo
+	}
+
+	size := defaultCacheSize
+	if value != "" {
+		size = intValue(l, config.CacheSize, defaultCacheSize)
+	}
+
+	return cache.New(repo,
+		size,
+		durationValue(l, config.CacheTTL, defaultCacheTTL),
+		durationValue(l, config.CacheNegativeTTL, defaultCacheNegativeTTL),
+	)
+}
+
+// RegisterChecks add readiness checks of storage backend, delete workers and file storage
+func (i *InstanceStruct) RegisterChecks(ready *health.Registry) {
+	ready.Add("storage", i.Repository.Health)
+	ready.Add("delete_workers", i.checkDeletes)
+	if i.checkFile != nil {
+		ready.Add("file", i.checkFile)
+	}
+}
+
+// StopWorkers stop periodic workers, after it they do not send to batch channel
+func (i *InstanceStruct) StopWorkers(ctx context.Context) error {
+	return i.periodic.stop(ctx)
+}
+
+// Close flush clicks and file storage log, close log and connect to db
+func (i *InstanceStruct) Close(ctx context.Context) error {
+	err := i.writers.stop(ctx)
+
+	// Log is closed only after its maintenance is stopped
+	if i.closeFile != nil && err == nil {
+		err = i.closeFile()
+	}
+	if i.SQLDB != nil {
+		i.SQLDB.Close()
+	}
+
+	return err
+}
+
+// durationValue get positive duration from config or default value
+func durationValue(l *zap.Logger, key string, def time.Duration) time.Duration {
+	// Config instance
+	cfg, err := config.Instance()
+	if err != nil {
+		return def
+	}
+
+	// Config value
+	value, err := cfg.GetCfgValue(key)
+	if err != nil {
+		return def
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		l.Info("invalid interval in config, set default", zap.String(key, value))
+		return def
+	}
+
+	return interval
+}
+
+// runPurge periodically send purge of old deleted links to batch channel
+func runPurge(ctx context.Context, l *zap.Logger, chBatch chan models.BatchDelete) {
+	days, err := utils.PurgeDays()
+	if err != nil {
+		l.Info("purge of deleted links is disabled", zap.Error(err))
+		return
+	}
+
+	if days == 0 {
+		l.Info("purge of deleted links is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(durationValue(l, config.PurgeInterval, defaultPurgeInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			select {
+			case chBatch <- models.BatchDelete{Op: models.BatchOpPurge, Before: utils.PurgeBefore(now, days)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// workerGroup background workers with common context which are stopped together
+type workerGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newWorkerGroup create group with own context
+func newWorkerGroup() *workerGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workerGroup{ctx: ctx, cancel: cancel}
+}
+
+// run worker of group in goroutine
+func (g *workerGroup) run(fn func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn(g.ctx)
+	}()
+}
+
+// stop cancel workers of group and wait until they return or context done
+func (g *workerGroup) stop(ctx context.Context) error {
+	g.cancel()
+	return wait(ctx, &g.wg)
+}
+
+// wait for wait group until context done
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
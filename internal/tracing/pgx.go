@@ -0,0 +1,125 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryTracer pgx tracer which create span for every query and batch of queries
+type QueryTracer struct{}
+
+// TraceQueryStart start span of query named by statement
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	name, op, table := statementName(data.SQL)
+	ctx, _ = Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperation(op),
+			semconv.DBSQLTable(table),
+			semconv.DBStatement(compactSQL(data.SQL)),
+		),
+	)
+	return ctx
+}
+
+// TraceQueryEnd end span of query
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	endSpan(span, data.Err, data.CommandTag.RowsAffected())
+}
+
+// TraceBatchStart start span of batch, queries of batch are events of it
+func (QueryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx, _ = Tracer().Start(ctx, "batch",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			attribute.Int("db.batch.size", data.Batch.Len()),
+		),
+	)
+	return ctx
+}
+
+// TraceBatchQuery add event with statement of query in batch
+func (QueryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	span := trace.SpanFromContext(ctx)
+	name, _, _ := statementName(data.SQL)
+	attrs := []attribute.KeyValue{
+		semconv.DBStatement(compactSQL(data.SQL)),
+		attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()),
+	}
+	if data.Err != nil {
+		attrs = append(attrs, attribute.String("error", data.Err.Error()))
+	}
+	span.AddEvent(name, trace.WithAttributes(attrs...))
+}
+
+// TraceBatchEnd end span of batch
+func (QueryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	endSpan(trace.SpanFromContext(ctx), data.Err, -1)
+}
+
+// endSpan set status of span by error and end it, negative rows are not reported
+func endSpan(span trace.Span, err error, rows int64) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if rows >= 0 {
+		span.SetAttributes(attribute.Int64("db.rows_affected", rows))
+	}
+	span.End()
+}
+
+// statementName name of span from operation and first table of statement like "SELECT short_links",
+// queries with CTE are named by first operation in it
+func statementName(sql string) (name, op, table string) {
+	words := strings.Fields(strings.NewReplacer("(", " ", ")", " ", ",", " ", ";", " ").Replace(sql))
+	if len(words) == 0 {
+		return "query", "", ""
+	}
+
+	op = strings.ToUpper(words[0])
+	if op == "WITH" {
+		op = cteOperation(words[1:])
+	}
+	for i, w := range words {
+		switch strings.ToUpper(w) {
+		case "FROM", "INTO", "UPDATE", "TABLE":
+			if i+1 < len(words) {
+				table = strings.TrimPrefix(words[i+1], "public.")
+			}
+		}
+		if table != "" {
+			break
+		}
+	}
+
+	if table == "" {
+		return op, op, table
+	}
+	return op + " " + table, op, table
+}
+
+// cteOperation first operation after WITH, WITH when there is none
+func cteOperation(words []string) string {
+	for _, w := range words {
+		switch w = strings.ToUpper(w); w {
+		case "SELECT", "INSERT", "UPDATE", "DELETE":
+			return w
+		}
+	}
+	return "WITH"
+}
+
+// compactSQL statement without line breaks and indentation
+func compactSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
@@ -0,0 +1,129 @@
+// Package tracing implement OpenTelemetry tracing of handlers, gRPC methods and storage
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Exporters of traces
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// serviceName name of service in traces
+const serviceName = "link-shortener"
+
+// tracerName name of instrumentation scope
+const tracerName = "github.com/grishagavrin/link-shortener"
+
+// Init set global tracer provider with exporter from config and W3C propagator,
+// returned function flush and stop exporter. Without exporter spans are not recorded
+func Init(l *zap.Logger) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	// Config instance
+	cfg, err := config.Instance()
+	if err != nil {
+		return nil, err
+	}
+
+	// Config value
+	name, err := cfg.GetCfgValue(config.TraceExporter)
+	if err != nil {
+		return nil, err
+	}
+
+	var exp sdktrace.SpanExporter
+	switch name {
+	case "", ExporterNone:
+		l.Info("tracing is disabled")
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+	case ExporterOTLP:
+		endpoint, err := cfg.GetCfgValue(config.TraceEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		exp, err = newOTLPExporter(endpoint)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", errs.ErrTraceExporter, name)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	l.Info("tracing is enabled", zap.String("exporter", name))
+
+	return provider.Shutdown, nil
+}
+
+// newOTLPExporter exporter to collector endpoint like http://localhost:4318 by OTLP/HTTP
+func newOTLPExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("%w: %s", errs.ErrTraceEndpoint, endpoint)
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithURLPath(strings.TrimRight(u.Path, "/") + "/v1/traces"),
+	}
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	// exporter connects lazily, collector may start later than service
+	return otlptracehttp.New(context.Background(), opts...)
+}
+
+// Tracer of service from global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Carrier W3C trace context of span in context for passing it through channel
+func Carrier(ctx context.Context) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// FromCarrier span context passed through channel by Carrier
+func FromCarrier(carrier map[string]string) trace.SpanContext {
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(carrier))
+	return trace.SpanContextFromContext(ctx)
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+)
+
+// ReservedAliases words which can`t be used as alias because of routes
+var ReservedAliases = []string{
+	"api",
+	"ping",
+	"debug",
+	"swagger",
+	"internal",
+	"metrics",
+	"healthz",
+	"readyz",
+}
+
+// ValidateAlias check custom alias for charset, length and reserved words
+func ValidateAlias(alias string) error {
+	if len(alias) < config.ALIASMINLEN || len(alias) > config.ALIASMAXLEN {
+		return errs.ErrInvalidAlias
+	}
+
+	for _, c := range alias {
+		switch {
+		case c >= 'a' && c <= 'z':
+		case c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9':
+		case c == '-' || c == '_':
+		default:
+			return errs.ErrInvalidAlias
+		}
+	}
+
+	for _, w := range ReservedAliases {
+		if strings.EqualFold(alias, w) {
+			return errs.ErrInvalidAlias
+		}
+	}
+
+	return nil
+}
+
+// IsValidShortKey check short key from request is hash or alias
+func IsValidShortKey(key string) bool {
+	return len(key) == config.LENHASH || ValidateAlias(key) == nil
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// apiKeyPrefix prefix of api keys, so they can be found in leaked configs
+const apiKeyPrefix = "lsk_"
+
+// NewAPIKey generate id and secret of api key
+func NewAPIKey() (id string, key string, err error) {
+	b, err := generateRandom(8)
+	if err != nil {
+		return "", "", err
+	}
+	id = hex.EncodeToString(b)
+
+	b, err = generateRandom(32)
+	if err != nil {
+		return "", "", err
+	}
+	return id, apiKeyPrefix + hex.EncodeToString(b), nil
+}
+
+// HashAPIKey get sha256 hex of api key, key is random so slow hash is not needed
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
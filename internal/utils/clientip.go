@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+)
+
+// trusted proxies are parsed from config once, on first call at startup
+var (
+	proxiesOnce sync.Once
+	proxies     []*net.IPNet
+	proxiesErr  error
+)
+
+// TrustedProxies get CIDRs of proxies which can set X-Real-IP, they are parsed once,
+// so invalid config is error of first call at startup
+func TrustedProxies() ([]*net.IPNet, error) {
+	proxiesOnce.Do(func() {
+		proxies, proxiesErr = parseTrustedProxies()
+	})
+	return proxies, proxiesErr
+}
+
+// parseTrustedProxies parse comma separated CIDRs of proxies from config
+func parseTrustedProxies() ([]*net.IPNet, error) {
+	cfg, err := config.Instance()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := cfg.GetCfgValue(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	var cidrs []*net.IPNet
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		_, cidr, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errs.ErrTrustedProxies, item)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+
+	return cidrs, nil
+}
+
+// RealIP ip of client, real ip from proxy header is used only when connection came from trusted proxy
+func RealIP(remoteAddr, realIP string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	if realIP == "" || net.ParseIP(realIP) == nil {
+		return host
+	}
+
+	// invalid config stops app at startup, here it means no trusted proxies
+	remote := net.ParseIP(host)
+	trusted, _ := TrustedProxies()
+	for _, proxy := range trusted {
+		if proxy.Contains(remote) {
+			return realIP
+		}
+	}
+	return host
+}
+
+// ClientIP get client ip from X-Real-IP header of trusted proxy or remote address
+func ClientIP(req *http.Request) string {
+	return RealIP(req.RemoteAddr, req.Header.Get("X-Real-IP"))
+}
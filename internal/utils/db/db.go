@@ -8,6 +8,7 @@ import (
 
 	"github.com/grishagavrin/link-shortener/internal/config"
 	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/tracing"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
@@ -33,7 +34,14 @@ func SQLDBConnection(l *zap.Logger) (*pgxpool.Pool, error) {
 			return instance, errs.ErrDatabaseNotAvaliable
 		}
 
-		inst, err := pgxpool.New(context.Background(), dsn)
+		poolCfg, err := pgxpool.ParseConfig(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errs.ErrDatabaseNotAvaliable, err)
+		}
+		// Spans of queries are children of span of request in context
+		poolCfg.ConnConfig.Tracer = tracing.QueryTracer{}
+
+		inst, err := pgxpool.NewWithConfig(context.Background(), poolCfg)
 		if err != nil {
 			return nil, fmt.Errorf("%w: %v", errs.ErrDatabaseNotAvaliable, err)
 		}
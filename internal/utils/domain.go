@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/grishagavrin/link-shortener/internal/storage/models"
+	"golang.org/x/net/idna"
+)
+
+// DomainLookup get registered domain by host
+type DomainLookup func(context.Context, string) (models.Domain, error)
+
+// NormalizeDomain lowercase host of custom domain and check it, port is not allowed
+func NormalizeDomain(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	if host == "" || strings.Contains(host, ":") || net.ParseIP(host) != nil {
+		return "", fmt.Errorf("%w: %s", errs.ErrInvalidDomain, host)
+	}
+
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil || len(ascii) > 253 {
+		return "", fmt.Errorf("%w: %s", errs.ErrInvalidDomain, host)
+	}
+
+	for _, label := range strings.Split(ascii, ".") {
+		if !validLabel(label) {
+			return "", fmt.Errorf("%w: %s", errs.ErrInvalidDomain, host)
+		}
+	}
+
+	return ascii, nil
+}
+
+// ResolveDomain get domain for new link, empty domain and host of base url mean default domain,
+// custom domain can be used only by its tenant, which is one user id
+func ResolveDomain(ctx context.Context, lookup DomainLookup, domain string, caller models.UniqUser) (string, error) {
+	if domain == "" {
+		return "", nil
+	}
+
+	host, err := NormalizeDomain(domain)
+	if err != nil {
+		return "", err
+	}
+	if host == baseHost() {
+		return "", nil
+	}
+
+	d, err := lookup(ctx, host)
+	if errors.Is(err, errs.ErrDomainNotFound) {
+		return "", fmt.Errorf("%w: %s", errs.ErrUnknownDomain, host)
+	}
+	if err != nil {
+		return "", err
+	}
+	if d.Tenant != string(caller) {
+		return "", fmt.Errorf("%w: %s", errs.ErrDomainForbidden, host)
+	}
+
+	return host, nil
+}
+
+// RequestDomain get domain of link by host of request, host of base url and ip addresses
+// are served as default domain. Unknown hosts (proxies, service names) are served as default
+// domain too, with STRICT_DOMAINS they are not served
+func RequestDomain(ctx context.Context, lookup DomainLookup, host string) (string, error) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" || host == baseHost() || net.ParseIP(strings.Trim(host, "[]")) != nil {
+		return "", nil
+	}
+
+	_, err := lookup(ctx, host)
+	if errors.Is(err, errs.ErrDomainNotFound) && !strictDomains() {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return host, nil
+}
+
+// LinkURL get short url of link, links of custom domains use scheme of base url
+func LinkURL(baseURL string, key models.ShortURL) string {
+	domain, short := models.SplitLinkKey(key)
+	if domain == "" {
+		return fmt.Sprintf("%s/%s", baseURL, short)
+	}
+
+	scheme := "http"
+	if u, err := url.Parse(baseURL); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	}
+	return fmt.Sprintf("%s://%s/%s", scheme, domain, short)
+}
+
+// strictDomains get config flag for not serving unknown hosts
+func strictDomains() bool {
+	cfg, err := config.Instance()
+	if err != nil {
+		return false
+	}
+
+	value, err := cfg.GetCfgValue(config.StrictDomains)
+	if err != nil {
+		return false
+	}
+
+	strict, _ := strconv.ParseBool(value)
+	return strict
+}
+
+// baseHost get host of base url without port
+func baseHost() string {
+	cfg, err := config.Instance()
+	if err != nil {
+		return ""
+	}
+
+	baseURL, err := cfg.GetCfgValue(config.BaseURL)
+	if err != nil {
+		return ""
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
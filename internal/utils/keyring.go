@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+)
+
+// cookieKeySize size of generated AES-256 key
+const cookieKeySize = 32
+
+// keyring ciphers of cookie keys, the first one is the newest
+type keyring struct {
+	keys    [][]byte
+	ciphers []cipher.AEAD
+}
+
+// Keyring instance and error of its initialization
+var (
+	ring     *keyring
+	ringErr  error
+	ringOnce sync.Once
+)
+
+// Decode userId from encrypted cookie, every key of keyring is tried
+func Decode(shaUserID string, userID *string) error {
+	kr, err := keyInit()
+	if err != nil {
+		return err
+	}
+	// Convert to bytes from hex
+	dst, err := hex.DecodeString(shaUserID)
+	if err != nil {
+		return err
+	}
+
+	for _, aesGCM := range kr.ciphers {
+		nonceSize := aesGCM.NonceSize()
+		if len(dst) < nonceSize {
+			return errs.ErrCookieDecrypt
+		}
+		// Nonce is stored before ciphertext
+		src, err := aesGCM.Open(nil, dst[:nonceSize], dst[nonceSize:], nil)
+		if err == nil {
+			*userID = string(src)
+			return nil
+		}
+	}
+
+	return errs.ErrCookieDecrypt
+}
+
+// Encode userId by GCM algorithm with newest key and fresh nonce and get hex
+func Encode(userID string) (string, error) {
+	kr, err := keyInit()
+	if err != nil {
+		return "", err
+	}
+
+	aesGCM := kr.ciphers[0]
+	nonce, err := generateRandom(aesGCM.NonceSize())
+	if err != nil {
+		return "", err
+	}
+	// Encrypt userId, nonce is prefix of result
+	dst := aesGCM.Seal(nonce, nonce, []byte(userID), nil)
+	// Get hexadecimal string from encode string
+	return hex.EncodeToString(dst), nil
+}
+
+// keyInit load keyring once from config
+func keyInit() (*keyring, error) {
+	ringOnce.Do(func() {
+		ring, ringErr = loadKeyring()
+	})
+	return ring, ringErr
+}
+
+// loadKeyring read keys from COOKIE_KEYS, else from key file, else generate key for process
+func loadKeyring() (*keyring, error) {
+	cfg, err := config.Instance()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := cfg.GetCfgValue(config.CookieKeys)
+	if err != nil {
+		return nil, err
+	}
+	if keys != "" {
+		return newKeyring(strings.Split(keys, ","))
+	}
+
+	path, err := cfg.GetCfgValue(config.CookieKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		// Without key file cookies live until restart
+		key, err := generateRandom(cookieKeySize)
+		if err != nil {
+			return nil, err
+		}
+		return newKeyring([]string{hex.EncodeToString(key)})
+	}
+
+	lines, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newKeyring(lines)
+}
+
+// readKeyFile read hex keys from file, one per line, newest first.
+// Missing file is created with new key.
+func readKeyFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := generateRandom(cookieKeySize)
+		if err != nil {
+			return nil, err
+		}
+		line := hex.EncodeToString(key)
+		if err = os.WriteFile(path, []byte(line+"\n"), 0600); err != nil {
+			return nil, err
+		}
+		return []string{line}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+// newKeyring create ciphers from hex keys
+func newKeyring(keys []string) (*keyring, error) {
+	kr := &keyring{}
+	for _, k := range keys {
+		key, err := hex.DecodeString(strings.TrimSpace(k))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errs.ErrCookieKey, err)
+		}
+
+		aesBlock, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errs.ErrCookieKey, err)
+		}
+		aesGCM, err := cipher.NewGCM(aesBlock)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errs.ErrCookieKey, err)
+		}
+		kr.keys = append(kr.keys, key)
+		kr.ciphers = append(kr.ciphers, aesGCM)
+	}
+
+	if len(kr.ciphers) == 0 {
+		return nil, fmt.Errorf("%w: keyring is empty", errs.ErrCookieKey)
+	}
+
+	return kr, nil
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+)
+
+// PurgeDays get retention of deleted links in days from config, zero disables purge
+func PurgeDays() (int, error) {
+	cfg, err := config.Instance()
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := cfg.GetCfgValue(config.PurgeAfterDays)
+	if err != nil {
+		return 0, err
+	}
+
+	if value == "" {
+		return 0, nil
+	}
+
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("%w: purge after days %q", errs.ErrInvalidPurgeDays, value)
+	}
+
+	return days, nil
+}
+
+// PurgeBefore return cutoff time for purge of links deleted more than days ago
+func PurgeBefore(now time.Time, days int) time.Time {
+	return now.AddDate(0, 0, -days)
+}
@@ -0,0 +1,19 @@
+package utils
+
+import "github.com/grishagavrin/link-shortener/internal/config"
+
+// IsValidRequestID check request id from client is short and has only safe symbols
+func IsValidRequestID(id string) bool {
+	if id == "" || len(id) > config.REQUESTIDMAXLEN {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
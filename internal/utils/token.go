@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+)
+
+// tokenKeyLabel label for derive signing key from cookie key
+const tokenKeyLabel = "bearer-token"
+
+// TokenClaims claims of bearer token, subject is user id
+type TokenClaims struct {
+	Scopes []string `json:"scopes"`
+	jwt.RegisteredClaims
+}
+
+// IssueToken sign token for user with newest key of keyring
+func IssueToken(userID string, scopes []string, expiresAt time.Time) (string, error) {
+	kr, err := keyInit()
+	if err != nil {
+		return "", err
+	}
+
+	claims := TokenClaims{
+		Scopes: scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(signingKey(kr.keys[0]))
+}
+
+// ParseToken check signature and expiry of token, every key of keyring is tried
+func ParseToken(token string) (*TokenClaims, error) {
+	kr, err := keyInit()
+	if err != nil {
+		return nil, err
+	}
+
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+	var lastErr error
+	for _, key := range kr.keys {
+		claims := &TokenClaims{}
+		_, err = parser.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+			return signingKey(key), nil
+		})
+		if err == nil && claims.Subject != "" {
+			return claims, nil
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("%w: %v", errs.ErrInvalidToken, lastErr)
+}
+
+// signingKey derive key of token signature from cookie key
+func signingKey(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(tokenKeyLabel))
+	return mac.Sum(nil)
+}
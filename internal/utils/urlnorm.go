@@ -0,0 +1,356 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"golang.org/x/net/idna"
+)
+
+// TrackingParams query params removed from urls, names ending with * are prefixes
+var TrackingParams = []string{
+	"utm_*",
+	"fbclid",
+	"gclid",
+	"yclid",
+	"dclid",
+	"msclkid",
+	"mc_cid",
+	"mc_eid",
+	"_openstat",
+}
+
+// defaultPorts ports removed from host
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// lookupIP resolver of hosts for check of private targets
+var lookupIP = net.DefaultResolver.LookupIPAddr
+
+// resolvedMax max count of cached results of host lookups, cache is cleared when it is full
+const resolvedMax = 10000
+
+// resolvedHost cached result of host lookup
+type resolvedHost struct {
+	err     error
+	expires time.Time
+}
+
+// resolved cached results of host lookups for check of private targets
+var resolved = struct {
+	sync.Mutex
+	hosts map[string]resolvedHost
+}{hosts: make(map[string]resolvedHost)}
+
+// NormalizeURL validate url and convert it to canonical form, so equal links have equal strings.
+// Host which resolves to private address is rejected, host which is not resolved now is allowed.
+func NormalizeURL(raw string) (string, error) {
+	normalized, host, err := canonicalURL(raw)
+	if err != nil {
+		return "", err
+	}
+
+	if net.ParseIP(host) == nil && !allowPrivate() {
+		if err = checkResolved(host); err != nil {
+			return "", err
+		}
+	}
+	return normalized, nil
+}
+
+// CanonicalURL convert url to canonical form without resolving of host
+func CanonicalURL(raw string) (string, error) {
+	normalized, _, err := canonicalURL(raw)
+	return normalized, err
+}
+
+// canonicalURL validate url and convert it to canonical form, host without port is returned too
+func canonicalURL(raw string) (string, string, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) > config.URLMAXLEN {
+		return "", "", fmt.Errorf("%w: longer than %d", errs.ErrInvalidURL, config.URLMAXLEN)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", errs.ErrInvalidURL, err)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if _, ok := defaultPorts[u.Scheme]; !ok {
+		return "", "", fmt.Errorf("%w: scheme must be http or https", errs.ErrInvalidURL)
+	}
+
+	hostname, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", "", err
+	}
+	host := hostname
+
+	if port := u.Port(); port != "" && port != defaultPorts[u.Scheme] {
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return "", "", fmt.Errorf("%w: bad port %s", errs.ErrInvalidURL, port)
+		}
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6 address without port
+		host = "[" + host + "]"
+	}
+	u.Host = host
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	u.RawQuery = normalizeQuery(u.RawQuery)
+	u.ForceQuery = false
+
+	normalized := u.String()
+	if len(normalized) > config.URLMAXLEN {
+		return "", "", fmt.Errorf("%w: longer than %d", errs.ErrInvalidURL, config.URLMAXLEN)
+	}
+	return normalized, hostname, nil
+}
+
+// normalizeHost lowercase host and check that it is valid and public if private hosts are not allowed
+func normalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return "", fmt.Errorf("%w: empty host", errs.ErrInvalidURL)
+	}
+
+	ip, err := parseIP(host)
+	if err != nil {
+		return "", err
+	}
+	if ip != nil {
+		if isPrivateIP(ip) && !allowPrivate() {
+			return "", fmt.Errorf("%w: %s", errs.ErrPrivateURL, host)
+		}
+		return ip.String(), nil
+	}
+
+	// Internationalized domain names are stored in punycode
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("%w: bad host %s", errs.ErrInvalidURL, host)
+	}
+	host = ascii
+
+	if len(host) > 253 {
+		return "", fmt.Errorf("%w: host is too long", errs.ErrInvalidURL)
+	}
+
+	labels := strings.Split(host, ".")
+	for _, label := range labels {
+		if !validLabel(label) {
+			return "", fmt.Errorf("%w: bad host %s", errs.ErrInvalidURL, host)
+		}
+	}
+
+	if (host == "localhost" || strings.HasSuffix(host, ".localhost")) && !allowPrivate() {
+		return "", fmt.Errorf("%w: %s", errs.ErrPrivateURL, host)
+	}
+
+	return host, nil
+}
+
+// parseIP parse ip address of host, ipv4 is parsed in all forms of inet_aton like browsers do:
+// 2130706433, 0x7f.1, 0177.0.0.1. Nil ip is returned for domain names.
+func parseIP(host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+
+	parts := strings.Split(host, ".")
+	if !isNumericLabel(parts[len(parts)-1]) {
+		return nil, nil
+	}
+
+	// Host with numeric last label is ipv4 address or invalid
+	if len(parts) > 4 {
+		return nil, fmt.Errorf("%w: bad host %s", errs.ErrInvalidURL, host)
+	}
+	var addr uint64
+	for n, part := range parts {
+		v, err := strconv.ParseUint(part, 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad host %s", errs.ErrInvalidURL, host)
+		}
+
+		// Last part fills all remaining bytes of address
+		if n == len(parts)-1 {
+			if v >= 1<<(8*(4-n)) {
+				return nil, fmt.Errorf("%w: bad host %s", errs.ErrInvalidURL, host)
+			}
+			addr = addr<<(8*(4-n)) | v
+			continue
+		}
+		if v > 255 {
+			return nil, fmt.Errorf("%w: bad host %s", errs.ErrInvalidURL, host)
+		}
+		addr = addr<<8 | v
+	}
+
+	return net.IPv4(byte(addr>>24), byte(addr>>16), byte(addr>>8), byte(addr)), nil
+}
+
+// isNumericLabel check if label is decimal, octal or hex number
+func isNumericLabel(label string) bool {
+	lower := strings.ToLower(label)
+	digits := "0123456789"
+	if strings.HasPrefix(lower, "0x") {
+		lower, digits = lower[2:], "0123456789abcdef"
+		if lower == "" {
+			return true
+		}
+	}
+	if lower == "" {
+		return false
+	}
+
+	for _, c := range lower {
+		if !strings.ContainsRune(digits, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkResolved reject host which resolves to private address, failed lookup is not an error.
+// Result is cached for config.RESOLVETTL.
+func checkResolved(host string) error {
+	now := time.Now()
+	resolved.Lock()
+	if r, ok := resolved.hosts[host]; ok && now.Before(r.expires) {
+		resolved.Unlock()
+		return r.err
+	}
+	resolved.Unlock()
+
+	err := lookupPrivate(host)
+
+	resolved.Lock()
+	defer resolved.Unlock()
+	if len(resolved.hosts) >= resolvedMax {
+		resolved.hosts = make(map[string]resolvedHost)
+	}
+	resolved.hosts[host] = resolvedHost{err: err, expires: now.Add(config.RESOLVETTL)}
+	return err
+}
+
+// lookupPrivate resolve host and check its addresses
+func lookupPrivate(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), config.RESOLVETIMEOUT)
+	defer cancel()
+
+	addrs, err := lookupIP(ctx, host)
+	if err != nil {
+		return nil
+	}
+
+	for _, addr := range addrs {
+		if isPrivateIP(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", errs.ErrPrivateURL, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// validLabel check label of domain name
+func validLabel(label string) bool {
+	if len(label) == 0 || len(label) > 63 {
+		return false
+	}
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+
+	for _, c := range label {
+		switch {
+		case c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9':
+		case c == '-' || c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isPrivateIP check if ip is loopback, private, link local or unspecified
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// allowPrivate get config flag for private and loopback targets
+func allowPrivate() bool {
+	cfg, err := config.Instance()
+	if err != nil {
+		return false
+	}
+
+	value, err := cfg.GetCfgValue(config.AllowPrivateURLs)
+	if err != nil {
+		return false
+	}
+
+	allow, _ := strconv.ParseBool(value)
+	return allow
+}
+
+// normalizeQuery remove tracking params and sort params by name, order of values of one param
+// is kept. Query which is not parsed cleanly is kept as is.
+func normalizeQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+	// Params without value are encoded with "=" and empty params are dropped by Encode
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" || !strings.Contains(pair, "=") {
+			return rawQuery
+		}
+	}
+
+	for name := range values {
+		if isTrackingParam(name) {
+			values.Del(name)
+		}
+	}
+
+	// Encode sorts params by name
+	return values.Encode()
+}
+
+// isTrackingParam check if query param is used only for tracking
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range TrackingParams {
+		if prefix := strings.TrimSuffix(p, "*"); prefix != p {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+			continue
+		}
+		if name == p {
+			return true
+		}
+	}
+	return false
+}
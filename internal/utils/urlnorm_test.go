@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/grishagavrin/link-shortener/internal/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	// подменяем резолвер: internal.example.com ведет в частную сеть, остальные хосты не резолвятся
+	lookupIP = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		if host == "internal.example.com" {
+			return []net.IPAddr{{IP: net.ParseIP("10.0.0.5")}}, nil
+		}
+		if host == "public.example.com" {
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	defer func() { lookupIP = net.DefaultResolver.LookupIPAddr }()
+
+	// создаём массив тестов: адрес и желаемый результат
+	tests := []struct {
+		name string
+		raw  string
+		want string
+		err  error
+	}{
+		{
+			name: "positive test #1",
+			raw:  "  HTTP://Yandex.RU:80  ",
+			want: "http://yandex.ru/",
+		},
+		{
+			name: "positive test #2",
+			raw:  "https://yandex.ru:443/path?b=2&a=1&utm_source=mail&fbclid=x",
+			want: "https://yandex.ru/path?a=1&b=2",
+		},
+		{
+			name: "positive test #3",
+			raw:  "http://yandex.ru/?tag=z&tag=a",
+			want: "http://yandex.ru/?tag=z&tag=a",
+		},
+		{
+			name: "positive test #4",
+			raw:  "http://yandex.ru/?flag&utm_source=mail",
+			want: "http://yandex.ru/?flag&utm_source=mail",
+		},
+		{
+			name: "positive test #5",
+			raw:  "http://yandex.ru/?a=1;b=2",
+			want: "http://yandex.ru/?a=1;b=2",
+		},
+		{
+			name: "positive test #6",
+			raw:  "http://пример.рф:8080/",
+			want: "http://xn--e1afmkfd.xn--p1ai:8080/",
+		},
+		{
+			name: "positive test #7",
+			raw:  "http://public.example.com",
+			want: "http://public.example.com/",
+		},
+		{
+			name: "positive test #8",
+			raw:  "http://[2001:db8::1]/",
+			want: "http://[2001:db8::1]/",
+		},
+		{
+			name: "positive test #9",
+			raw:  "http://0x5d.0xb8.0xd8.0x22/",
+			want: "http://93.184.216.34/",
+		},
+		{
+			name: "negative test #1",
+			raw:  "ftp://yandex.ru",
+			err:  errs.ErrInvalidURL,
+		},
+		{
+			name: "negative test #2",
+			raw:  "http://yandex.ru/" + strings.Repeat("a", 2048),
+			err:  errs.ErrInvalidURL,
+		},
+		{
+			name: "negative test #3",
+			raw:  "http://bad_-.-host/",
+			err:  errs.ErrInvalidURL,
+		},
+		{
+			name: "negative test #4",
+			raw:  "http://127.0.0.1/",
+			err:  errs.ErrPrivateURL,
+		},
+		{
+			name: "negative test #5",
+			raw:  "http://2130706433/",
+			err:  errs.ErrPrivateURL,
+		},
+		{
+			name: "negative test #6",
+			raw:  "http://0x7f.1/",
+			err:  errs.ErrPrivateURL,
+		},
+		{
+			name: "negative test #7",
+			raw:  "http://0177.0.0.1/",
+			err:  errs.ErrPrivateURL,
+		},
+		{
+			name: "negative test #8",
+			raw:  "http://1.2.3.256/",
+			err:  errs.ErrInvalidURL,
+		},
+		{
+			name: "negative test #9",
+			raw:  "http://internal.example.com/",
+			err:  errs.ErrPrivateURL,
+		},
+		{
+			name: "negative test #10",
+			raw:  "http://localhost:8080/",
+			err:  errs.ErrPrivateURL,
+		},
+		{
+			name: "negative test #11",
+			raw:  "http://yandex.ru:99999/",
+			err:  errs.ErrInvalidURL,
+		},
+	}
+	for _, tt := range tests {
+		// запускаем каждый тест
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeURL(tt.raw)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	// хост не резолвится при приведении сохраненных ссылок
+	lookupIP = func(context.Context, string) ([]net.IPAddr, error) {
+		t.Fatal("host must not be resolved")
+		return nil, nil
+	}
+	defer func() { lookupIP = net.DefaultResolver.LookupIPAddr }()
+
+	got, err := CanonicalURL("HTTP://Internal.Example.com:80?b=1&a=2")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://internal.example.com/?a=2&b=1", got)
+}
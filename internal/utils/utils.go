@@ -2,88 +2,21 @@
 package utils
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
 	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
 
 	"crypto/rand"
 
 	"github.com/grishagavrin/link-shortener/internal/config"
+	"github.com/grishagavrin/link-shortener/internal/errs"
 	"github.com/grishagavrin/link-shortener/internal/storage/models"
 )
 
-// encKey rand key
-type encData struct {
-	aesGCM cipher.AEAD
-	nonce  []byte
-}
-
-// encInstance save encrypt data
-var encInstance *encData
-
-// Decode userId  from encrypted cookie
-func Decode(shaUserID string, userID *string) error {
-	// Init encrypt data
-	if err := keyInit(); err != nil {
-		return err
-	}
-	// Convert to bytes from hex
-	dst, err := hex.DecodeString(shaUserID)
-	if err != nil {
-		return err
-	}
-	// Decode
-	src, err := encInstance.aesGCM.Open(nil, encInstance.nonce, dst, nil)
-	if err != nil {
-		return err
-	}
-	*userID = string(src)
-	return nil
-}
-
-// Encode userId by GCM algorithm and get hex
-func Encode(userID string) (string, error) {
-	// Init encrypt data
-	if err := keyInit(); err != nil {
-		return "", err
-	}
-	src := []byte(userID)
-	// Encrypt userId
-	dst := encInstance.aesGCM.Seal(nil, encInstance.nonce, src, nil)
-	// Get hexadecimal string from encode string
-	sha := hex.EncodeToString(dst)
-	return sha, nil
-}
-
-// keyInit init crypt params
-func keyInit() error {
-	// If you need generate new key
-	if encInstance == nil {
-		key, err := generateRandom(aes.BlockSize)
-		if err != nil {
-			return err
-		}
-
-		aesBlock, err := aes.NewCipher(key)
-		if err != nil {
-			return err
-		}
-		aesGCM, err := cipher.NewGCM(aesBlock)
-		if err != nil {
-			return err
-		}
-		// initialize vector
-		nonce, err := generateRandom(aesGCM.NonceSize())
-		if err != nil {
-			return err
-		}
-		// Allocation enc type
-		encInstance = new(encData)
-		encInstance.aesGCM = aesGCM
-		encInstance.nonce = nonce
-	}
-	return nil
-}
+// permanentMaxAge cache lifetime of permanent redirects
+const permanentMaxAge = 24 * time.Hour
 
 // generateRandom byte slice
 func generateRandom(size int) ([]byte, error) {
@@ -104,3 +37,67 @@ func RandStringBytes() (models.ShortURL, error) {
 	}
 	return models.ShortURL(hex.EncodeToString(b)), nil
 }
+
+// IPBucket return coarse client subnet: /24 for IPv4 and /48 for IPv6
+func IPBucket(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// ValidateRedirect check redirect status code of link, zero means default code
+func ValidateRedirect(code int) error {
+	switch code {
+	case 0, http.StatusMovedPermanently, http.StatusFound,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return nil
+	}
+	return errs.ErrInvalidRedirect
+}
+
+// CacheControl return Cache-Control header value for redirect of link
+func CacheControl(link models.Link, now time.Time) string {
+	switch link.RedirectCode {
+	case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		maxAge := permanentMaxAge
+		// Permanent redirect must not live in cache longer than link
+		if !link.ExpiresAt.IsZero() {
+			left := link.ExpiresAt.Sub(now)
+			if left <= 0 {
+				return "no-store"
+			}
+			if left < maxAge {
+				maxAge = left
+			}
+		}
+		return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+	}
+	return "private, no-cache, no-store, must-revalidate"
+}
+
+// LinkExpiry return absolute expiration time from relative or absolute value
+func LinkExpiry(expiresIn int64, expiresAt time.Time) (time.Time, error) {
+	switch {
+	case expiresIn == 0 && expiresAt.IsZero():
+		return time.Time{}, nil
+	case expiresIn != 0 && !expiresAt.IsZero():
+		return time.Time{}, errs.ErrInvalidExpiry
+	case expiresIn < 0:
+		return time.Time{}, errs.ErrInvalidExpiry
+	case expiresIn > 0:
+		return time.Now().Add(time.Duration(expiresIn) * time.Second).UTC(), nil
+	}
+
+	if !expiresAt.After(time.Now()) {
+		return time.Time{}, errs.ErrInvalidExpiry
+	}
+
+	return expiresAt.UTC(), nil
+}